@@ -0,0 +1,99 @@
+package main
+
+import (
+	"HomeExam/game"
+	"HomeExam/network"
+	"fmt"
+	"io"
+	"log"
+)
+
+// runLobby runs a lobby on port and plays a game at every table once its seats have all been
+// taken, each in its own goroutine. It only returns if the lobby cannot be started.
+//
+// Parameters:
+//   - lobby: The lobby server to run.
+//   - port: The port the lobby listens on.
+//   - config: The settings shared by every table, the number of players and bots come from the table.
+//
+// Returns:
+//   - error: An error if the settings cannot be used for a lobby, the manifest is invalid or the lobby cannot listen.
+func runLobby(lobby network.LobbyServer, port string, config game.HostConfig) error {
+	if config.RecordPath != "" || config.SavePath != "" || config.ResumePath != "" {
+		return fmt.Errorf("-record, -save and -resume cannot be used with -lobby")
+	}
+	_, manifestErrors, err := game.ValidatePointSaladManifest(config.ManifestPath, config.DeckPath)
+	if err != nil {
+		return err
+	}
+	if len(manifestErrors) > 0 {
+		return fmt.Errorf("The manifest has %d invalid criteria, see validate-manifest", len(manifestErrors))
+	}
+
+	maxSize := game.CreatePointSaladHost().GetMaxHostDataSize()
+	err = lobby.Listen(port, maxSize, func(table network.TableConfig) error {
+		return game.CheckPointSaladConfig(getTableHostConfig(config, table, 0))
+	})
+	if err != nil {
+		return err
+	}
+
+	for table := range lobby.GetTableChannel() {
+		go hostTable(table, getTableHostConfig(config, table.Config, table.Id))
+	}
+	return nil
+}
+
+// getTableHostConfig returns the settings of the game at a table. With -seed every table plays
+// with the seed plus its id, so tables deal different decks but every game can still be reproduced.
+//
+// Parameters:
+//   - config: The settings shared by every table.
+//   - table: The number of players and bots of the table.
+//   - tableId: The id of the table, 0 while the table is checked before it is created.
+//
+// Returns:
+//   - game.HostConfig: The settings of the game at the table.
+func getTableHostConfig(config game.HostConfig, table network.TableConfig, tableId int) game.HostConfig {
+	config.PlayerNum = table.Players
+	config.BotNum = table.Bots
+	config.BotStrategies = table.BotStrategies
+	if config.Seed != 0 {
+		config.Seed += int64(tableId)
+	}
+	return config
+}
+
+// hostTable plays a game at a table whose seats have all been taken and closes the table once it is over.
+// A game that cannot be set up, for example because a manifest was changed since the lobby started,
+// only closes its own table and leaves the lobby and the other tables running.
+func hostTable(table *network.Table, config game.HostConfig) {
+	log.Printf("Starting the game at table %d with %d players and %d bots\n", table.Id, config.PlayerNum, config.BotNum)
+	host := game.CreatePointSaladHost()
+	err := host.Init(config)
+	if err != nil {
+		log.Printf("ERROR: Failed to start the game at table %d: %s\n", table.Id, err)
+		table.Close()
+		return
+	}
+	host.SetPlayerNames(table.GetPlayerNames())
+	host.SetPlayerBots(table.GetBotStrategies())
+	host.RunHost(table.GetReadChannels(), table.GetWriteChannels(), table.GetStatusChannels(), table.GetSpectatorChannel())
+	table.Close()
+	log.Printf("The game at table %d is over\n", table.Id)
+}
+
+// printTables prints the tables of a lobby, one per line.
+func printTables(w io.Writer, tables []network.TableInfo) {
+	if len(tables) == 0 {
+		fmt.Fprintf(w, "No tables, create one with -create\n")
+		return
+	}
+	for _, table := range tables {
+		state := "open"
+		if table.Started {
+			state = "playing"
+		}
+		fmt.Fprintf(w, "table %d: %d/%d players seated, %d bots, %s\n", table.Id, table.Seated, table.Config.Players, table.Config.Bots, state)
+	}
+}
@@ -4,50 +4,328 @@ import (
 	"HomeExam/game"
 	"HomeExam/network"
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		err := runSimulation(os.Args[2:], os.Stdout)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "validate-manifest" {
+		err := runValidateManifest(os.Args[2:], os.Stdout)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		return
+	}
 
 	var isServer bool
 	var hostname string
 	var port string
 	var playerNum int
-	var botNum int
+	var bots string
+	var isBot bool
+	var strategy string
+	var gracePeriod time.Duration
+	var turnTimeout time.Duration
+	var seed int64
+	var session string
+	var recordPath string
+	var replayPath string
+	var savePath string
+	var resumePath string
+	var manifestPath string
+	var isLobby bool
+	var listTables bool
+	var createTable bool
+	var tableId int
+	var spectate bool
+	var transport string
+	var tlsCert string
+	var tlsKey string
+	var tlsCA string
+	var password string
+	var seat int
+	var name string
+	var ties string
+	var deckPath string
 
 	flag.BoolVar(&isServer, "server", false, "ex. -server")
 	flag.StringVar(&hostname, "hostname", "127.0.0.1", "ex. 127.0.0.1")
 	flag.StringVar(&port, "port", "8080", "ex. 8080")
 	flag.IntVar(&playerNum, "players", 1, "ex. 2")
-	flag.IntVar(&botNum, "bots", 1, "ex. 2")
+	flag.StringVar(&bots, "bots", "1", "number of random bots or their strategies in seat order, ex. 2 or greedy,random")
+	flag.BoolVar(&isBot, "bot", false, "connect as a bot player instead of a human, ex. -bot")
+	flag.StringVar(&strategy, "strategy", "random", "strategy used with -bot: random, greedy, lookahead or ismcts, ex. ismcts:5000:2s")
+	flag.DurationVar(&gracePeriod, "grace", time.Minute, "how long a disconnected player keeps its seat before a bot takes over, ex. 30s")
+	flag.Int64Var(&seed, "seed", 0, "seed of the game, the same seed and inputs replay the same game, 0 picks one from the current time")
+	flag.DurationVar(&turnTimeout, "turn-timeout", 0, "how long a player has to answer before the move is made automatically, 0 means no limit, ex. 45s")
+	flag.StringVar(&recordPath, "record", "", "write a recording of the hosted game to this file, ex. out.json")
+	flag.StringVar(&replayPath, "replay", "", "replay a recorded game and verify its final scores, ex. out.json")
+	flag.StringVar(&savePath, "save", "", "save the hosted game to this file at the start of every turn, ex. save.json")
+	flag.StringVar(&resumePath, "resume", "", "continue the game saved in this file once its players have connected, ex. save.json")
+	flag.StringVar(&manifestPath, "manifest", "", "comma separated card manifests to play with, base is the embedded manifest, which is used if it is empty, ex. base,expansion.json")
+	flag.StringVar(&deckPath, "deck", "", "game-config file with more manifests, the card ids to include or exclude and the deck size of every vegetable, ex. deck.json")
+	flag.BoolVar(&isLobby, "lobby", false, "with -server, host a lobby with any number of tables instead of a single game, ex. -server -lobby")
+	flag.BoolVar(&listTables, "tables", false, "list the tables of a lobby and exit, ex. -tables")
+	flag.BoolVar(&createTable, "create", false, "create a lobby table with -players and -bots and take its first seat, ex. -create -players 2 -bots 1")
+	flag.IntVar(&tableId, "table", 0, "join the lobby table with this id, ex. 3")
+	flag.BoolVar(&spectate, "spectate", false, "watch the game without taking a seat, with -table to watch a lobby table, ex. -spectate -table 3")
+	flag.StringVar(&transport, "transport", "tcp", "how clients connect: tcp or ws for websockets at ws://hostname:port/, ex. -transport ws")
+	flag.StringVar(&tlsCert, "tls-cert", "", "with -server, accept only TLS connections presenting this PEM certificate, needs -tls-key, ex. cert.pem")
+	flag.StringVar(&tlsKey, "tls-key", "", "with -server, the PEM private key of -tls-cert, ex. key.pem")
+	flag.StringVar(&tlsCA, "tls-ca", "", "connect over TLS and trust servers whose certificate is signed by this PEM certificate, ex. cert.pem")
+	flag.StringVar(&password, "password", "", "with -server, the password players need to join, otherwise the password to join with")
+	flag.StringVar(&name, "name", "", "the name other players see you as, bots are called after their strategy by default, ex. Ada")
+	flag.IntVar(&seat, "seat", -1, "ask for the seat with this actor id instead of the next free one, ex. 0")
+	flag.StringVar(&ties, "ties", "first", "with -server, who scores a tied MOST or FEWEST card: first (lowest seat), shared (every tied player) or none, ex. shared")
+	flag.StringVar(&session, "session", "", "session token to resume a seat after the client was restarted")
 	flag.Parse()
 
-	log.Printf("isServer = %v, hostname = %v port = %v playerNum = %v botNum = %v\n", isServer, hostname, port, playerNum, botNum)
+	if replayPath != "" {
+		err := game.ReplayPointSaladGame(replayPath, manifestPath, deckPath, os.Stdout)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		log.Printf("Replay of %s matches the recorded scores\n", replayPath)
+		return
+	}
+
+	botNum, botStrategies, err := parseBots(bots)
+	if err != nil {
+		log.Fatalf("%s\n", err)
+	}
+	if transport != "tcp" && transport != "ws" {
+		log.Fatalf("Unknown transport %q, expected tcp or ws\n", transport)
+	}
+
+	log.Printf("isServer = %v, hostname = %v port = %v playerNum = %v botNum = %v isBot = %v\n", isServer, hostname, port, playerNum, botNum, isBot)
 
-	if isServer {
+	config := game.HostConfig{PlayerNum: playerNum, BotNum: botNum, BotStrategies: botStrategies, GracePeriod: gracePeriod, TurnTimeout: turnTimeout, Seed: seed, RecordPath: recordPath, SavePath: savePath, ResumePath: resumePath, ManifestPath: manifestPath, DeckPath: deckPath, TiePolicy: ties}
+
+	if isServer && isLobby {
+		lobby := createLobby(transport)
+		err := secureServer(lobby, tlsCert, tlsKey, password)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		err = runLobby(lobby, port, config)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+	} else if isServer {
 		host := game.CreatePointSaladHost()
-		host.Init(playerNum, botNum)
+		err := host.Init(config)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
 
-		server := network.CreateTCPServer()
-		err := server.Listen(port, playerNum, host.GetMaxHostDataSize())
+		server := createServer(transport)
+		err = secureServer(server, tlsCert, tlsKey, password)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		// a resumed game gives every player back its own seat
+		server.ReserveSeats(host.GetSeatNames())
+		err = server.Listen(port, host.GetPlayerNum(), host.GetMaxHostDataSize())
 		if err != nil {
 			log.Fatalf("%s\n", err)
 		}
 
-		host.RunHost(server.GetReadChannels(), server.GetWriteChannels())
+		host.SetPlayerNames(server.GetPlayerNames())
+		host.SetPlayerBots(server.GetBotStrategies())
+		host.RunHost(server.GetReadChannels(), server.GetWriteChannels(), server.GetStatusChannels(), server.GetSpectatorChannel())
 		server.Close()
 
+	} else if listTables {
+		client := createClient(transport)
+		err := secureClient(client, tlsCA, password)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		tables, err := client.ListTables(hostname, port)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		printTables(os.Stdout, tables)
 	} else {
-		player := game.CreatePointSaladPlayer()
-		player.Init()
+		var player game.GamePlayer
+		if isBot {
+			var err error
+			player, err = game.CreatePointSaladBotPlayer(strategy)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+			}
+		} else {
+			player = game.CreatePointSaladPlayer()
+			player.Init()
+		}
 
-		client := network.CreateTCPClient()
-		err := client.Connect(hostname, port, player.GetMaxPlayerDataSize())
+		client := createClient(transport)
+		err := secureClient(client, tlsCA, password)
 		if err != nil {
 			log.Fatalf("%s\n", err)
 		}
+		client.SetSessionToken(session)
+		client.SetName(name)
+		if isBot {
+			client.SetBot(strategy)
+		}
+		if seat >= 0 {
+			client.RequestSeat(seat)
+		}
+		if spectate {
+			client.Spectate()
+		}
+		if createTable {
+			client.CreateTable(network.TableConfig{Players: playerNum, Bots: botNum, BotStrategies: botStrategies})
+		} else if tableId != 0 {
+			client.JoinTable(tableId)
+		}
+		err = client.Connect(hostname, port, player.GetMaxPlayerDataSize())
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		if spectate {
+			log.Printf("Spectating\n")
+		} else {
+			if client.GetTableId() != 0 {
+				log.Printf("Seated at table %d\n", client.GetTableId())
+			}
+			log.Printf("Playing as player %d\n", client.GetActorId())
+			log.Printf("Connected, use -session %s to resume the seat if the client is restarted\n", client.GetSessionToken())
+		}
 
 		player.RunPlayer(client.GetReadChannel(), client.GetWriteChannel())
 		client.Close()
 	}
 }
+
+// parseBots parses the -bots flag, which is either the number of bots or a comma separated list
+// of their strategies.
+//
+// Parameters:
+//   - value: The value of the flag, ex. "2" or "greedy,random".
+//
+// Returns:
+//   - int: The number of bots.
+//   - []string: The strategies of the bots in seat order, empty if only a number was given.
+//   - error: An error if the number is negative or a strategy name is empty.
+func parseBots(value string) (int, []string, error) {
+	num, err := strconv.Atoi(value)
+	if err == nil {
+		if num < 0 {
+			return 0, nil, fmt.Errorf("Number of bots cannot be negative: %d", num)
+		}
+		return num, []string{}, nil
+	}
+	strategies := strings.Split(value, ",")
+	for i := range strategies {
+		strategies[i] = strings.TrimSpace(strategies[i])
+		if strategies[i] == "" {
+			return 0, nil, fmt.Errorf("Empty bot strategy in -bots %q", value)
+		}
+	}
+	return len(strategies), strategies, nil
+}
+
+// createServer returns a game server for the transport given with -transport.
+//
+// Parameters:
+//   - transport: "ws" for websockets, anything else for tcp.
+//
+// Returns:
+//   - network.Server: The server.
+func createServer(transport string) network.Server {
+	if transport == "ws" {
+		return network.CreateWebSocketServer()
+	}
+	return network.CreateTCPServer()
+}
+
+// createClient returns a game client for the transport given with -transport.
+//
+// Parameters:
+//   - transport: "ws" for websockets, anything else for tcp.
+//
+// Returns:
+//   - network.Client: The client.
+func createClient(transport string) network.Client {
+	if transport == "ws" {
+		return network.CreateWebSocketClient()
+	}
+	return network.CreateTCPClient()
+}
+
+// createLobby returns a lobby server for the transport given with -transport.
+//
+// Parameters:
+//   - transport: "ws" for websockets, anything else for tcp.
+//
+// Returns:
+//   - network.LobbyServer: The lobby.
+func createLobby(transport string) network.LobbyServer {
+	if transport == "ws" {
+		return network.CreateWebSocketLobby()
+	}
+	return network.CreateTCPLobby()
+}
+
+// securable is implemented by network.Server and network.LobbyServer.
+type securable interface {
+	SetTLS(certFile string, keyFile string) error
+	SetPassword(password string)
+}
+
+// secureServer sets up TLS and the table password of a server or lobby from the flags.
+//
+// Parameters:
+//   - server: The server or lobby, before it listens.
+//   - certFile: The -tls-cert flag, TLS is only used if it is set.
+//   - keyFile: The -tls-key flag.
+//   - password: The -password flag, empty to accept every client.
+//
+// Returns:
+//   - error: An error if only one of -tls-cert and -tls-key is set or they cannot be loaded.
+func secureServer(server securable, certFile string, keyFile string, password string) error {
+	if (certFile == "") != (keyFile == "") {
+		return fmt.Errorf("-tls-cert and -tls-key have to be used together")
+	}
+	if certFile != "" {
+		err := server.SetTLS(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+	}
+	server.SetPassword(password)
+	return nil
+}
+
+// secureClient sets up TLS and the table password of a client from the flags.
+//
+// Parameters:
+//   - client: The client, before it connects.
+//   - caFile: The -tls-ca flag, TLS is only used if it is set.
+//   - password: The -password flag.
+//
+// Returns:
+//   - error: An error if the CA cannot be loaded.
+func secureClient(client network.Client, caFile string, password string) error {
+	if caFile != "" {
+		err := client.SetTLS(caFile)
+		if err != nil {
+			return err
+		}
+	}
+	client.SetPassword(password)
+	return nil
+}
@@ -1,9 +1,215 @@
 package main
 
 import (
+	"HomeExam/game"
+	"HomeExam/network"
+	"bytes"
+	"encoding/csv"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSimulation(t *testing.T) {
+	simulate := func(args ...string) string {
+		out := bytes.Buffer{}
+		args = append(args, "-games", "6", "-format", "csv")
+		err := runSimulation(args, &out)
+		if err != nil {
+			t.Fatalf("simulation failed: %v", err)
+		}
+		return out.String()
+	}
 
+	report := simulate("-bots", "greedy,random", "-workers", "2")
+	rows, err := csv.NewReader(strings.NewReader(report)).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read csv report: %v", err)
+	}
+	// header, 2 strategies, 2 seats and the game length
+	if len(rows) != 6 {
+		t.Fatalf("expected 6 rows got %d:\n%s", len(rows), report)
+	}
+	for _, group := range []string{"strategy", "seat"} {
+		winRate := 0.0
+		for _, row := range rows[1:] {
+			if row[0] != group {
+				continue
+			}
+			if row[2] != "6" {
+				t.Errorf("expected 6 games for %s %s got %s", group, row[1], row[2])
+			}
+			rate, err := strconv.ParseFloat(row[3], 64)
+			if err != nil {
+				t.Fatalf("failed to parse win rate %q", row[3])
+			}
+			winRate += rate
+		}
+		if math.Abs(winRate-1) > 0.001 {
+			t.Errorf("expected the win rates of every %s to add up to 1 got %f", group, winRate)
+		}
+	}
+
+	// the games do not depend on how many run in parallel
+	if again := simulate("-bots", "greedy,random", "-workers", "1"); again != report {
+		t.Errorf("expected the same report with another number of workers, got\n%s\nand\n%s", report, again)
+	}
+
+	err = runSimulation([]string{"-format", "xml"}, &bytes.Buffer{})
+	if err == nil {
+		t.Errorf("expected an unknown format to be rejected")
+	}
+}
+
+func TestParseBots(t *testing.T) {
+	test_table := []struct {
+		value      string
+		num        int
+		strategies []string
+		valid      bool
+	}{
+		{"2", 2, []string{}, true},
+		{"0", 0, []string{}, true},
+		{"greedy,random", 2, []string{"greedy", "random"}, true},
+		{"lookahead", 1, []string{"lookahead"}, true},
+		{"-1", 0, nil, false},
+		{"greedy,,random", 0, nil, false},
+	}
+
+	for _, test := range test_table {
+		num, strategies, err := parseBots(test.value)
+		if (err == nil) != test.valid {
+			t.Errorf("expected valid = %v for %q, got error %v", test.valid, test.value, err)
+			continue
+		}
+		if num != test.num || !reflect.DeepEqual(strategies, test.strategies) {
+			t.Errorf("expected %d %v for %q got %d %v", test.num, test.strategies, test.value, num, strategies)
+		}
+	}
+}
+
+func TestValidateManifest(t *testing.T) {
+	out := bytes.Buffer{}
+	err := runValidateManifest([]string{}, &out)
+	if err != nil {
+		t.Fatalf("expected the embedded manifest to be valid got %v:\n%s", err, out.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	manifest := `{"cards": [
+		{"id": 0, "criteria": {"PEPPER": "MOST PEPPER = 10", "LETTUCE": "MOST", "CARROT": "MOST CARROT = 10", "CABBAGE": "MOST CABBAGE = 10", "ONION": "MOST ONION = 10", "TOMATO": "MOST TOMATO = 10"}},
+		{"id": 1, "criteria": {"PEPPER": "MOST PEPPER = 10", "LETTUCE": "MOST LETTUCE = 10", "CARROT": "MOST CARROT = 10", "CABBAGE": "MOST CABBAGE = 10", "ONION": "MOST ONION = 10", "TOMATO": "ONION: +"}}
+	]}`
+	err = os.WriteFile(path, []byte(manifest), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	err = runValidateManifest([]string{"-manifest", path}, &out)
+	if err == nil {
+		t.Fatalf("expected the broken manifest to be rejected")
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "card 0 LETTUCE") || !strings.HasPrefix(lines[1], "card 1 TOMATO") {
+		t.Errorf("expected a failure for card 0 LETTUCE and card 1 TOMATO got\n%s", out.String())
+	}
+}
+
+func TestLobbyPlaysTablesConcurrently(t *testing.T) {
+	for _, transport := range []string{"tcp", "ws"} {
+		t.Run(transport, func(t *testing.T) {
+			testLobbyPlaysTables(t, transport)
+		})
+	}
+}
+
+// testLobbyPlaysTables plays two tables of a lobby at the same time and a third one afterwards.
+func testLobbyPlaysTables(t *testing.T, transport string) {
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close()
+
+	lobby := createLobby(transport)
+	defer lobby.Close()
+	go func() {
+		err := runLobby(lobby, port, game.HostConfig{GracePeriod: time.Minute})
+		if err != nil {
+			t.Errorf("failed to run lobby: %v", err)
+		}
+	}()
+
+	done := make(chan int)
+	for i := range 2 {
+		player, err := game.CreatePointSaladBotPlayer("greedy")
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := createClient(transport)
+		client.CreateTable(network.TableConfig{Players: 1, Bots: 1})
+		for range 50 {
+			err = client.Connect("127.0.0.1", port, player.GetMaxPlayerDataSize())
+			if err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatalf("failed to create table %d: %v", i, err)
+		}
+		go func() {
+			player.RunPlayer(client.GetReadChannel(), client.GetWriteChannel())
+			client.Close()
+			done <- client.GetTableId()
+		}()
+	}
+
+	tables := []int{<-done, <-done}
+	slices.Sort(tables)
+	if !reflect.DeepEqual(tables, []int{1, 2}) {
+		t.Errorf("expected games at tables 1 and 2 got %v", tables)
+	}
+
+	// the lobby stays up for the next game
+	player, _ := game.CreatePointSaladBotPlayer("random")
+	client := createClient(transport)
+	client.CreateTable(network.TableConfig{Players: 1, Bots: 1})
+	err = client.Connect("127.0.0.1", port, player.GetMaxPlayerDataSize())
+	if err != nil {
+		t.Fatalf("failed to create a table after the first games: %v", err)
+	}
+	player.RunPlayer(client.GetReadChannel(), client.GetWriteChannel())
+	client.Close()
+	if client.GetTableId() != 3 {
+		t.Errorf("expected the game at table 3 got %d", client.GetTableId())
+	}
+}
+
+func TestLobbyTableConfig(t *testing.T) {
+	config := game.HostConfig{Seed: 100, GracePeriod: time.Minute}
+	table := network.TableConfig{Players: 1, Bots: 1}
+	first := getTableHostConfig(config, table, 1)
+	second := getTableHostConfig(config, table, 2)
+	if first.Seed != 101 || second.Seed != 102 {
+		t.Errorf("expected every table to play with its own seed got %d and %d", first.Seed, second.Seed)
+	}
+	if unseeded := getTableHostConfig(game.HostConfig{}, table, 3); unseeded.Seed != 0 {
+		t.Errorf("expected a lobby without -seed to leave the seed to the game got %d", unseeded.Seed)
+	}
+
+	// a manifest removed after the lobby started fails the table instead of the lobby
+	first.ManifestPath = filepath.Join(t.TempDir(), "removed.json")
+	err := game.CreatePointSaladHost().Init(first)
+	if err == nil {
+		t.Errorf("expected a missing manifest to be an error")
+	}
 }
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"HomeExam/game"
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// runSimulation runs the simulate subcommand, which plays games between bots without any network
+// and prints the win rates, scores, game lengths and seat bias.
+//
+// Parameters:
+//   - args: The arguments after the subcommand, ex. -games 100 -bots greedy,random.
+//   - w: Where the report is printed.
+//
+// Returns:
+//   - error: An error if the arguments are invalid or the simulation fails.
+func runSimulation(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	var games int
+	var bots string
+	var workers int
+	var seed int64
+	var format string
+	var manifestPath string
+	var ties string
+	var deckPath string
+	flags.IntVar(&games, "games", 100, "number of games to play, ex. 1000")
+	flags.StringVar(&bots, "bots", "greedy,random", "number of random bots or their strategies, ex. 3 or greedy,lookahead")
+	flags.IntVar(&workers, "workers", runtime.NumCPU(), "number of games played in parallel, ex. 8")
+	flags.Int64Var(&seed, "seed", 1, "seed of the first game, game i is played with seed + i")
+	flags.StringVar(&format, "format", "table", "format of the report: table or csv")
+	flags.StringVar(&manifestPath, "manifest", "", "comma separated card manifests to play with, base is the embedded manifest, which is used if it is empty, ex. base,expansion.json")
+	flags.StringVar(&deckPath, "deck", "", "game-config file with more manifests, the card ids to include or exclude and the deck size of every vegetable, ex. deck.json")
+	flags.StringVar(&ties, "ties", "first", "who scores a tied MOST or FEWEST card: first, shared or none, ex. shared")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if format != "table" && format != "csv" {
+		return fmt.Errorf("Unknown format %q, expected table or csv", format)
+	}
+
+	botNum, strategies, err := parseBots(bots)
+	if err != nil {
+		return err
+	}
+	for len(strategies) < botNum {
+		strategies = append(strategies, "random")
+	}
+
+	report, err := game.SimulatePointSaladGames(game.SimulationConfig{
+		Games:        games,
+		Strategies:   strategies,
+		Workers:      workers,
+		Seed:         seed,
+		ManifestPath: manifestPath,
+		DeckPath:     deckPath,
+		TiePolicy:    ties,
+	})
+	if err != nil {
+		return err
+	}
+	if format == "csv" {
+		fmt.Fprint(w, report.CSV())
+	} else {
+		fmt.Fprint(w, report.Table())
+	}
+	return nil
+}
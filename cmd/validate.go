@@ -0,0 +1,42 @@
+package main
+
+import (
+	"HomeExam/game"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// runValidateManifest runs the validate-manifest subcommand, which parses the criteria of every
+// vegetable of every card in a manifest and prints every failure, not only the first one.
+//
+// Parameters:
+//   - args: The arguments after the subcommand, ex. -manifest pointsaladManifest.json.
+//   - w: Where the failures and the summary are printed.
+//
+// Returns:
+//   - error: An error if a manifest or the game-config file cannot be read or used, or any criteria fails to parse.
+func runValidateManifest(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("validate-manifest", flag.ContinueOnError)
+	var manifestPath string
+	var deckPath string
+	flags.StringVar(&manifestPath, "manifest", "", "comma separated card manifests to validate, base is the embedded manifest, which is used if it is empty, ex. base,expansion.json")
+	flags.StringVar(&deckPath, "deck", "", "game-config file to validate together with its manifests, ex. deck.json")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	cardNum, manifestErrors, err := game.ValidatePointSaladManifest(manifestPath, deckPath)
+	if err != nil {
+		return err
+	}
+	for _, e := range manifestErrors {
+		fmt.Fprintf(w, "%s\n", e)
+	}
+	if len(manifestErrors) > 0 {
+		return fmt.Errorf("%d invalid criteria in %d cards", len(manifestErrors), cardNum)
+	}
+	fmt.Fprintf(w, "All criteria of the %d cards are valid\n", cardNum)
+	return nil
+}
@@ -2,20 +2,44 @@ package game
 
 import (
 	"HomeExam/game/pointsalad"
+	"io"
 )
 
+// HostConfig holds the settings of a hosted game, such as the number of players and bots.
+type HostConfig = pointsalad.HostConfig
+
+// SimulationConfig holds the settings of a batch of bot games, such as the number of games and the strategies.
+type SimulationConfig = pointsalad.SimulationConfig
+
+// SimulationReport holds the win rates, scores and game lengths of a batch of bot games.
+type SimulationReport = pointsalad.SimulationReport
+
+// ManifestError describes a criteria of a card in a manifest that cannot be parsed.
+type ManifestError = pointsalad.ManifestError
+
 // Game defines the interface for a game that can be initialized, run in a host or player mode, and provides information about
 // the maximum data size allowed for host and player communication.
 //
 // Methods:
-//   - Init(playerNum int, botNum int): Initializes the game with a specified number of players and bots.
-//   - RunHost(in map[int]chan []byte, out map[int]chan []byte): Starts the game in host mode, managing communication between players and bots.
+//   - Init(config HostConfig) error: Initializes the game with a specified number of players and bots and the other settings in config,
+//     returns an error if the settings, the save or the manifests cannot be used.
+//   - RunHost(in map[int]chan []byte, out map[int]chan []byte, status map[int]chan bool, spectators chan chan []byte): Starts the game in host mode,
+//     managing communication between players and bots and sending every broadcast to the spectators.
+//   - GetPlayerNum(): Returns the number of human players the host waits for once it is initialized.
+//   - GetSeatNames(): Returns the names the players of a resumed game connected with, keyed by actor id, nil for a new game.
+//   - SetPlayerNames(names map[int]string): Sets the names the players chose when connecting, keyed by actor id, must be called before RunHost.
+//   - SetPlayerBots(strategies map[int]string): Sets the strategies of the players that connected as bots, keyed by actor id,
+//     must be called before RunHost.
 //   - RunPlayer(in chan []byte, out chan []byte): Starts the game in player mode, allowing a human player to interact with the game.
 //   - GetMaxHostDataSize(): Returns the maximum data size that can be received by the host (server).
 //   - GetMaxPlayerDataSize(): Returns the maximum data size that can be sent by the player (client).
 type GameHost interface {
-	Init(playerNum int, botNum int)
-	RunHost(in map[int]chan []byte, out map[int]chan []byte)
+	Init(config HostConfig) error
+	RunHost(in map[int]chan []byte, out map[int]chan []byte, status map[int]chan bool, spectators chan chan []byte)
+	GetPlayerNum() int
+	GetSeatNames() map[int]string
+	SetPlayerNames(names map[int]string)
+	SetPlayerBots(strategies map[int]string)
 	GetMaxHostDataSize() int
 }
 
@@ -32,3 +56,38 @@ func CreatePointSaladHost() GameHost {
 func CreatePointSaladPlayer() GamePlayer {
 	return &pointsalad.GamePlayerState{}
 }
+
+// CreatePointSaladBotPlayer creates a player that connects like a human would but picks its
+// actions with the named bot strategy instead of reading them from standard input.
+func CreatePointSaladBotPlayer(strategyName string) (GamePlayer, error) {
+	strategy, err := pointsalad.GetBotStrategy(strategyName)
+	if err != nil {
+		return nil, err
+	}
+	player := &pointsalad.GamePlayerState{}
+	player.InitBot(strategy)
+	return player, nil
+}
+
+// ReplayPointSaladGame rebuilds a game recorded by a Point Salad host, prints every step to w and
+// verifies that the final scores match the recording. manifestPath is a comma separated list of manifests,
+// an empty list uses the embedded manifest, and deckPath the game-config file the game was played with, if any.
+func ReplayPointSaladGame(path string, manifestPath string, deckPath string, w io.Writer) error {
+	return pointsalad.ReplayGame(path, manifestPath, deckPath, w)
+}
+
+// CheckPointSaladConfig checks the number of players and bots and the bot strategies of a new Point Salad game.
+func CheckPointSaladConfig(config HostConfig) error {
+	return pointsalad.CheckHostConfig(config)
+}
+
+// ValidatePointSaladManifest checks that every criteria of every card in the Point Salad manifests, with the
+// cards a game-config file leaves out left out, can be parsed and returns the number of cards together with every failure.
+func ValidatePointSaladManifest(manifestPath string, deckPath string) (int, []ManifestError, error) {
+	return pointsalad.ValidateManifest(manifestPath, deckPath)
+}
+
+// SimulatePointSaladGames plays a batch of Point Salad games between bots in-process and summarizes the results.
+func SimulatePointSaladGames(config SimulationConfig) (SimulationReport, error) {
+	return pointsalad.SimulateGames(config)
+}
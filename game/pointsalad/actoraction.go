@@ -2,9 +2,7 @@ package pointsalad
 
 import (
 	"fmt"
-	"math/rand"
 	"strconv"
-	"strings"
 )
 
 type ActorActionType int
@@ -26,7 +24,9 @@ type ActorAction struct {
 // getMarketActionFromBot generates a random market action for a bot player. The bot either chooses
 // to pick vegetables or point cards from the market. It performs this action only if the action is legal
 // and results in an equal or better score compared to the current game state. It ensures that the action
-// chosen is beneficial by simulating the effect of the action before finalizing it.
+// chosen is beneficial by simulating the effect of the action before finalizing it. The random choices
+// are drawn from the game's random number generator. If no random action keeps the score within
+// botAttempts tries, since every legal action may lower it, the best legal action is chosen instead.
 //
 // Parameters:
 //   - s: The current game state (GameHostState) to evaluate the action on.
@@ -39,18 +39,22 @@ func getMarketActionFromBot(s *GameHostState) ActorAction {
 	marketWidth := getMarketWidth(&s.market)
 	marketHeight := getMarketHeight(&s.market)
 	var action ActorAction
-	for {
+	for attempt := 0; ; attempt += 1 {
+		if attempt == botAttempts {
+			action = getBestAction(s, getLegalMarketActions(s))
+			break
+		}
 		action = ActorAction{}
-		if rand.Intn(2) == 0 {
+		if s.rng.Intn(2) == 0 {
 			action.kind = pickVegFromMarket
-			action.amount = rand.Intn(2) + 1
+			action.amount = s.rng.Intn(2) + 1
 			for i := range action.amount {
-				action.ids[i] = rand.Intn(marketWidth * marketHeight)
+				action.ids[i] = s.rng.Intn(marketWidth * marketHeight)
 			}
 		} else {
 			action.kind = pickPointFromMarket
 			action.amount = 1
-			action.ids[0] = rand.Intn(marketWidth)
+			action.ids[0] = s.rng.Intn(marketWidth)
 		}
 		err := isActionLegal(s, action)
 		if err == nil {
@@ -76,7 +80,8 @@ func getMarketActionFromBot(s *GameHostState) ActorAction {
 // getSwapActionFromBot generates a random swap action for a bot player. The bot chooses to swap one or
 // two point cards from their point pile with vegetables from the market. The action is only accepted
 // if it is legal and results in a score that is equal to or greater than the previous score.
-// The bot makes sure the chosen swap is beneficial by simulating the result first.
+// The bot makes sure the chosen swap is beneficial by simulating the result first. Not swapping
+// never changes the score, so a swap is always found.
 //
 // Parameters:
 //   - s: The current game state (GameHostState) to evaluate the swap action on.
@@ -89,13 +94,13 @@ func getSwapActionFromBot(s *GameHostState) ActorAction {
 	assert(len(s.actorData[s.activeActor].pointPile) > 0)
 
 	action := ActorAction{}
-	for true {
+	for {
 		action.kind = pickToSwap
-		action.amount = rand.Intn(2)
+		action.amount = s.rng.Intn(2)
 
 		for i := range action.amount {
 			n := len(s.actorData[s.activeActor].pointPile)
-			action.ids[i] = rand.Intn(n)
+			action.ids[i] = s.rng.Intn(n)
 		}
 
 		err := isActionLegal(s, action)
@@ -116,28 +121,105 @@ func getSwapActionFromBot(s *GameHostState) ActorAction {
 	return action
 }
 
+// botAttempts is how many random actions getMarketActionFromBot tries before it settles for the best legal action.
+const botAttempts = 1000
+
+// getLegalMarketActions returns every legal market action of the active actor: taking one or two
+// vegetables from the market or taking the top point card of a pile.
+func getLegalMarketActions(s *GameHostState) []ActorAction {
+	actions := []ActorAction{}
+	marketSize := getMarketWidth(&s.market) * getMarketHeight(&s.market)
+	for i := range marketSize {
+		if !hasCard(&s.market, i) {
+			continue
+		}
+		actions = append(actions, ActorAction{kind: pickVegFromMarket, amount: 1, ids: [2]int{i, 0}})
+		for j := i + 1; j < marketSize; j += 1 {
+			if hasCard(&s.market, j) {
+				actions = append(actions, ActorAction{kind: pickVegFromMarket, amount: 2, ids: [2]int{i, j}})
+			}
+		}
+	}
+	for i := range getMarketWidth(&s.market) {
+		if len(s.market.piles[i]) > 0 {
+			actions = append(actions, ActorAction{kind: pickPointFromMarket, amount: 1, ids: [2]int{i, 0}})
+		}
+	}
+	return actions
+}
+
+// getLegalSwapActions returns every legal swap action of the active actor, starting with not swapping.
+func getLegalSwapActions(s *GameHostState) []ActorAction {
+	actions := []ActorAction{{kind: pickToSwap}}
+	for i := range s.actorData[s.activeActor].pointPile {
+		actions = append(actions, ActorAction{kind: pickToSwap, amount: 1, ids: [2]int{i, 0}})
+	}
+	return actions
+}
+
+// getScoreAfterAction returns the score of the active actor after the action, without changing the state.
+func getScoreAfterAction(s *GameHostState, action ActorAction) int {
+	new_s := deepCloneGameHostState(s)
+	doAction(&new_s, action)
+	return calculateScore(&new_s, new_s.activeActor)
+}
+
+// getBestAction returns the action giving the active actor the highest score, the first one on ties.
+func getBestAction(s *GameHostState, actions []ActorAction) ActorAction {
+	assert(len(actions) > 0)
+	best := actions[0]
+	bestScore := getScoreAfterAction(s, best)
+	for _, action := range actions[1:] {
+		score := getScoreAfterAction(s, action)
+		if score > bestScore {
+			best = action
+			bestScore = score
+		}
+	}
+	return best
+}
+
 func isWithinAtoF(a byte) bool {
 	return a >= 'A' && a <= 'F'
 }
 
+// parsePlayerInput parses a line typed by a player into an action for the requested phase.
+// Typing 'Q' quits the game in every phase.
+//
+// Parameters:
+//   - phase: The phase the host requested an action for.
+//   - input: A slice of bytes representing the player's input.
+//
+// Returns:
+//   - ActorAction: The action generated based on the player's input.
+//   - error: An error if the input is not a well formed action for the phase.
+func parsePlayerInput(phase Phase, input []byte) (ActorAction, error) {
+	if len(input) == 1 && input[0] == 'Q' {
+		return ActorAction{kind: Quit}, nil
+	}
+	if phase == PhaseSwap {
+		return parseSwapActionFromPlayer(input)
+	}
+	return parseMarketActionFromPlayer(input)
+}
+
 // parseMarketActionFromPlayer parses a player's input for a market action. The input specifies
 // the player's choice of picking point cards or vegetables from the market. The function interprets
 // the input based on the format and returns an ActorAction representing the player's decision.
-// If the input is invalid or the action is illegal, an error is returned.
+// Whether the action is legal is decided by the host once it receives the action.
 //
 // Parameters:
-//   - s: The current game state (GameHostState).
 //   - input: A slice of bytes representing the player's input.
 //
 // Returns:
 //   - ActorAction: The action generated based on the player's input.
-//   - error: An error if the input is invalid or the action is not legal.
+//   - error: An error if the input is invalid.
 //
 // The input can be:
 //   - A single digit ('0'-'9') to pick a point card from the market.
 //   - A single letter ('A'-'F') to pick a vegetable from the market.
 //   - Two letters ('A'-'F') to pick two vegetables from the market.
-func parseMarketActionFromPlayer(s *GameHostState, input []byte) (ActorAction, error) {
+func parseMarketActionFromPlayer(input []byte) (ActorAction, error) {
 	action := ActorAction{}
 
 	if len(input) == 1 && input[0] >= '0' && input[0] <= '9' {
@@ -155,44 +237,35 @@ func parseMarketActionFromPlayer(s *GameHostState, input []byte) (ActorAction, e
 	} else {
 		return action, fmt.Errorf("Invalid input")
 	}
-	err := isActionLegal(s, action)
-	if err != nil {
-		return action, err
-	}
 	return action, nil
 }
 
 // parseSwapActionFromPlayer parses a player's input for a swap action. The player can either
 // choose to skip the swap ('n') or specify an index to swap a point card with a vegetable from the market.
-// If the input is invalid or the swap is not legal, an error is returned.
+// Whether the swap is legal is decided by the host once it receives the action.
 //
 // Parameters:
-//   - s: The current game state (GameHostState).
 //   - input: A slice of bytes representing the player's input.
 //
 // Returns:
 //   - ActorAction: The action generated based on the player's input (either no swap or a specific swap).
-//   - error: An error if the input is invalid or the swap is not legal.
+//   - error: An error if the input is invalid.
 //
 // The input can be:
 //   - 'n' to indicate no swap.
 //   - A number to indicate the index of the point card the player wants to swap.
-func parseSwapActionFromPlayer(s *GameHostState, input []byte) (ActorAction, error) {
+func parseSwapActionFromPlayer(input []byte) (ActorAction, error) {
 	action := ActorAction{}
 
-	if input[0] == 'n' {
+	if len(input) == 1 && input[0] == 'n' {
 		action = ActorAction{kind: pickToSwap, amount: 0}
 	} else {
 		index, err := strconv.Atoi(string(input))
 		if err != nil {
-			return action, fmt.Errorf("Expected a number or 'n'\n")
+			return action, fmt.Errorf("Expected a number or 'n'")
 		}
 		action = ActorAction{kind: pickToSwap, amount: 1, ids: [2]int{index, 0}}
 	}
-	err := isActionLegal(s, action)
-	if err != nil {
-		return action, err
-	}
 	return action, nil
 }
 
@@ -310,39 +383,5 @@ func doAction(s *GameHostState, action ActorAction) {
 }
 
 func getActionString(s *GameHostState, action ActorAction) string {
-	assert(isActionLegal(s, action) == nil)
-	builder := strings.Builder{}
-
-	builder.WriteString("---- Action ----\n")
-	switch action.kind {
-	case Invalid:
-		panic("unreachable")
-	case pickVegFromMarket:
-		{
-			for i := range action.amount {
-				builder.WriteString(fmt.Sprintf("Player %d drew %v from market\n", s.activeActor, getCardFromMarket(&s.market, action.ids[i]).vegType))
-			}
-		}
-	case pickPointFromMarket:
-		{
-			for i := range action.amount {
-				pile := s.market.piles[action.ids[i]]
-				card := pile[len(pile)-1]
-				builder.WriteString(fmt.Sprintf("Player %d drew %v from market\n", s.activeActor, card))
-			}
-		}
-	case pickToSwap:
-		{
-			if action.amount == 0 {
-				builder.WriteString(fmt.Sprintf("Player %d did not swap any card\n", s.activeActor))
-			} else {
-				for i := range action.amount {
-					card := s.actorData[s.activeActor].pointPile[action.ids[i]]
-					criteria := card.criteria.String()
-					builder.WriteString(fmt.Sprintf("Player %d swapped %v to %v\n", s.activeActor, criteria, card.vegType))
-				}
-			}
-		}
-	}
-	return builder.String()
+	return renderAction(getActorInfo(s, s.activeActor).Name, getActionView(s, action))
 }
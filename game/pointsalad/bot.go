@@ -0,0 +1,502 @@
+package pointsalad
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// BotStrategy decides the actions of a bot. The same strategies are used by bots run
+// inside the host and by remote bot clients, which rebuild the game state from the
+// messages they receive.
+//
+// Methods:
+//   - getMarketAction(v BotView): Returns a legal market action for the active actor.
+//   - getSwapAction(v BotView): Returns a legal swap action for the active actor, only called when it has point cards.
+//   - String(): Returns the name the strategy is selected by.
+type BotStrategy interface {
+	getMarketAction(v BotView) ActorAction
+	getSwapAction(v BotView) ActorAction
+	String() string
+}
+
+// BotView is the read-only view of the game a BotStrategy decides on. Trying an action
+// returns a new view and leaves the game itself untouched.
+type BotView struct {
+	s *GameHostState
+}
+
+func (v BotView) ActiveActor() int {
+	return v.s.activeActor
+}
+
+func (v BotView) ActorNum() int {
+	return len(v.s.actorData)
+}
+
+func (v BotView) Score(actorId int) int {
+	return calculateScore(v.s, actorId)
+}
+
+// MarketActions returns every legal market action of the active actor.
+func (v BotView) MarketActions() []ActorAction {
+	return getLegalMarketActions(v.s)
+}
+
+// SwapActions returns every legal swap action of the active actor, including not swapping.
+func (v BotView) SwapActions() []ActorAction {
+	return getLegalSwapActions(v.s)
+}
+
+// After returns a view of the game after the active actor played the legal action.
+func (v BotView) After(action ActorAction) BotView {
+	s := deepCloneGameHostState(v.s)
+	doAction(&s, action)
+	return BotView{s: &s}
+}
+
+// WithActiveActor returns a view of the game where actorId is the active actor.
+func (v BotView) WithActiveActor(actorId int) BotView {
+	assert(actorId >= 0 && actorId < len(v.s.actorData))
+	s := deepCloneGameHostState(v.s)
+	s.activeActor = actorId
+	return BotView{s: &s}
+}
+
+// RandomStrategy picks random actions that do not lower its score.
+type RandomStrategy struct{}
+
+func (_ *RandomStrategy) getMarketAction(v BotView) ActorAction {
+	return getMarketActionFromBot(v.s)
+}
+
+func (_ *RandomStrategy) getSwapAction(v BotView) ActorAction {
+	return getSwapActionFromBot(v.s)
+}
+
+func (_ *RandomStrategy) String() string {
+	return "random"
+}
+
+// GreedyStrategy picks the action that gives it the highest score right away.
+type GreedyStrategy struct{}
+
+func (_ *GreedyStrategy) getMarketAction(v BotView) ActorAction {
+	return getBestAction(v.s, v.MarketActions())
+}
+
+func (_ *GreedyStrategy) getSwapAction(v BotView) ActorAction {
+	return getBestAction(v.s, v.SwapActions())
+}
+
+func (_ *GreedyStrategy) String() string {
+	return "greedy"
+}
+
+// LookaheadStrategy looks one ply of the other actors ahead. It tries each of its actions, lets
+// every other actor in turn answer with the market action that leaves that actor furthest ahead,
+// and picks the action that leaves it furthest ahead of the best other actor after the answers.
+// With two actors that is a minimax search of depth two. Only the visible market is used, the
+// cards flipped from the piles after an action are not guessed.
+type LookaheadStrategy struct{}
+
+func (_ *LookaheadStrategy) getMarketAction(v BotView) ActorAction {
+	return getBestLookaheadAction(v, v.MarketActions())
+}
+
+func (_ *LookaheadStrategy) getSwapAction(v BotView) ActorAction {
+	return getBestLookaheadAction(v, v.SwapActions())
+}
+
+func (_ *LookaheadStrategy) String() string {
+	return "lookahead"
+}
+
+// getBestLookaheadAction returns the action with the best margin after every other actor answered
+// it, the first one on ties.
+//
+// Parameters:
+//   - v: The view of the game, with the bot as the active actor.
+//   - actions: The legal actions to choose from.
+//
+// Returns:
+//   - ActorAction: The action with the best margin.
+func getBestLookaheadAction(v BotView, actions []ActorAction) ActorAction {
+	assert(len(actions) > 0)
+	self := v.ActiveActor()
+
+	best := actions[0]
+	bestMargin := 0
+	for i, action := range actions {
+		margin := getMarginAfterReplies(v.After(action), self)
+		if i == 0 || margin > bestMargin {
+			best = action
+			bestMargin = margin
+		}
+	}
+	return best
+}
+
+// getMarginAfterReplies lets every actor after self answer with the market action giving that
+// actor the best margin, in seat order, and returns the margin of self after the answers.
+//
+// Parameters:
+//   - v: The view of the game after the action of self.
+//   - self: The actor the margin is returned of.
+//
+// Returns:
+//   - int: The score of self minus the best score of the other actors after their answers.
+func getMarginAfterReplies(v BotView, self int) int {
+	for j := 1; j < v.ActorNum(); j += 1 {
+		other := v.WithActiveActor((self + j) % v.ActorNum())
+		actions := other.MarketActions()
+		if len(actions) == 0 {
+			break
+		}
+		reply := other.After(actions[0])
+		replyMargin := getMargin(reply, other.ActiveActor())
+		for _, action := range actions[1:] {
+			after := other.After(action)
+			if margin := getMargin(after, other.ActiveActor()); margin > replyMargin {
+				reply = after
+				replyMargin = margin
+			}
+		}
+		v = reply
+	}
+	return getMargin(v, self)
+}
+
+// getMargin returns the score of an actor minus the best score of the other actors.
+func getMargin(v BotView, actorId int) int {
+	bestOther := math.MinInt
+	for j := range v.ActorNum() {
+		if j != actorId {
+			bestOther = max(bestOther, v.Score(j))
+		}
+	}
+	if bestOther == math.MinInt {
+		return v.Score(actorId)
+	}
+	return v.Score(actorId) - bestOther
+}
+
+// GetBotStrategy returns the bot strategy with the given name.
+//
+// Parameters:
+//   - name: The name of the strategy, as returned by its String method. The budgets of ismcts can be left out, ex. ismcts or ismcts:5000.
+//
+// Returns:
+//   - BotStrategy: The strategy.
+//   - error: An error if there is no strategy with that name.
+func GetBotStrategy(name string) (BotStrategy, error) {
+	if strings.HasPrefix(name, "ismcts") {
+		return parseISMCTSStrategy(name)
+	}
+	strategies := []BotStrategy{
+		&RandomStrategy{},
+		&GreedyStrategy{},
+		&LookaheadStrategy{},
+	}
+	for _, strategy := range strategies {
+		if strategy.String() == name {
+			return strategy, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown bot strategy: %s", name)
+}
+
+// setBotStrategies sets the strategies of the bots of a game by name, in seat order. Bots without
+// a name play with the random strategy.
+//
+// Parameters:
+//   - s: The game state.
+//   - names: The names of the strategies, at most one per bot.
+//
+// Returns:
+//   - error: An error if there are more names than bots or a name is unknown.
+func setBotStrategies(s *GameHostState, names []string) error {
+	if len(names) > s.botNum {
+		return fmt.Errorf("Got %d bot strategies for %d bots", len(names), s.botNum)
+	}
+	s.botStrategies = []BotStrategy{}
+	for i := range s.botNum {
+		strategy := BotStrategy(&RandomStrategy{})
+		if i < len(names) {
+			var err error
+			strategy, err = GetBotStrategy(names[i])
+			if err != nil {
+				return err
+			}
+		}
+		s.botStrategies = append(s.botStrategies, strategy)
+	}
+	return nil
+}
+
+// getBotStrategy returns the strategy of a bot, or the random strategy for a player seat.
+func getBotStrategy(s *GameHostState, actorId int) BotStrategy {
+	i := actorId - s.playerNum
+	if i < 0 || i >= len(s.botStrategies) {
+		return &RandomStrategy{}
+	}
+	return s.botStrategies[i]
+}
+
+func getBotStrategyNames(s *GameHostState) []string {
+	names := []string{}
+	for _, strategy := range s.botStrategies {
+		names = append(names, strategy.String())
+	}
+	return names
+}
+
+// runPlayerWithStrategy plays the game as a remote bot, answering every ActionRequest from
+// the host with the action chosen by the strategy instead of reading input from a human.
+//
+// Parameters:
+//   - in: A channel from which the function receives messages from the host.
+//   - out: A channel to which the function sends the bot's actions back to the game.
+//   - strategy: The strategy deciding the actions.
+//   - rng: The random number generator the strategy draws from.
+//
+// Returns:
+//   - None. The function ends when the game is over or the input channel is closed.
+func runPlayerWithStrategy(in chan []byte, out chan []byte, strategy BotStrategy, rng *rand.Rand) {
+	assert(in != nil)
+	assert(out != nil)
+	assert(strategy != nil)
+	assert(rng != nil)
+
+	for {
+		data := <-in
+		if expectQuit(data) {
+			return
+		}
+		msg, err := decodeMessage(data)
+		if err != nil {
+			log.Printf("ERROR: %s\n", err)
+			continue
+		}
+		fmt.Printf("%s", renderMessage(msg))
+		if msg.Kind == MsgGameOver {
+			return
+		}
+		if expectResponse(msg) {
+			action := ActorAction{kind: Quit}
+			s, err := createGameHostStateFromMessage(msg, rng)
+			if err != nil {
+				log.Printf("ERROR: %s\n", err)
+			} else if msg.Phase == PhaseSwap {
+				action = strategy.getSwapAction(BotView{s: &s})
+			} else {
+				action = strategy.getMarketAction(BotView{s: &s})
+			}
+			view := actionToView(action)
+			out <- encodeMessage(Message{Kind: MsgAction, ActorId: msg.ActorId, Action: &view, Seq: msg.Seq})
+			if action.kind == Quit {
+				return
+			}
+		}
+	}
+}
+
+// createGameHostStateFromMessage rebuilds the game state as far as it is known to a player from an
+// ActionRequest. Only the top card of every pile is visible, the cards below it are sampled with
+// sampleHiddenPileCards so every pile has the size the host sent, and the criteria of the vegetables
+// in the market are unknown. Ties are scored with the tie policy of the host.
+//
+// Parameters:
+//   - msg: An ActionRequest carrying the market, the hands of all actors, the vegetables of the game and its tie policy.
+//   - rng: The random number generator the hidden cards are sampled with, the state keeps it for the bots.
+//
+// Returns:
+//   - GameHostState: The rebuilt state with the requested actor as the active actor.
+//   - error: An error if the message is missing data or contains unknown vegetables, criteria or tie policy.
+func createGameHostStateFromMessage(msg Message, rng *rand.Rand) (GameHostState, error) {
+	s := GameHostState{rng: rng}
+	if msg.Market == nil || len(msg.Market.Piles) == 0 {
+		return s, fmt.Errorf("Expected a market in %v", msg.Kind)
+	}
+	vegetables, err := getCatalogueOrBase(msg.Vegetables)
+	if err != nil {
+		return s, err
+	}
+	s.vegetables = vegetables
+	s.tiePolicy, err = ParseTiePolicy(string(msg.TiePolicy))
+	if err != nil {
+		return s, err
+	}
+
+	for _, spotView := range msg.Market.Spots {
+		spot := CardSpot{}
+		if spotView.HasCard {
+			vegType, ok := getVegetableType(vegetables, spotView.Vegetable)
+			if !ok {
+				return s, fmt.Errorf("Unknown vegetable: %s", spotView.Vegetable)
+			}
+			spot.hasCard = true
+			spot.card.vegType = vegType
+		}
+		s.market.cardSpots = append(s.market.cardSpots, spot)
+	}
+
+	for _, pileView := range msg.Market.Piles {
+		pile := []Card{}
+		if pileView.Top != nil {
+			card, err := createCardFromView(*pileView.Top, vegetables)
+			if err != nil {
+				return s, err
+			}
+			pile = append(pile, card)
+		}
+		s.market.piles = append(s.market.piles, pile)
+	}
+
+	for i, hand := range msg.Hands {
+		if hand.ActorId != i {
+			return s, fmt.Errorf("Expected hand of actor %d got %d", i, hand.ActorId)
+		}
+		actorData := ActorData{vegetableNum: make([]int, len(vegetables))}
+		for _, v := range hand.Vegetables {
+			vegType, ok := getVegetableType(vegetables, v.Vegetable)
+			if !ok {
+				return s, fmt.Errorf("Unknown vegetable: %s", v.Vegetable)
+			}
+			actorData.vegetableNum[vegType] = v.Count
+		}
+		for _, cardView := range hand.PointCards {
+			card, err := createCardFromView(cardView, vegetables)
+			if err != nil {
+				return s, err
+			}
+			actorData.pointPile = append(actorData.pointPile, card)
+		}
+		s.actorData = append(s.actorData, actorData)
+	}
+
+	if msg.ActorId < 0 || msg.ActorId >= len(s.actorData) {
+		return s, fmt.Errorf("Actor %d has no hand", msg.ActorId)
+	}
+	s.activeActor = msg.ActorId
+	s.playerNum = len(s.actorData)
+
+	sizes := []int{}
+	for _, pileView := range msg.Market.Piles {
+		sizes = append(sizes, pileView.Size)
+	}
+	sampleHiddenPileCards(&s, sizes)
+	return s, nil
+}
+
+// sampleHiddenPileCards puts cards below the top card of every pile until the piles have the sizes
+// the host sent. In a game of the base vegetables the cards are drawn from the embedded manifest
+// without the point cards that are visible on the piles and in the hands. The manifests of other
+// games are only known to the host, so their hidden cards are drawn from the visible point cards.
+//
+// Parameters:
+//   - s: The rebuilt state, its piles hold only their top card and its rng is set.
+//   - sizes: The size of every pile at the host.
+//
+// Returns:
+//   - None. The piles of s are filled in place.
+func sampleHiddenPileCards(s *GameHostState, sizes []int) {
+	assert(s.rng != nil)
+	assert(len(sizes) == len(s.market.piles))
+
+	visible := []Card{}
+	for _, pile := range s.market.piles {
+		visible = append(visible, pile...)
+	}
+	for _, actorData := range s.actorData {
+		visible = append(visible, actorData.pointPile...)
+	}
+	if len(visible) == 0 {
+		return
+	}
+
+	unseen := []Card{}
+	if slices.Equal(s.vegetables, baseCatalogue) {
+		unseen = getUnseenBaseCards(visible)
+	}
+	s.rng.Shuffle(len(unseen), func(i int, j int) {
+		unseen[i], unseen[j] = unseen[j], unseen[i]
+	})
+
+	for i, pile := range s.market.piles {
+		hidden := []Card{}
+		for len(hidden)+len(pile) < sizes[i] {
+			if len(unseen) > 0 {
+				hidden = append(hidden, unseen[0])
+				unseen = unseen[1:]
+			} else {
+				hidden = append(hidden, visible[s.rng.Intn(len(visible))])
+			}
+		}
+		s.market.piles[i] = append(hidden, pile...)
+	}
+}
+
+// getUnseenBaseCards returns every point card of the embedded manifest that is not one of the
+// visible cards. A card is told apart by its vegetable and criteria, every visible card takes away
+// one matching card.
+//
+// Parameters:
+//   - visible: The point cards the player can see.
+//
+// Returns:
+//   - []Card: The cards of the embedded manifest that are not visible, empty if it cannot be read.
+func getUnseenBaseCards(visible []Card) []Card {
+	jsonCards, _, err := loadManifest(baseManifest)
+	if err != nil {
+		log.Printf("ERROR: %s\n", err)
+		return []Card{}
+	}
+	key := func(card Card) string {
+		return fmt.Sprintf("%d %s", card.vegType, card.criteria)
+	}
+	seen := map[string]int{}
+	for _, card := range visible {
+		seen[key(card)] += 1
+	}
+	unseen := []Card{}
+	for id := range jsonCards.Cards {
+		for i := range jsonCards.Vegetables {
+			criteria, err := parseCriteria(getJCriteria(&jsonCards, VegType(i), id), jsonCards.Vegetables)
+			if err != nil {
+				continue
+			}
+			card := Card{criteria: criteria, vegType: VegType(i)}
+			if seen[key(card)] > 0 {
+				seen[key(card)] -= 1
+			} else {
+				unseen = append(unseen, card)
+			}
+		}
+	}
+	return unseen
+}
+
+// createCardFromView parses a card as it is sent to players and written to saves.
+//
+// Parameters:
+//   - view: The card.
+//   - vegetables: The vegetables of the game.
+//
+// Returns:
+//   - Card: The card.
+//   - error: An error if the vegetable is not in the catalogue or the criteria cannot be parsed.
+func createCardFromView(view CardView, vegetables Catalogue) (Card, error) {
+	vegType, ok := getVegetableType(vegetables, view.Vegetable)
+	if !ok {
+		return Card{}, fmt.Errorf("Unknown vegetable: %s", view.Vegetable)
+	}
+	criteria, err := parseCriteria(view.Criteria, vegetables)
+	if err != nil {
+		return Card{}, err
+	}
+	return Card{criteria: criteria, vegType: vegType}, nil
+}
@@ -0,0 +1,268 @@
+package pointsalad
+
+import (
+	"fmt"
+	"time"
+)
+
+// connectionEvent reports that a player connected or dropped, or that a spectator joined,
+// in which case spectator is the channel to send it messages on.
+type connectionEvent struct {
+	actorId   int
+	connected bool
+	spectator chan []byte
+}
+
+// afkTimeouts is the number of turn timeouts in a row after which a player is marked AFK.
+const afkTimeouts = 3
+
+// hostConnections keeps track of the players' connections while the host runs a game.
+// Players whose connection dropped keep their seat for the grace period, after which
+// the seat is played by a bot until the player reconnects. Players that run out of time
+// get their move made automatically, and are marked AFK if it keeps happening. Spectators
+// get every broadcast, starting with a snapshot of the game when they join.
+type hostConnections struct {
+	in         map[int]chan []byte
+	out        map[int]chan []byte
+	spectators []chan []byte
+	events     chan connectionEvent
+	done       chan bool
+	// who plays every seat, used in the notices
+	actors []ActorInfo
+
+	disconnectedAt map[int]time.Time
+	botFallback    map[int]bool
+	gracePeriod    time.Duration
+
+	timeouts    map[int]int
+	afk         map[int]bool
+	turnTimeout time.Duration
+	requestSeq  int
+}
+
+// createHostConnections merges the status channels of all players and the joining spectators into
+// a single event channel that the host can wait on together with the input of the active player.
+//
+// Parameters:
+//   - in: The channels to receive messages from the players on.
+//   - out: The channels to send messages to the players on.
+//   - status: The channels reporting the connection status of the players, may be nil.
+//   - spectators: The channel new spectators arrive on as the channel to send them messages on, may be nil.
+//   - actors: Who plays every seat, indexed by actor id.
+//   - gracePeriod: How long a disconnected player keeps its seat before a bot takes over.
+//   - turnTimeout: How long a player has to answer a request, 0 means no limit.
+//
+// Returns:
+//   - *hostConnections: The connections, stop has to be called once the game is over.
+func createHostConnections(in map[int]chan []byte, out map[int]chan []byte, status map[int]chan bool, spectators chan chan []byte, actors []ActorInfo, gracePeriod time.Duration, turnTimeout time.Duration) *hostConnections {
+	c := &hostConnections{
+		in:             in,
+		out:            out,
+		events:         make(chan connectionEvent),
+		done:           make(chan bool),
+		actors:         actors,
+		disconnectedAt: make(map[int]time.Time),
+		botFallback:    make(map[int]bool),
+		gracePeriod:    gracePeriod,
+		timeouts:       make(map[int]int),
+		afk:            make(map[int]bool),
+		turnTimeout:    turnTimeout,
+	}
+	for id, ch := range status {
+		go func() {
+			for {
+				select {
+				case <-c.done:
+					return
+				case connected := <-ch:
+					select {
+					case <-c.done:
+						return
+					case c.events <- connectionEvent{actorId: id, connected: connected}:
+					}
+				}
+			}
+		}()
+	}
+	if spectators != nil {
+		go func() {
+			for {
+				select {
+				case <-c.done:
+					return
+				case ch := <-spectators:
+					select {
+					case <-c.done:
+						return
+					case c.events <- connectionEvent{actorId: -1, spectator: ch}:
+					}
+				}
+			}
+		}()
+	}
+	return c
+}
+
+func (c *hostConnections) stop() {
+	close(c.done)
+}
+
+// isBot returns true if the actor is a bot or a player whose seat is currently played by a bot.
+func (c *hostConnections) isBot(actorId int) bool {
+	return c.in[actorId] == nil || c.botFallback[actorId]
+}
+
+func (c *hostConnections) send(actorId int, msg Message) {
+	c.out[actorId] <- encodeMessage(msg)
+}
+
+// broadcast sends a message to every player and spectator. Spectators never hold up the game,
+// one that cannot keep up is dropped.
+func (c *hostConnections) broadcast(msg Message) {
+	data := broadcastToAll(c.out, msg)
+	following := []chan []byte{}
+	for _, ch := range c.spectators {
+		if sendToSpectator(ch, data) {
+			following = append(following, ch)
+		}
+	}
+	c.spectators = following
+}
+
+// sendToSpectator sends data to a spectator without waiting for it. The channel of a spectator is
+// buffered, a spectator whose buffer is full cannot keep up with the game. Its channel is closed,
+// which tells the network to close its connection, and the host stops sending to it.
+//
+// Parameters:
+//   - ch: The channel of the spectator.
+//   - data: The message to send.
+//
+// Returns:
+//   - bool: false if the spectator was dropped.
+func sendToSpectator(ch chan []byte, data []byte) bool {
+	select {
+	case ch <- data:
+		return true
+	default:
+		close(ch)
+		return false
+	}
+}
+
+// notice tells everyone something about a player, text is formatted with the name of the player
+// followed by args.
+func (c *hostConnections) notice(actorId int, format string, args ...any) {
+	actor := c.actors[actorId]
+	args = append([]any{actor.Name}, args...)
+	c.broadcast(Message{Kind: MsgNotice, ActorId: actorId, Actor: &actor, Text: fmt.Sprintf(format, args...)})
+}
+
+// graceTimeout returns a channel that fires when the grace period of a disconnected player runs out,
+// or nil if the player is connected.
+func (c *hostConnections) graceTimeout(actorId int) <-chan time.Time {
+	t, disconnected := c.disconnectedAt[actorId]
+	if !disconnected {
+		return nil
+	}
+	return time.After(time.Until(t.Add(c.gracePeriod)))
+}
+
+// turnTimer returns a channel that fires when the turn timeout runs out, or nil if there is no limit.
+func (c *hostConnections) turnTimer() <-chan time.Time {
+	if c.turnTimeout <= 0 {
+		return nil
+	}
+	return time.After(c.turnTimeout)
+}
+
+// ranOutOfTime tells everyone that the move of a player was made automatically, and marks the
+// player AFK once it ran out of time afkTimeouts turns in a row.
+func (c *hostConnections) ranOutOfTime(actorId int) {
+	c.timeouts[actorId] += 1
+	c.notice(actorId, "%s did not answer within %v, the move is made automatically", c.turnTimeout)
+	if c.timeouts[actorId] >= afkTimeouts && !c.afk[actorId] {
+		c.afk[actorId] = true
+		c.notice(actorId, "%s is AFK")
+	}
+}
+
+// answeredInTime resets the timeouts of a player and tells everyone if it is no longer AFK.
+func (c *hostConnections) answeredInTime(actorId int) {
+	delete(c.timeouts, actorId)
+	if c.afk[actorId] {
+		delete(c.afk, actorId)
+		c.notice(actorId, "%s is back")
+	}
+}
+
+// handleEvent updates the connection status of a player and tells everyone about it.
+// A player that reconnects and a spectator that joins are sent the current state of the game.
+//
+// Parameters:
+//   - state: The current game state.
+//   - e: The connection event to handle.
+//
+// Returns:
+//   - None
+func (c *hostConnections) handleEvent(state *GameHostState, e connectionEvent) {
+	if e.spectator != nil {
+		c.addSpectator(state, e.spectator)
+		return
+	}
+	_, wasDisconnected := c.disconnectedAt[e.actorId]
+	if !e.connected {
+		if wasDisconnected || c.botFallback[e.actorId] {
+			return
+		}
+		c.disconnectedAt[e.actorId] = time.Now()
+		c.notice(e.actorId, "%s disconnected, waiting %v for reconnect…", c.gracePeriod)
+		return
+	}
+
+	delete(c.disconnectedAt, e.actorId)
+	if c.botFallback[e.actorId] {
+		delete(c.botFallback, e.actorId)
+		c.notice(e.actorId, "%s reconnected and took back the seat from the bot")
+	} else {
+		c.notice(e.actorId, "%s reconnected")
+	}
+	market := getMarketView(&state.market, state.vegetables)
+	c.send(e.actorId, Message{Kind: MsgStateUpdate, ActorId: state.activeActor, Actor: getMessageActor(state, state.activeActor), Market: &market, Hands: getHandViews(state), Vegetables: state.vegetables, TiePolicy: state.tiePolicy})
+}
+
+// pollEvents handles all connection events that are waiting without blocking, and hands the seats
+// of players whose grace period ran out to bots.
+func (c *hostConnections) pollEvents(state *GameHostState) {
+	for {
+		select {
+		case e := <-c.events:
+			c.handleEvent(state, e)
+		default:
+			for id, t := range c.disconnectedAt {
+				if time.Since(t) >= c.gracePeriod {
+					c.fallBackToBot(id)
+				}
+			}
+			return
+		}
+	}
+}
+
+func (c *hostConnections) fallBackToBot(actorId int) {
+	delete(c.disconnectedAt, actorId)
+	c.botFallback[actorId] = true
+	c.notice(actorId, "%s did not reconnect in time, a bot plays the seat")
+}
+
+// addSpectator sends a new spectator a snapshot of the market and of every hand, after which it
+// gets every broadcast. Spectators are never asked for an action.
+func (c *hostConnections) addSpectator(state *GameHostState, ch chan []byte) {
+	if !sendToSpectator(ch, encodeMessage(Message{Kind: MsgNotice, ActorId: -1, Text: fmt.Sprintf("You are spectating a game of %d players and %d bots", state.playerNum, state.botNum)})) {
+		return
+	}
+	market := getMarketView(&state.market, state.vegetables)
+	if !sendToSpectator(ch, encodeMessage(Message{Kind: MsgSnapshot, ActorId: state.activeActor, Actor: getMessageActor(state, state.activeActor), Market: &market, Hands: getHandViews(state), Vegetables: state.vegetables, TiePolicy: state.tiePolicy})) {
+		return
+	}
+	c.spectators = append(c.spectators, ch)
+}
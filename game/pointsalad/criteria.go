@@ -7,28 +7,23 @@ import (
 	"strings"
 )
 
-type JCriteria struct {
-	PEPPER  string
-	LETTUCE string
-	CARROT  string
-	CABBAGE string
-	ONION   string
-	TOMATO  string
-}
-
+// JCard is a card of a manifest, with a criteria for every vegetable of the manifest by vegetable name.
 type JCard struct {
 	Id       int
-	Criteria JCriteria
+	Criteria map[string]string
 }
 
+// JCards is a manifest, its cards and the vegetables they are played with. Vegetables is
+// empty in manifests of the base game.
 type JCards struct {
-	Cards []JCard
+	Vegetables Catalogue `json:"vegetables,omitempty"`
+	Cards      []JCard
 }
 
 // getJCriteria returns the specific criteria associated with a vegetable type
-// for a given card ID in the provided JCards structure. The function accesses
-// the appropriate vegetable criteria based on the VegType (such as PEPPER, LETTUCE, etc.)
-// and the card ID, then returns the corresponding criteria as a string.
+// for a given card ID in the provided JCards structure. The function looks up
+// the criteria by the name the vegetable has in the catalogue of jsonCards,
+// and returns the corresponding criteria as a string.
 //
 // Parameters:
 // - jsonCards: A pointer to the JCards structure that contains the card data.
@@ -36,42 +31,206 @@ type JCards struct {
 // - id: The card ID within the jsonCards structure to retrieve the criteria for.
 //
 // Returns:
-// - string: The criteria associated with the specified vegetable type and card ID.
-//
-// Panics:
-// - The function panics if the vegetable type provided does not match any known type (unreachable case).
+// - string: The criteria associated with the specified vegetable type and card ID, empty if the card has none.
 func getJCriteria(jsonCards *JCards, vegType VegType, id int) string {
-	Criteria := jsonCards.Cards[id].Criteria
-	switch vegType {
-	case PEPPER:
-		return Criteria.PEPPER
-	case LETTUCE:
-		return Criteria.LETTUCE
-	case CARROT:
-		return Criteria.CARROT
-	case CABBAGE:
-		return Criteria.CABBAGE
-	case ONION:
-		return Criteria.ONION
-	case TOMATO:
-		return Criteria.TOMATO
-	}
-	panic("unreachable")
+	return jsonCards.Cards[id].Criteria[jsonCards.Vegetables[vegType].Name]
 }
 
+// Criteria is the scoring rule of a point card. calculateScore is called for every move the bots
+// weigh and every game simulated, so it never formats, breakdown is only called to render a hand
+// or the final scores.
 type Criteria interface {
 	calculateScore(s *GameHostState, actorId int) int
+	breakdown(s *GameHostState, actorId int) scoreBreakdown
 	String() string
 }
 
+// TiePolicy decides who scores a MOST or FEWEST criteria when several actors share the most or fewest vegetables.
+type TiePolicy string
+
+const (
+	// TieFirstSeat gives the points to the tied actor with the lowest actor id, the rule the game
+	// was first played with. An empty TiePolicy is the same policy, so saves and recordings made
+	// before the policy existed are scored the way they were played.
+	TieFirstSeat TiePolicy = "first"
+	// TieShared gives the points to every tied actor.
+	TieShared TiePolicy = "shared"
+	// TieNobody gives the points to nobody if the most or fewest is tied.
+	TieNobody TiePolicy = "none"
+)
+
+// ParseTiePolicy returns the tie policy with the given name.
+//
+// Parameters:
+//   - name: "first", "shared" or "none", an empty name is the legacy "first".
+//
+// Returns:
+//   - TiePolicy: The tie policy.
+//   - error: An error if there is no tie policy with the name.
+func ParseTiePolicy(name string) (TiePolicy, error) {
+	switch TiePolicy(name) {
+	case "", TieFirstSeat:
+		return TieFirstSeat, nil
+	case TieShared, TieNobody:
+		return TiePolicy(name), nil
+	}
+	return "", fmt.Errorf("Unknown tie policy %q, expected first, shared or none", name)
+}
+
+// comparison is the outcome of comparing the count of one actor with the counts of every other
+// actor for a MOST or FEWEST criteria.
+type comparison struct {
+	// the count of the compared actor
+	own int
+	// the highest (or lowest) count of the other actors, equal to own if there are no other actors
+	others int
+	// the other actors sharing the best count with the compared actor, empty if it is not tied
+	tied []int
+	// whether the compared actor gets the points
+	wins bool
+}
+
+// points returns what the comparison earned the actor of a criteria worth score.
+func (c comparison) points(score int) int {
+	if c.wins {
+		return score
+	}
+	return 0
+}
+
+// compareActors compares the count of an actor with the counts of every other actor, settling
+// ties with the tie policy of the game. The actor is never compared with itself, it only has the
+// most (or fewest) if no other actor has more (or fewer).
+//
+// Parameters:
+//   - s: The current state of the game, its tie policy is used.
+//   - actorId: The ID of the actor being scored.
+//   - counts: The count of every actor, indexed by actor id.
+//   - most: true if the highest count wins, false if the lowest count wins.
+//
+// Returns:
+//   - comparison: The counts compared and whether the actor gets the points.
+func compareActors(s *GameHostState, actorId int, counts []int, most bool) comparison {
+	c := comparison{own: counts[actorId], others: counts[actorId], tied: []int{}}
+	first := true
+	for i, count := range counts {
+		if i == actorId {
+			continue
+		}
+		if first || (most && count > c.others) || (!most && count < c.others) {
+			c.others = count
+			first = false
+		}
+	}
+	if (most && c.others > c.own) || (!most && c.others < c.own) {
+		return c
+	}
+	for i, count := range counts {
+		if i != actorId && count == c.own {
+			c.tied = append(c.tied, i)
+		}
+	}
+	switch {
+	case len(c.tied) == 0:
+		c.wins = true
+	case s.tiePolicy == TieShared:
+		c.wins = true
+	case s.tiePolicy == TieNobody:
+		c.wins = false
+	default:
+		c.wins = actorId < c.tied[0]
+	}
+	return c
+}
+
+// scoreBreakdown is what a point card earned an actor and why. Every player and spectator is
+// shown every hand, so the reason never speaks to the owner of the card.
+type scoreBreakdown struct {
+	points int
+	reason string
+}
+
+// explainComparison explains the outcome of a MOST or FEWEST criteria, ex.
+// "MOST PEPPER: has 4, the most of the others is 4 (tie, ties are shared) → 10".
+//
+// Parameters:
+//   - s: The current state of the game, its tie policy is named for ties.
+//   - c: The outcome of the comparison.
+//   - name: The name of the criteria without its score, ex. "MOST PEPPER".
+//   - most: true for MOST, false for FEWEST.
+//   - score: The points of the criteria.
+//
+// Returns:
+//   - scoreBreakdown: The points earned and the explanation.
+func explainComparison(s *GameHostState, c comparison, name string, most bool, score int) scoreBreakdown {
+	points := c.points(score)
+	others := "the fewest of the others is"
+	if most {
+		others = "the most of the others is"
+	}
+	reason := fmt.Sprintf("%s: has %d, %s %d", name, c.own, others, c.others)
+	if len(c.tied) > 0 {
+		switch s.tiePolicy {
+		case TieShared:
+			reason += " (tie, ties are shared)"
+		case TieNobody:
+			reason += " (tie, ties score nothing)"
+		default:
+			reason += " (tie, ties go to the lowest seat)"
+		}
+	}
+	return scoreBreakdown{points: points, reason: fmt.Sprintf("%s → %d", reason, points)}
+}
+
+// getVegetableCounts returns how many vegetables of a type every actor has, indexed by actor id.
+func getVegetableCounts(s *GameHostState, vegType VegType) []int {
+	counts := []int{}
+	for _, actorData := range s.actorData {
+		counts = append(counts, actorData.vegetableNum[vegType])
+	}
+	return counts
+}
+
+// listVegetables formats the names of vegetable types for a score breakdown, ex. " (PEPPER, ONION)",
+// or an empty string if there are none.
+func listVegetables(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", strings.Join(names, ", "))
+}
+
+// countSets formats a number of sets for a score breakdown, ex. "1 set" or "2 sets".
+func countSets(num int, name string) string {
+	if num == 1 {
+		return fmt.Sprintf("1 %s", name)
+	}
+	return fmt.Sprintf("%d %ss", num, name)
+}
+
+// getVegetableTotals returns how many vegetables every actor has in total, indexed by actor id.
+func getVegetableTotals(s *GameHostState) []int {
+	totals := []int{}
+	for _, actorData := range s.actorData {
+		total := 0
+		for _, count := range actorData.vegetableNum {
+			total += count
+		}
+		totals = append(totals, total)
+	}
+	return totals
+}
+
 type CriteriaMost struct {
-	vegType VegType
-	score   int
+	vegType    VegType
+	score      int
+	vegetables Catalogue
 }
 
 // calculateScore calculates the score based on the actor's vegetable count for a specific vegetable type.
 // It checks whether the actor with the given actorId has the highest vegetable count for the specified vegetable type (vegType).
 // If the actor does not have the highest count, the function returns 0. Otherwise, it returns the score associated with this criterion.
+// If several actors have the highest count, the tie policy of the game decides who scores.
 //
 // Parameters:
 //   - s *GameHostState: The current state of the game containing actor data and vegetable counts.
@@ -80,20 +239,13 @@ type CriteriaMost struct {
 // Returns:
 //   - int: The calculated score for the actor.
 func (c *CriteriaMost) calculateScore(s *GameHostState, actorId int) int {
-	vegType := int(c.vegType)
+	return compareActors(s, actorId, getVegetableCounts(s, c.vegType), true).points(c.score)
+}
 
-	max := math.MinInt32
-	maxId := -1
-	for i, actorData := range s.actorData {
-		if actorData.vegetableNum[vegType] > max {
-			max = actorData.vegetableNum[vegType]
-			maxId = i
-		}
-	}
-	if maxId != actorId {
-		return 0
-	}
-	return c.score
+// breakdown explains whether the actor has the most vegetables of the type and what it earned.
+func (c *CriteriaMost) breakdown(s *GameHostState, actorId int) scoreBreakdown {
+	result := compareActors(s, actorId, getVegetableCounts(s, c.vegType), true)
+	return explainComparison(s, result, fmt.Sprintf("MOST %s", c.vegetables[c.vegType].Name), true, c.score)
 }
 
 // String returns a string representation of the CriteriaMost object, indicating the vegetable type and the associated score.
@@ -102,17 +254,19 @@ func (c *CriteriaMost) calculateScore(s *GameHostState, actorId int) int {
 // Returns:
 //   - string: The string representation of the criteria.
 func (c *CriteriaMost) String() string {
-	return fmt.Sprintf("MOST %v = %v", c.vegType, c.score)
+	return fmt.Sprintf("MOST %s = %v", c.vegetables[c.vegType].Name, c.score)
 }
 
 type CriteriaFewest struct {
-	vegType VegType
-	score   int
+	vegType    VegType
+	score      int
+	vegetables Catalogue
 }
 
 // calculateScore calculates the score based on the actor's vegetable count for a specific vegetable type,
 // but this time it checks whether the actor with the given actorId has the fewest vegetable count for the specified vegetable type (vegType).
 // If the actor does not have the fewest count, the function returns 0. Otherwise, it returns the score associated with this criterion.
+// If several actors have the fewest count, the tie policy of the game decides who scores.
 //
 // Parameters:
 //   - s *GameHostState: The current state of the game containing actor data and vegetable counts.
@@ -121,20 +275,13 @@ type CriteriaFewest struct {
 // Returns:
 //   - int: The calculated score for the actor.
 func (c *CriteriaFewest) calculateScore(s *GameHostState, actorId int) int {
-	vegType := int(c.vegType)
+	return compareActors(s, actorId, getVegetableCounts(s, c.vegType), false).points(c.score)
+}
 
-	min := math.MaxInt32
-	minId := -1
-	for i, actorData := range s.actorData {
-		if actorData.vegetableNum[vegType] < min {
-			min = actorData.vegetableNum[vegType]
-			minId = i
-		}
-	}
-	if minId != actorId {
-		return 0
-	}
-	return c.score
+// breakdown explains whether the actor has the fewest vegetables of the type and what it earned.
+func (c *CriteriaFewest) breakdown(s *GameHostState, actorId int) scoreBreakdown {
+	result := compareActors(s, actorId, getVegetableCounts(s, c.vegType), false)
+	return explainComparison(s, result, fmt.Sprintf("FEWEST %s", c.vegetables[c.vegType].Name), false, c.score)
 }
 
 // String returns a string representation of the CriteriaFewest object, indicating the vegetable type and the associated score.
@@ -143,13 +290,14 @@ func (c *CriteriaFewest) calculateScore(s *GameHostState, actorId int) int {
 // Returns:
 //   - string: The string representation of the criteria.
 func (c *CriteriaFewest) String() string {
-	return fmt.Sprintf("FEWEST %v = %v", c.vegType, c.score)
+	return fmt.Sprintf("FEWEST %s = %v", c.vegetables[c.vegType].Name, c.score)
 }
 
 type CriteriaEvenOdd struct {
-	vegType   VegType
-	evenScore int
-	oddScore  int
+	vegType    VegType
+	evenScore  int
+	oddScore   int
+	vegetables Catalogue
 }
 
 // calculateScore calculates the score based on the actor's vegetable count for a specific vegetable type,
@@ -171,6 +319,17 @@ func (c *CriteriaEvenOdd) calculateScore(s *GameHostState, actorId int) int {
 	}
 }
 
+// breakdown explains whether the actor has an even or odd number of the vegetable and what it earned.
+func (c *CriteriaEvenOdd) breakdown(s *GameHostState, actorId int) scoreBreakdown {
+	count := s.actorData[actorId].vegetableNum[c.vegType]
+	parity := "odd"
+	if count%2 == 0 {
+		parity = "even"
+	}
+	points := c.calculateScore(s, actorId)
+	return scoreBreakdown{points: points, reason: fmt.Sprintf("%s EVEN/ODD: has %d, %s → %d", c.vegetables[c.vegType].Name, count, parity, points)}
+}
+
 // String returns a string representation of the CriteriaEvenOdd object,
 // showing the vegetable type and the associated even and odd scores.
 //
@@ -178,11 +337,13 @@ func (c *CriteriaEvenOdd) calculateScore(s *GameHostState, actorId int) int {
 //   - string: The string representation of the criteria in the format:
 //     "<vegetable type>: EVEN=<evenScore>, ODD=<oddScore>"
 func (c *CriteriaEvenOdd) String() string {
-	return fmt.Sprintf("%v: EVEN=%v, ODD=%v", c.vegType, c.evenScore, c.oddScore)
+	return fmt.Sprintf("%s: EVEN=%v, ODD=%v", c.vegetables[c.vegType].Name, c.evenScore, c.oddScore)
 }
 
 type CriteriaPer struct {
-	perScores [vegetableTypeNum]int
+	// the score of every vegetable, indexed by VegType
+	perScores  []int
+	vegetables Catalogue
 }
 
 // calculateScore calculates the total score for an actor based on the vegetable quantities and their associated per-vegetable scores.
@@ -202,6 +363,18 @@ func (c *CriteriaPer) calculateScore(s *GameHostState, actorId int) int {
 	return score
 }
 
+// breakdown explains what every vegetable the criteria counts earned the actor.
+func (c *CriteriaPer) breakdown(s *GameHostState, actorId int) scoreBreakdown {
+	terms := []string{}
+	for i, perValue := range c.perScores {
+		if perValue != 0 {
+			terms = append(terms, fmt.Sprintf("%d %s × %d", s.actorData[actorId].vegetableNum[i], c.vegetables[i].Name, perValue))
+		}
+	}
+	points := c.calculateScore(s, actorId)
+	return scoreBreakdown{points: points, reason: fmt.Sprintf("PER VEGETABLE: %s → %d", strings.Join(terms, ", "), points)}
+}
+
 // String returns a string representation of the CriteriaPer object, showing the vegetable types and their associated per-scores.
 // The format is: "<score> / <vegetable type>" for each vegetable type where the score is non-zero. The result is a comma-separated list.
 //
@@ -215,10 +388,10 @@ func (c *CriteriaPer) String() string {
 	for i, score := range c.perScores {
 		if score != 0 {
 			if first {
-				builder.WriteString(fmt.Sprintf("%v / %v", score, VegType(i)))
+				builder.WriteString(fmt.Sprintf("%v / %s", score, c.vegetables[i].Name))
 				first = false
 			} else {
-				builder.WriteString(fmt.Sprintf(", %v / %v", score, VegType(i)))
+				builder.WriteString(fmt.Sprintf(", %v / %s", score, c.vegetables[i].Name))
 			}
 		}
 	}
@@ -226,8 +399,10 @@ func (c *CriteriaPer) String() string {
 }
 
 type CriteriaSum struct {
-	vegCount [vegetableTypeNum]int
-	score    int
+	// the number of every vegetable in the set, indexed by VegType
+	vegCount   []int
+	score      int
+	vegetables Catalogue
 }
 
 // calculateScore calculates the score for an actor based on the vegetable counts and their associated "vegCount" values.
@@ -258,6 +433,26 @@ func (c *CriteriaSum) calculateScore(s *GameHostState, actorId int) int {
 	return min * c.score
 }
 
+// breakdown explains how many sets of the vegetables the actor has and what they earned.
+func (c *CriteriaSum) breakdown(s *GameHostState, actorId int) scoreBreakdown {
+	names := []string{}
+	counts := []string{}
+	for i, count := range c.vegCount {
+		for range count {
+			names = append(names, c.vegetables[i].Name)
+		}
+		if count != 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", s.actorData[actorId].vegetableNum[i], c.vegetables[i].Name))
+		}
+	}
+	points := c.calculateScore(s, actorId)
+	sets := 0
+	if c.score != 0 {
+		sets = points / c.score
+	}
+	return scoreBreakdown{points: points, reason: fmt.Sprintf("SET OF %s: has %s, %s → %d", strings.Join(names, " + "), strings.Join(counts, ", "), countSets(sets, "set"), points)}
+}
+
 // String returns a string representation of the CriteriaSum object, showing the vegetable types used in the calculation
 // and the associated score. The format is: "<vegetable type> + <vegetable type> = <score>", where the vegetable types
 // are listed based on the vegCount array.
@@ -272,10 +467,10 @@ func (c *CriteriaSum) String() string {
 	for i, count := range c.vegCount {
 		for range count {
 			if first {
-				builder.WriteString(fmt.Sprintf("%v", VegType(i)))
+				builder.WriteString(c.vegetables[i].Name)
 				first = false
 			} else {
-				builder.WriteString(fmt.Sprintf(" + %v", VegType(i)))
+				builder.WriteString(fmt.Sprintf(" + %s", c.vegetables[i].Name))
 			}
 		}
 	}
@@ -288,33 +483,25 @@ type CriteriaMostTotal struct {
 }
 
 // calculateScore calculates the score for an actor based on their total number of vegetables compared to all other actors.
-// The actor's total vegetable count is compared to the total vegetable count of each other actor. If any other actor has
-// an equal or greater vegetable count, the score is set to 0. If the actor has more vegetables than all other actors, the
-// score is returned.
+// The actor's total vegetable count is compared to the total vegetable count of each other actor, but not to its own.
+// If another actor has more vegetables, the score is 0. If the actor has more vegetables than all other actors, the
+// score is returned. If other actors have as many vegetables, the tie policy of the game decides who scores.
 //
 // Parameters:
 //   - s *GameHostState: The current state of the game containing actor data and vegetable counts.
 //   - actorId int: The ID of the actor whose score is being calculated.
 //
 // Returns:
-//   - int: The calculated score for the actor, which is 0 if any other actor has an equal or greater total vegetable count,
-//     otherwise it returns the score associated with this criterion.
+//   - int: The calculated score for the actor, which is 0 if another actor has a greater total vegetable count,
+//     otherwise it returns the score associated with this criterion unless the tie policy rules it out.
 func (c *CriteriaMostTotal) calculateScore(s *GameHostState, actorId int) int {
-	vegCount := 0
-	for _, count := range s.actorData[actorId].vegetableNum {
-		vegCount += count
-	}
+	return compareActors(s, actorId, getVegetableTotals(s), true).points(c.score)
+}
 
-	for _, actorData := range s.actorData {
-		other_vegCount := 0
-		for _, count := range actorData.vegetableNum {
-			other_vegCount += count
-		}
-		if other_vegCount >= vegCount {
-			return 0
-		}
-	}
-	return c.score
+// breakdown explains whether the actor has the most vegetables in total and what it earned.
+func (c *CriteriaMostTotal) breakdown(s *GameHostState, actorId int) scoreBreakdown {
+	result := compareActors(s, actorId, getVegetableTotals(s), true)
+	return explainComparison(s, result, "MOST TOTAL VEGETABLE", true, c.score)
 }
 
 // String returns a string representation of the CriteriaMostTotal object, indicating that the criterion is based on the
@@ -332,33 +519,25 @@ type CriteriaFewestTotal struct {
 }
 
 // calculateScore calculates the score for an actor based on their total number of vegetables compared to all other actors.
-// The actor's total vegetable count is compared to the total vegetable count of each other actor. If any other actor has
-// an equal or fewer total vegetable count, the score is set to 0. If the actor has the fewest vegetables compared to all
-// other actors, the score is returned.
+// The actor's total vegetable count is compared to the total vegetable count of each other actor, but not to its own.
+// If another actor has fewer vegetables, the score is 0. If the actor has the fewest vegetables compared to all
+// other actors, the score is returned. If other actors have as few vegetables, the tie policy of the game decides who scores.
 //
 // Parameters:
 //   - s *GameHostState: The current state of the game containing actor data and vegetable counts.
 //   - actorId int: The ID of the actor whose score is being calculated.
 //
 // Returns:
-//   - int: The calculated score for the actor, which is 0 if any other actor has an equal or fewer total vegetable count,
-//     otherwise it returns the score associated with this criterion.
+//   - int: The calculated score for the actor, which is 0 if another actor has a smaller total vegetable count,
+//     otherwise it returns the score associated with this criterion unless the tie policy rules it out.
 func (c *CriteriaFewestTotal) calculateScore(s *GameHostState, actorId int) int {
-	vegCount := 0
-	for _, count := range s.actorData[actorId].vegetableNum {
-		vegCount += count
-	}
+	return compareActors(s, actorId, getVegetableTotals(s), false).points(c.score)
+}
 
-	for _, actorData := range s.actorData {
-		other_vegCount := 0
-		for _, count := range actorData.vegetableNum {
-			other_vegCount += count
-		}
-		if other_vegCount <= vegCount {
-			return 0
-		}
-	}
-	return c.score
+// breakdown explains whether the actor has the fewest vegetables in total and what it earned.
+func (c *CriteriaFewestTotal) breakdown(s *GameHostState, actorId int) scoreBreakdown {
+	result := compareActors(s, actorId, getVegetableTotals(s), false)
+	return explainComparison(s, result, "FEWEST TOTAL VEGETABLE", false, c.score)
 }
 
 // String returns a string representation of the CriteriaFewestTotal object, indicating that the criterion is based on the
@@ -397,14 +576,26 @@ func (c *CriteriaPerTypeGreaterThanEq) calculateScore(s *GameHostState, actorId
 	return score
 }
 
+// breakdown explains which vegetable types the actor has enough of and what they earned.
+func (c *CriteriaPerTypeGreaterThanEq) breakdown(s *GameHostState, actorId int) scoreBreakdown {
+	types := []string{}
+	for i, count := range s.actorData[actorId].vegetableNum {
+		if count >= c.greaterThanEq {
+			types = append(types, s.vegetables[i].Name)
+		}
+	}
+	points := c.calculateScore(s, actorId)
+	return scoreBreakdown{points: points, reason: fmt.Sprintf("VEGETABLE TYPES >=%d: has %d%s → %d", c.greaterThanEq, len(types), listVegetables(types), points)}
+}
+
 // String returns a string representation of the CriteriaPerTypeGreaterThanEq object, indicating the threshold for each
-// vegetable type and the associated score. The format is: "<vegetable type> / VEGETABLE TYPE >=<threshold>"
+// vegetable type and the associated score. The format is: "<score> / VEGETABLE TYPE >=<threshold>"
 //
 // Returns:
 //   - string: The string representation of the criteria in the format:
-//     "<vegetable type> / VEGETABLE TYPE >=<threshold>"
+//     "<score> / VEGETABLE TYPE >=<threshold>"
 func (c *CriteriaPerTypeGreaterThanEq) String() string {
-	return fmt.Sprintf("%v / VEGETABLE TYPE >=%v", c.greaterThanEq, c.score)
+	return fmt.Sprintf("%v / VEGETABLE TYPE >=%v", c.score, c.greaterThanEq)
 }
 
 type CriteriaPerMissingType struct {
@@ -431,6 +622,18 @@ func (c *CriteriaPerMissingType) calculateScore(s *GameHostState, actorId int) i
 	return score
 }
 
+// breakdown explains which vegetable types the actor is missing and what they earned.
+func (c *CriteriaPerMissingType) breakdown(s *GameHostState, actorId int) scoreBreakdown {
+	types := []string{}
+	for i, count := range s.actorData[actorId].vegetableNum {
+		if count == 0 {
+			types = append(types, s.vegetables[i].Name)
+		}
+	}
+	points := c.calculateScore(s, actorId)
+	return scoreBreakdown{points: points, reason: fmt.Sprintf("MISSING VEGETABLE TYPES: misses %d%s → %d", len(types), listVegetables(types), points)}
+}
+
 // String returns a string representation of the CriteriaPerMissingType object, indicating the associated score for each missing vegetable type.
 // The format is: "<score> / MISSING VEGETABLE TYPE"
 //
@@ -466,6 +669,16 @@ func (c *CriteriaCompleteSet) calculateScore(s *GameHostState, actorId int) int
 	return c.score * min
 }
 
+// breakdown explains how many complete sets the actor has and what they earned.
+func (c *CriteriaCompleteSet) breakdown(s *GameHostState, actorId int) scoreBreakdown {
+	sets := s.actorData[actorId].vegetableNum[0]
+	for _, count := range s.actorData[actorId].vegetableNum {
+		sets = min(sets, count)
+	}
+	points := c.calculateScore(s, actorId)
+	return scoreBreakdown{points: points, reason: fmt.Sprintf("COMPLETE SET: has %s → %d", countSets(sets, "complete set"), points)}
+}
+
 // String returns a string representation of the CriteriaCompleteSet object, indicating that the criterion is based on having
 // a complete set of vegetables, with the associated score. The format is: "COMPLETE SET = <score>"
 //
@@ -476,6 +689,61 @@ func (c *CriteriaCompleteSet) String() string {
 	return fmt.Sprintf("COMPLETE SET = %v", c.score)
 }
 
+type CriteriaThreshold struct {
+	vegType VegType
+	// whether the threshold is on the total number of vegetables instead of vegType
+	total      bool
+	atLeast    int
+	score      int
+	vegetables Catalogue
+}
+
+// calculateScore calculates the score for an actor that needs at least a number of vegetables of a type,
+// or in total. If the actor has at least atLeast vegetables the score is returned, otherwise 0.
+//
+// Parameters:
+//   - s *GameHostState: The current state of the game containing actor data and vegetable counts.
+//   - actorId int: The ID of the actor whose score is being calculated.
+//
+// Returns:
+//   - int: The score of the criteria if the actor reaches the threshold, otherwise 0.
+func (c *CriteriaThreshold) calculateScore(s *GameHostState, actorId int) int {
+	if c.count(s, actorId) < c.atLeast {
+		return 0
+	}
+	return c.score
+}
+
+// count returns the number of vegetables the threshold is on.
+func (c *CriteriaThreshold) count(s *GameHostState, actorId int) int {
+	if c.total {
+		return getVegetableTotals(s)[actorId]
+	}
+	return s.actorData[actorId].vegetableNum[c.vegType]
+}
+
+// breakdown explains whether the actor reaches the threshold and what it earned.
+func (c *CriteriaThreshold) breakdown(s *GameHostState, actorId int) scoreBreakdown {
+	name := fmt.Sprintf("%s >=%d", c.vegetables[c.vegType].Name, c.atLeast)
+	if c.total {
+		name = fmt.Sprintf("TOTAL VEGETABLE >=%d", c.atLeast)
+	}
+	points := c.calculateScore(s, actorId)
+	return scoreBreakdown{points: points, reason: fmt.Sprintf("%s: has %d → %d", name, c.count(s, actorId), points)}
+}
+
+// String returns a string representation of the CriteriaThreshold object, showing what is counted, the threshold and the score.
+//
+// Returns:
+//   - string: The string representation of the criteria in the format:
+//     "<vegetable type> >=<atLeast> = <score>" or "TOTAL VEGETABLE >=<atLeast> = <score>"
+func (c *CriteriaThreshold) String() string {
+	if c.total {
+		return fmt.Sprintf("TOTAL VEGETABLE >=%v = %v", c.atLeast, c.score)
+	}
+	return fmt.Sprintf("%s >=%v = %v", c.vegetables[c.vegType].Name, c.atLeast, c.score)
+}
+
 type TokenType int
 
 const (
@@ -493,86 +761,164 @@ const (
 type Token struct {
 	token_type TokenType
 	s          string
+	// the byte offset of the token in the source
+	pos int
 }
 
 type Lexer struct {
 	raw_src string
 	tokens  []Token
 	index   int
+	// the vegetables a criteria can name
+	vegetables Catalogue
 }
 
-func getToken(lex *Lexer) Token {
-	assert(lex.index < len(lex.tokens))
-	return lex.tokens[lex.index]
+// parseError is a syntax error in a criteria, with the position it was found at.
+type parseError struct {
+	src string
+	// the byte offset of the error in src, len(src) if the criteria ended too early
+	pos int
+	msg string
 }
 
-func nextToken(lex *Lexer) Token {
-	lex.index += 1
-	assert(lex.index < len(lex.tokens))
-	return lex.tokens[lex.index]
+func (e *parseError) Error() string {
+	return fmt.Sprintf("%s at column %d in %q", e.msg, e.pos+1, e.src)
 }
 
-func expectTokenStr(lex *Lexer, str string) error {
-	token := getToken(lex)
-	if token.s != str {
-		return fmt.Errorf("Expected %v got %v in %s\n", str, token.s, lex.raw_src)
+// errorAt returns a parseError at the current token, or at the end of the source if every token is used.
+func errorAt(lex *Lexer, format string, args ...any) error {
+	pos := len(lex.raw_src)
+	got := "the end of the criteria"
+	token, ok := peekToken(lex)
+	if ok {
+		pos = token.pos
+		got = fmt.Sprintf("%q", token.s)
 	}
-	return nil
+	return &parseError{src: lex.raw_src, pos: pos, msg: fmt.Sprintf(format, args...) + ", got " + got}
 }
 
-func expectNextTokenStr(lex *Lexer, str string) error {
-	token := nextToken(lex)
-	if token.s != str {
-		return fmt.Errorf("Expected %v got %v in %s\n", str, token.s, lex.raw_src)
+// tokenize splits a criteria into tokens, spaces only separate tokens.
+//
+// Parameters:
+//   - src: The criteria.
+//
+// Returns:
+//   - Lexer: The lexer positioned at the first token.
+//   - error: A parseError if src holds a character that is not part of any token.
+func tokenize(src string) (Lexer, error) {
+	lex := Lexer{raw_src: src}
+	symbols := map[byte]TokenType{'=': EQUAL, ':': COLON, ',': COMMA, '/': SLASH, '+': PLUS, '-': MINUS, '>': GREATER}
+	for i := 0; i < len(src); {
+		start := i
+		if src[i] == ' ' {
+			i += 1
+		} else if isAlpha(src[i]) {
+			for i < len(src) && isAlpha(src[i]) {
+				i += 1
+			}
+			lex.tokens = append(lex.tokens, Token{IDENTIFIER, src[start:i], start})
+		} else if isDigit(src[i]) {
+			for i < len(src) && isDigit(src[i]) {
+				i += 1
+			}
+			lex.tokens = append(lex.tokens, Token{NUMBER, src[start:i], start})
+		} else if token_type, ok := symbols[src[i]]; ok {
+			i += 1
+			lex.tokens = append(lex.tokens, Token{token_type, src[start:i], start})
+		} else {
+			return lex, &parseError{src: src, pos: i, msg: fmt.Sprintf("unknown character %q", src[i])}
+		}
 	}
-	return nil
+	return lex, nil
 }
 
-func expectTokenType(lex *Lexer, token_type TokenType) error {
-	token := getToken(lex)
-	if token.token_type != token_type {
-		return fmt.Errorf("Expected %v got %v in %s\n", token_type, token.token_type, lex.raw_src)
+// peekToken returns the current token without consuming it, false if every token is used.
+func peekToken(lex *Lexer) (Token, bool) {
+	if lex.index >= len(lex.tokens) {
+		return Token{}, false
 	}
-	return nil
+	return lex.tokens[lex.index], true
+}
+
+// acceptToken consumes the current token if it has the type and, unless str is empty, the text given.
+func acceptToken(lex *Lexer, token_type TokenType, str string) bool {
+	token, ok := peekToken(lex)
+	if !ok || token.token_type != token_type || (str != "" && token.s != str) {
+		return false
+	}
+	lex.index += 1
+	return true
 }
 
-func expectNextTokenType(lex *Lexer, token_type TokenType) error {
-	token := nextToken(lex)
-	if token.token_type != token_type {
-		return fmt.Errorf("Expected %v got %v in %s\n", token_type, token.token_type, lex.raw_src)
+// expectToken consumes the current token, which has to have the type and, unless str is empty, the text given.
+// what names the expected token in the error.
+func expectToken(lex *Lexer, token_type TokenType, str string, what string) error {
+	if !acceptToken(lex, token_type, str) {
+		return errorAt(lex, "expected %s", what)
 	}
 	return nil
 }
 
-func lookToken(lex *Lexer, pos int) (Token, bool) {
-	if lex.index+pos >= len(lex.tokens) {
-		return Token{}, false
+// parseNumber parses an integer with an optional minus sign.
+func parseNumber(lex *Lexer) (int, error) {
+	negative := acceptToken(lex, MINUS, "")
+	token, ok := peekToken(lex)
+	if !ok || token.token_type != NUMBER {
+		return 0, errorAt(lex, "expected a number")
+	}
+	num, err := strconv.Atoi(token.s)
+	if err != nil {
+		return 0, &parseError{src: lex.raw_src, pos: token.pos, msg: fmt.Sprintf("the number %s is too large", token.s)}
+	}
+	lex.index += 1
+	if negative {
+		num = -num
 	}
-	return lex.tokens[lex.index+pos], true
+	return num, nil
 }
 
-func parseNumber(lex *Lexer, minus_or_num Token) (int, error) {
-	var err error
-	num := 0
-	if minus_or_num.token_type == MINUS {
-		t := nextToken(lex)
-		expectTokenType(lex, NUMBER)
+// parseVegetable parses the name of a vegetable type.
+func parseVegetable(lex *Lexer) (VegType, error) {
+	token, ok := peekToken(lex)
+	if !ok || token.token_type != IDENTIFIER || !isVegetable(lex.vegetables, token.s) {
+		return 0, errorAt(lex, "expected a vegetable")
+	}
+	lex.index += 1
+	vegType, _ := getVegetableType(lex.vegetables, token.s)
+	return vegType, nil
+}
 
-		num, err = strconv.Atoi(t.s)
-		if err != nil {
-			return 0, err
-		}
-		num *= -1
+// parseScore parses the "= <number>" that ends most criteria.
+func parseScore(lex *Lexer) (int, error) {
+	err := expectToken(lex, EQUAL, "", `"="`)
+	if err != nil {
+		return 0, err
+	}
+	return parseNumber(lex)
+}
 
-	} else if minus_or_num.token_type == NUMBER {
-		num, err = strconv.Atoi(minus_or_num.s)
+// parseAtLeast parses the ">= <number>" of a threshold.
+func parseAtLeast(lex *Lexer) (int, error) {
+	err := expectToken(lex, GREATER, "", `">="`)
+	if err != nil {
+		return 0, err
+	}
+	err = expectToken(lex, EQUAL, "", `">="`)
+	if err != nil {
+		return 0, err
+	}
+	return parseNumber(lex)
+}
+
+// parseWords consumes a fixed phrase, ex. "VEGETABLE TYPE".
+func parseWords(lex *Lexer, words ...string) error {
+	for _, word := range words {
+		err := expectToken(lex, IDENTIFIER, word, word)
 		if err != nil {
-			return 0, err
+			return err
 		}
-	} else {
-		return 0, fmt.Errorf("Expected number or minus got %v\n", minus_or_num)
 	}
-	return num, nil
+	return nil
 }
 
 func isAlpha(char byte) bool {
@@ -583,307 +929,279 @@ func isDigit(char byte) bool {
 	return char >= '0' && char <= '9'
 }
 
-func isVegetable(s string) bool {
-
-	for i := range vegetableTypeNum {
-		if VegType(i).String() == s {
-			return true
-		}
-	}
-
-	return false
-}
-
-func getVegetableType(s string) VegType {
-	for i := range vegetableTypeNum {
-		if VegType(i).String() == s {
-			return VegType(i)
-		}
-	}
-	panic("unreachable")
-	return -1
-}
+// maxSetCount is the most vegetables of one type a set can ask for, so a set can be written out in full.
+const maxSetCount = 20
 
 // parseCriteria parses a string representing a criteria into a Criteria object.
-// The function takes a string `s` and tokenizes it into a series of tokens using a lexer. It then identifies the specific criteria
-// based on keywords and patterns found in the string and constructs the corresponding Criteria object, which can be of various types
-// such as CriteriaMost, CriteriaFewest, CriteriaCompleteSet, CriteriaPer, etc. The function handles different formats of criteria,
-// including operations on vegetable types, and supports complex expressions like "MOST TOTAL VEGETABLE" or "MOST / VEGETABLE TYPE >= N".
+// The criteria is split into tokens and parsed by recursive descent, following the grammar
+//
+//	criteria  = comparison | complete | vegetable-criteria | number-criteria | total-threshold
+//	comparison = ("MOST" | "FEWEST") (VEGETABLE | "TOTAL" "VEGETABLE") "=" NUMBER
+//	complete  = "COMPLETE" "SET" "=" NUMBER
+//	vegetable-criteria = VEGETABLE ":" "EVEN" "=" NUMBER "," "ODD" "=" NUMBER
+//	          | VEGETABLE ">=" NUMBER "=" NUMBER
+//	          | set
+//	set       = set-item {"+" set-item} "=" NUMBER
+//	set-item  = [NUMBER] VEGETABLE
+//	number-criteria = NUMBER "/" VEGETABLE {"," NUMBER "/" VEGETABLE}
+//	          | NUMBER "/" "VEGETABLE" "TYPE" ">=" NUMBER
+//	          | NUMBER "/" "MISSING" "VEGETABLE" "TYPE"
+//	          | set
+//	total-threshold = "TOTAL" "VEGETABLE" ">=" NUMBER "=" NUMBER
+//	NUMBER    = ["-"] digits
+//
+// where VEGETABLE is the name of a vegetable type of the catalogue. Spaces only separate tokens. Besides the phrases of the
+// printed cards the grammar accepts "N / VEG" lists of any length, sets with a number of every vegetable,
+// ex. "2 PEPPER + ONION = 8", and thresholds, ex. "PEPPER >=4 = 5" or "TOTAL VEGETABLE >=12 = 10", so new
+// cards can be written in a manifest without changing the program. Every Criteria is parsed back from its String.
 //
 // Parameters:
 //   - s string: The string representing the criteria to be parsed.
+//   - vegetables Catalogue: The vegetables of the game, the criteria keeps it to print their names.
 //
 // Returns:
 //   - Criteria: A Criteria object representing the parsed criteria. This can be of types such as CriteriaMost, CriteriaFewest,
 //     CriteriaCompleteSet, CriteriaPer, etc.
-//   - error: An error if the parsing fails, such as invalid syntax, unexpected tokens, or unsupported formats.
+//   - error: A parseError with the column the criteria went wrong at, if it does not follow the grammar.
 //
 // Example usage:
 //   - "MOST TOTAL VEGETABLE = 5" will return a CriteriaMostTotal object with score 5.
-//   - "FEWEST / VEGETABLE TYPE >= 2" will return a CriteriaPerTypeGreaterThanEq object with greaterThanEq set to 2 and score 5.
-func parseCriteria(s string) (Criteria, error) {
+//   - "5 / VEGETABLE TYPE >=2" will return a CriteriaPerTypeGreaterThanEq object with greaterThanEq set to 2 and score 5.
+//   - "MOST PEPPER =" will return an error at column 14, where the score is missing.
+func parseCriteria(s string, vegetables Catalogue) (Criteria, error) {
+	lex, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	lex.vegetables = vegetables
+	c, err := parseCriterion(&lex)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := peekToken(&lex); ok {
+		return nil, errorAt(&lex, "expected the end of the criteria")
+	}
+	return c, nil
+}
 
-	lex := Lexer{}
-	lex.raw_src = s
+// parseCriterion picks the rule of the grammar from the first tokens of the criteria.
+func parseCriterion(lex *Lexer) (Criteria, error) {
+	first, ok := peekToken(lex)
+	if !ok {
+		return nil, errorAt(lex, "expected a criteria")
+	}
+	switch {
+	case first.token_type == IDENTIFIER && (first.s == "MOST" || first.s == "FEWEST"):
+		return parseComparison(lex)
+	case first.token_type == IDENTIFIER && first.s == "COMPLETE":
+		err := parseWords(lex, "COMPLETE", "SET")
+		if err != nil {
+			return nil, err
+		}
+		score, err := parseScore(lex)
+		if err != nil {
+			return nil, err
+		}
+		return &CriteriaCompleteSet{score: score}, nil
+	case first.token_type == IDENTIFIER && first.s == "TOTAL":
+		err := parseWords(lex, "TOTAL", "VEGETABLE")
+		if err != nil {
+			return nil, err
+		}
+		return parseThreshold(lex, 0, true)
+	case first.token_type == IDENTIFIER && isVegetable(lex.vegetables, first.s):
+		return parseVegetableCriteria(lex)
+	case first.token_type == NUMBER || first.token_type == MINUS:
+		return parseNumberCriteria(lex)
+	}
+	return nil, errorAt(lex, "expected MOST, FEWEST, COMPLETE, TOTAL, a vegetable or a number")
+}
 
-	// tokenize the criterias
-	for i := 0; i < len(s); {
-		if s[i] == ' ' {
-			i += 1
-			continue
-		} else if isAlpha(s[i]) {
-			start := i
-			end := i
-			for i < len(s) && isAlpha(s[i]) {
-				i += 1
-				end += 1
-			}
+// parseComparison parses a MOST or FEWEST criteria, of a vegetable type or of the total.
+func parseComparison(lex *Lexer) (Criteria, error) {
+	most := acceptToken(lex, IDENTIFIER, "MOST")
+	if !most {
+		err := parseWords(lex, "FEWEST")
+		if err != nil {
+			return nil, err
+		}
+	}
+	total := acceptToken(lex, IDENTIFIER, "TOTAL")
+	vegType := VegType(0)
+	if total {
+		err := parseWords(lex, "VEGETABLE")
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		token, ok := peekToken(lex)
+		if !ok || token.token_type != IDENTIFIER || !isVegetable(lex.vegetables, token.s) {
+			return nil, errorAt(lex, "expected TOTAL or a vegetable")
+		}
+		vegType, _ = parseVegetable(lex)
+	}
+	score, err := parseScore(lex)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case most && total:
+		return &CriteriaMostTotal{score: score}, nil
+	case total:
+		return &CriteriaFewestTotal{score: score}, nil
+	case most:
+		return &CriteriaMost{vegType: vegType, score: score, vegetables: lex.vegetables}, nil
+	}
+	return &CriteriaFewest{vegType: vegType, score: score, vegetables: lex.vegetables}, nil
+}
 
-			token := Token{IDENTIFIER, s[start:end]}
-			lex.tokens = append(lex.tokens, token)
-		} else if isDigit(s[i]) {
-			start := i
-			end := i
-			for i < len(s) && isDigit(s[i]) {
-				i += 1
-				end += 1
-			}
+// parseVegetableCriteria parses the criteria starting with a vegetable: even/odd, a threshold or a set.
+func parseVegetableCriteria(lex *Lexer) (Criteria, error) {
+	start := lex.index
+	vegType, err := parseVegetable(lex)
+	if err != nil {
+		return nil, err
+	}
+	if acceptToken(lex, COLON, "") {
+		err = parseWords(lex, "EVEN")
+		if err != nil {
+			return nil, err
+		}
+		even, err := parseScore(lex)
+		if err != nil {
+			return nil, err
+		}
+		err = expectToken(lex, COMMA, "", `","`)
+		if err != nil {
+			return nil, err
+		}
+		err = parseWords(lex, "ODD")
+		if err != nil {
+			return nil, err
+		}
+		odd, err := parseScore(lex)
+		if err != nil {
+			return nil, err
+		}
+		return &CriteriaEvenOdd{vegType: vegType, evenScore: even, oddScore: odd, vegetables: lex.vegetables}, nil
+	}
+	if token, ok := peekToken(lex); ok && token.token_type == GREATER {
+		return parseThreshold(lex, vegType, false)
+	}
+	lex.index = start
+	return parseSet(lex)
+}
 
-			token := Token{NUMBER, s[start:end]}
-			lex.tokens = append(lex.tokens, token)
-		} else if s[i] == '=' {
-			lex.tokens = append(lex.tokens, Token{EQUAL, s[i : i+1]})
-			i += 1
-		} else if s[i] == ':' {
-			lex.tokens = append(lex.tokens, Token{COLON, s[i : i+1]})
-			i += 1
-		} else if s[i] == ',' {
-			lex.tokens = append(lex.tokens, Token{COMMA, s[i : i+1]})
-			i += 1
-		} else if s[i] == '/' {
-			lex.tokens = append(lex.tokens, Token{SLASH, s[i : i+1]})
-			i += 1
-		} else if s[i] == '+' {
-			lex.tokens = append(lex.tokens, Token{PLUS, s[i : i+1]})
-			i += 1
-		} else if s[i] == '-' {
-			lex.tokens = append(lex.tokens, Token{MINUS, s[i : i+1]})
-			i += 1
-		} else if s[i] == '>' {
-			lex.tokens = append(lex.tokens, Token{GREATER, s[i : i+1]})
-			i += 1
-		} else {
-			return nil, fmt.Errorf("Unknown character: %c", s[i])
-		}
-	}
-
-	for lex.index = 0; lex.index < len(lex.tokens); lex.index += 1 {
-		first := getToken(&lex)
-		if first.token_type == IDENTIFIER {
-			if first.s == "MOST" || first.s == "FEWEST" {
-				t := nextToken(&lex)
-				if t.s == "TOTAL" {
-
-					err := expectNextTokenStr(&lex, "VEGETABLE")
-					if err != nil {
-						return nil, err
-					}
-					err = expectNextTokenType(&lex, EQUAL)
-					if err != nil {
-						return nil, err
-					}
-
-					num, err := parseNumber(&lex, nextToken(&lex))
-					if err != nil {
-						return nil, err
-					}
-
-					if first.s == "MOST" {
-						return &CriteriaMostTotal{score: num}, nil
-					} else if first.s == "FEWEST" {
-						return &CriteriaFewestTotal{score: num}, nil
-					} else {
-						panic("unreachable")
-					}
-
-				} else if isVegetable(t.s) {
-					v := getVegetableType(t.s)
-					err := expectNextTokenType(&lex, EQUAL)
-					if err != nil {
-						return nil, err
-					}
-					num, err := parseNumber(&lex, nextToken(&lex))
-					if err != nil {
-						return nil, err
-					}
-
-					if first.s == "MOST" {
-						return &CriteriaMost{vegType: v, score: num}, nil
-					} else if first.s == "FEWEST" {
-						return &CriteriaFewest{vegType: v, score: num}, nil
-					} else {
-						panic("unreachable")
-					}
-				} else {
-					return nil, fmt.Errorf("Expected TOTAL or a vegetable type")
-				}
-			} else if first.s == "COMPLETE" {
-				err := expectNextTokenStr(&lex, "SET")
-				if err != nil {
-					return nil, err
-				}
-				err = expectNextTokenType(&lex, EQUAL)
-				if err != nil {
-					return nil, err
-				}
-
-				num, err := parseNumber(&lex, nextToken(&lex))
-				if err != nil {
-					return nil, err
-				}
-
-				return &CriteriaCompleteSet{score: num}, nil
-
-			} else if isVegetable(first.s) {
-				v := getVegetableType(first.s)
-				t := nextToken(&lex)
-				if t.token_type == COLON {
-					err := expectNextTokenStr(&lex, "EVEN")
-					if err != nil {
-						return nil, err
-					}
-					err = expectNextTokenType(&lex, EQUAL)
-					if err != nil {
-						return nil, err
-					}
-
-					even, err := parseNumber(&lex, nextToken(&lex))
-					if err != nil {
-						return nil, err
-					}
-					err = expectNextTokenType(&lex, COMMA)
-					if err != nil {
-						return nil, err
-					}
-					err = expectNextTokenStr(&lex, "ODD")
-					if err != nil {
-						return nil, err
-					}
-					err = expectNextTokenType(&lex, EQUAL)
-					if err != nil {
-						return nil, err
-					}
-
-					odd, err := parseNumber(&lex, nextToken(&lex))
-					if err != nil {
-						return nil, err
-					}
-
-					return &CriteriaEvenOdd{vegType: v, evenScore: even, oddScore: odd}, nil
-
-				} else if t.token_type == PLUS {
-					var vegCount [vegetableTypeNum]int
-					vegCount[int(v)] += 1
-					for true {
-						t := nextToken(&lex)
-						if !isVegetable(t.s) {
-							return nil, fmt.Errorf("Expected vegetable type")
-						}
-						v := getVegetableType(t.s)
-						vegCount[int(v)] += 1
-						if lex.index >= len(lex.tokens)-1 || nextToken(&lex).token_type != PLUS {
-							break
-						}
-					}
-					err := expectTokenType(&lex, EQUAL)
-					if err != nil {
-						return nil, err
-					}
-					num, err := parseNumber(&lex, nextToken(&lex))
-					if err != nil {
-						return nil, err
-					}
-
-					return &CriteriaSum{vegCount: vegCount, score: num}, nil
-
-				} else {
-					return nil, fmt.Errorf("Expected COLON or PLUS here")
-				}
-			} else {
-				return nil, fmt.Errorf("Expected MOST or FEWEST OR COMPLETE or a vegetable type here")
-			}
-		} else if first.token_type == NUMBER {
-			num, err := parseNumber(&lex, first)
+// parseThreshold parses the ">= N = score" of a threshold on a vegetable type or on the total.
+func parseThreshold(lex *Lexer, vegType VegType, total bool) (Criteria, error) {
+	atLeast, err := parseAtLeast(lex)
+	if err != nil {
+		return nil, err
+	}
+	score, err := parseScore(lex)
+	if err != nil {
+		return nil, err
+	}
+	return &CriteriaThreshold{vegType: vegType, total: total, atLeast: atLeast, score: score, vegetables: lex.vegetables}, nil
+}
+
+// parseSet parses a set of vegetables and its score, ex. "CARROT + ONION = 5" or "2 PEPPER + ONION = 8".
+func parseSet(lex *Lexer) (Criteria, error) {
+	vegCount := make([]int, len(lex.vegetables))
+	for {
+		count := 1
+		if token, ok := peekToken(lex); ok && token.token_type == NUMBER {
+			var err error
+			count, err = parseNumber(lex)
 			if err != nil {
 				return nil, err
 			}
-			err = expectNextTokenType(&lex, SLASH)
-			if err != nil {
-				return nil, err
+			if count < 1 || count > maxSetCount {
+				lex.index -= 1
+				return nil, errorAt(lex, "expected a count between 1 and %d", maxSetCount)
 			}
+		}
+		vegType, err := parseVegetable(lex)
+		if err != nil {
+			return nil, err
+		}
+		if vegCount[vegType]+count > maxSetCount {
+			lex.index -= 1
+			return nil, errorAt(lex, "expected at most %d %s in a set", maxSetCount, lex.vegetables[vegType].Name)
+		}
+		vegCount[vegType] += count
+		if !acceptToken(lex, PLUS, "") {
+			break
+		}
+	}
+	score, err := parseScore(lex)
+	if err != nil {
+		return nil, err
+	}
+	return &CriteriaSum{vegCount: vegCount, score: score, vegetables: lex.vegetables}, nil
+}
 
-			t := nextToken(&lex)
-			if t.s == "VEGETABLE" {
-				err = expectNextTokenStr(&lex, "TYPE")
-				if err != nil {
-					return nil, err
-				}
-				err = expectNextTokenType(&lex, GREATER)
-				if err != nil {
-					return nil, err
-				}
-				err = expectNextTokenType(&lex, EQUAL)
-				if err != nil {
-					return nil, err
-				}
-
-				num2, err := parseNumber(&lex, nextToken(&lex))
-				if err != nil {
-					return nil, err
-				}
-
-				return &CriteriaPerTypeGreaterThanEq{greaterThanEq: num2, score: num}, nil
-
-			} else if t.s == "MISSING" {
-				err = expectNextTokenStr(&lex, "VEGETABLE")
-				if err != nil {
-					return nil, err
-				}
-				err = expectNextTokenStr(&lex, "TYPE")
-				if err != nil {
-					return nil, err
-				}
-
-				return &CriteriaPerMissingType{score: num}, nil
+// parseNumberCriteria parses the criteria starting with a number: "N / ..." criteria or a set with counts.
+func parseNumberCriteria(lex *Lexer) (Criteria, error) {
+	start := lex.index
+	num, err := parseNumber(lex)
+	if err != nil {
+		return nil, err
+	}
+	if token, ok := peekToken(lex); ok && token.token_type == IDENTIFIER && isVegetable(lex.vegetables, token.s) {
+		lex.index = start
+		return parseSet(lex)
+	}
+	err = expectToken(lex, SLASH, "", `"/" or a vegetable`)
+	if err != nil {
+		return nil, err
+	}
 
-			} else {
-				var perScores [vegetableTypeNum]int
-				for true {
-					if !isVegetable(t.s) {
-						return nil, fmt.Errorf("Expected vegetable type here")
-					}
-					v := getVegetableType(t.s)
-					perScores[int(v)] = num
-
-					if lex.index >= len(lex.tokens)-1 || nextToken(&lex).token_type != COMMA {
-						break
-					}
-					num, err = parseNumber(&lex, nextToken(&lex))
-					if err != nil {
-						return nil, err
-					}
-					err = expectNextTokenType(&lex, SLASH)
-					if err != nil {
-						return nil, err
-					}
-					t = nextToken(&lex)
-				}
-
-				return &CriteriaPer{perScores: perScores}, nil
-			}
-		} else {
-			return nil, fmt.Errorf("Expected Identifier or number as first token")
+	if acceptToken(lex, IDENTIFIER, "VEGETABLE") {
+		err = parseWords(lex, "TYPE")
+		if err != nil {
+			return nil, err
+		}
+		atLeast, err := parseAtLeast(lex)
+		if err != nil {
+			return nil, err
+		}
+		return &CriteriaPerTypeGreaterThanEq{greaterThanEq: atLeast, score: num}, nil
+	}
+	if acceptToken(lex, IDENTIFIER, "MISSING") {
+		err = parseWords(lex, "VEGETABLE", "TYPE")
+		if err != nil {
+			return nil, err
+		}
+		return &CriteriaPerMissingType{score: num}, nil
+	}
+
+	perScores := make([]int, len(lex.vegetables))
+	for {
+		token, _ := peekToken(lex)
+		vegType, err := parseVegetable(lex)
+		if err != nil {
+			return nil, err
+		}
+		if perScores[vegType] != 0 {
+			lex.index -= 1
+			return nil, errorAt(lex, "expected every vegetable once")
+		}
+		if num == 0 {
+			return nil, &parseError{src: lex.raw_src, pos: token.pos, msg: fmt.Sprintf("expected %s to score something, got 0", lex.vegetables[vegType].Name)}
+		}
+		perScores[vegType] = num
+		if !acceptToken(lex, COMMA, "") {
+			break
+		}
+		num, err = parseNumber(lex)
+		if err != nil {
+			return nil, err
+		}
+		err = expectToken(lex, SLASH, "", `"/"`)
+		if err != nil {
+			return nil, err
 		}
 	}
-	panic("unreachable")
+	return &CriteriaPer{perScores: perScores, vegetables: lex.vegetables}, nil
 }
@@ -0,0 +1,319 @@
+package pointsalad
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ismctsDefaultIterations = 2000
+	ismctsDefaultDuration   = time.Second
+	// how much exploring a rarely visited action is worth, in points of margin
+	ismctsExploration = 10.0
+)
+
+// ISMCTSStrategy picks actions with information set Monte Carlo tree search. Every iteration
+// samples an order of the hidden pile cards, walks down the search tree with the actions that
+// are possible in that sample and plays the game to the end with random actions. The action
+// with the best average final margin over the other actors is chosen.
+//
+// The search stops when either budget runs out, a budget of 0 means no limit. The iterations
+// are split over Workers goroutines that each search their own tree, and the trees are merged
+// at the root. With a time budget the chosen action depends on the speed of the machine.
+//
+// Fields:
+//   - Iterations: The number of iterations to run in total.
+//   - Duration: How long the search may take.
+//   - Workers: The number of goroutines searching in parallel, 0 uses one per CPU.
+type ISMCTSStrategy struct {
+	Iterations int
+	Duration   time.Duration
+	Workers    int
+}
+
+func (c *ISMCTSStrategy) getMarketAction(v BotView) ActorAction {
+	return searchISMCTS(v.s, PhaseMarket, c.Iterations, c.Duration, c.Workers)
+}
+
+func (c *ISMCTSStrategy) getSwapAction(v BotView) ActorAction {
+	return searchISMCTS(v.s, PhaseSwap, c.Iterations, c.Duration, c.Workers)
+}
+
+// String returns the name of the strategy together with its budgets, ex. ismcts:2000:1s.
+func (c *ISMCTSStrategy) String() string {
+	return fmt.Sprintf("ismcts:%d:%v", c.Iterations, c.Duration)
+}
+
+// parseISMCTSStrategy parses the name of an ISMCTSStrategy, which is "ismcts" optionally followed
+// by the iteration budget and the time budget, ex. ismcts, ismcts:5000 or ismcts:0:500ms.
+//
+// Parameters:
+//   - name: The name of the strategy.
+//
+// Returns:
+//   - *ISMCTSStrategy: The strategy with the default budgets for the parts left out.
+//   - error: An error if the name is malformed or both budgets are 0.
+func parseISMCTSStrategy(name string) (*ISMCTSStrategy, error) {
+	parts := strings.Split(name, ":")
+	if parts[0] != "ismcts" || len(parts) > 3 {
+		return nil, fmt.Errorf("Unknown bot strategy: %s", name)
+	}
+	strategy := &ISMCTSStrategy{Iterations: ismctsDefaultIterations, Duration: ismctsDefaultDuration}
+	if len(parts) > 1 {
+		iterations, err := strconv.Atoi(parts[1])
+		if err != nil || iterations < 0 {
+			return nil, fmt.Errorf("Expected an iteration budget in %s, got %q", name, parts[1])
+		}
+		strategy.Iterations = iterations
+	}
+	if len(parts) > 2 {
+		duration, err := time.ParseDuration(parts[2])
+		if err != nil || duration < 0 {
+			return nil, fmt.Errorf("Expected a time budget in %s, got %q", name, parts[2])
+		}
+		strategy.Duration = duration
+	}
+	if strategy.Iterations == 0 && strategy.Duration == 0 {
+		return nil, fmt.Errorf("Expected an iteration or time budget in %s", name)
+	}
+	return strategy, nil
+}
+
+type ismctsNode struct {
+	action   ActorAction
+	actorId  int
+	parent   *ismctsNode
+	children []*ismctsNode
+
+	visits    int
+	available int
+	// sum of the final margins of actorId over all visits
+	reward float64
+}
+
+// searchISMCTS runs the search for the active actor of s in the given phase.
+//
+// Parameters:
+//   - s: The game state, it is not changed.
+//   - phase: The phase the action is chosen for.
+//   - iterations: The iteration budget, 0 means no limit.
+//   - duration: The time budget, 0 means no limit.
+//   - workers: The number of goroutines, 0 uses one per CPU.
+//
+// Returns:
+//   - ActorAction: The legal action with the best average final margin.
+func searchISMCTS(s *GameHostState, phase Phase, iterations int, duration time.Duration, workers int) ActorAction {
+	assert(iterations > 0 || duration > 0)
+	actions := getLegalActions(s, phase)
+	assert(len(actions) > 0)
+	if len(actions) == 1 {
+		return actions[0]
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var deadline time.Time
+	if duration > 0 {
+		deadline = time.Now().Add(duration)
+	}
+
+	// the clones share the random number generator of s, so every worker gets its own
+	roots := make([]*ismctsNode, workers)
+	wg := sync.WaitGroup{}
+	for i := range workers {
+		budget := 0
+		if iterations > 0 {
+			budget = iterations / workers
+			if i < iterations%workers {
+				budget += 1
+			}
+			if budget == 0 {
+				continue
+			}
+		}
+		rng := rand.New(rand.NewSource(s.rng.Int63()))
+		root := &ismctsNode{actorId: -1}
+		roots[i] = root
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; budget == 0 || n < budget; n += 1 {
+				if !deadline.IsZero() && time.Now().After(deadline) {
+					break
+				}
+				runISMCTSIteration(s, phase, root, rng)
+			}
+		}()
+	}
+	wg.Wait()
+
+	best := actions[0]
+	bestAverage := math.Inf(-1)
+	for _, action := range actions {
+		visits := 0
+		reward := 0.0
+		for _, root := range roots {
+			if root == nil {
+				continue
+			}
+			for _, child := range root.children {
+				if child.action == action {
+					visits += child.visits
+					reward += child.reward
+				}
+			}
+		}
+		if visits > 0 && reward/float64(visits) > bestAverage {
+			best = action
+			bestAverage = reward / float64(visits)
+		}
+	}
+	return best
+}
+
+// runISMCTSIteration samples the hidden cards, descends the tree to a new node, plays the game
+// to the end with random actions and adds the final margins to the visited nodes.
+func runISMCTSIteration(original *GameHostState, phase Phase, root *ismctsNode, rng *rand.Rand) {
+	s := deepCloneGameHostState(original)
+	s.rng = rng
+	sampleHiddenPileOrder(&s, rng)
+
+	node := root
+	terminal := false
+	for !terminal {
+		actions := getLegalActions(&s, phase)
+		untried := []ActorAction{}
+		for _, action := range actions {
+			tried := false
+			for _, child := range node.children {
+				if child.action == action {
+					child.available += 1
+					tried = true
+				}
+			}
+			if !tried {
+				untried = append(untried, action)
+			}
+		}
+
+		if len(untried) > 0 {
+			child := &ismctsNode{action: untried[rng.Intn(len(untried))], actorId: s.activeActor, parent: node, available: 1}
+			node.children = append(node.children, child)
+			node = child
+			phase, terminal = advanceSimulation(&s, phase, child.action)
+			break
+		}
+
+		var next *ismctsNode
+		bestValue := math.Inf(-1)
+		for _, child := range node.children {
+			if !slices.Contains(actions, child.action) {
+				continue
+			}
+			value := child.reward/float64(child.visits) + ismctsExploration*math.Sqrt(math.Log(float64(child.available))/float64(child.visits))
+			if value > bestValue {
+				next = child
+				bestValue = value
+			}
+		}
+		node = next
+		phase, terminal = advanceSimulation(&s, phase, node.action)
+	}
+
+	// rollout, swaps are skipped since a random swap mostly throws away points
+	for !terminal {
+		action := ActorAction{kind: pickToSwap}
+		if phase == PhaseMarket {
+			actions := getLegalMarketActions(&s)
+			action = actions[rng.Intn(len(actions))]
+		}
+		phase, terminal = advanceSimulation(&s, phase, action)
+	}
+
+	margins := getFinalMargins(&s)
+	for n := node; n != nil; n = n.parent {
+		n.visits += 1
+		if n.actorId >= 0 {
+			n.reward += float64(margins[n.actorId])
+		}
+	}
+}
+
+func getLegalActions(s *GameHostState, phase Phase) []ActorAction {
+	if phase == PhaseSwap {
+		return getLegalSwapActions(s)
+	}
+	return getLegalMarketActions(s)
+}
+
+// advanceSimulation applies a legal action and moves the game on the way RunHost does: an actor
+// with point cards gets a swap phase, then the turn passes to the next actor and the market is
+// refilled.
+//
+// Parameters:
+//   - s: The game state to change.
+//   - phase: The phase the action is played in.
+//   - action: The legal action of the active actor.
+//
+// Returns:
+//   - Phase: The phase of the next decision.
+//   - bool: true if the game is over.
+func advanceSimulation(s *GameHostState, phase Phase, action ActorAction) (Phase, bool) {
+	doAction(s, action)
+	if phase == PhaseMarket && len(s.actorData[s.activeActor].pointPile) > 0 {
+		return PhaseSwap, false
+	}
+	if hasWon(s) {
+		return phase, true
+	}
+	s.activeActor = (s.activeActor + 1) % len(s.actorData)
+	flipCardsFromPiles(&s.market)
+	return PhaseMarket, false
+}
+
+// sampleHiddenPileOrder shuffles the cards below the top of every pile between the piles, keeping
+// the size of every pile. The top cards are visible to every player and stay where they are.
+func sampleHiddenPileOrder(s *GameHostState, rng *rand.Rand) {
+	hidden := []Card{}
+	for _, pile := range s.market.piles {
+		if len(pile) > 1 {
+			hidden = append(hidden, pile[:len(pile)-1]...)
+		}
+	}
+	rng.Shuffle(len(hidden), func(i int, j int) {
+		hidden[i], hidden[j] = hidden[j], hidden[i]
+	})
+	for _, pile := range s.market.piles {
+		if len(pile) > 1 {
+			n := copy(pile[:len(pile)-1], hidden)
+			hidden = hidden[n:]
+		}
+	}
+}
+
+// getFinalMargins returns the score of every actor minus the best score among the other actors.
+func getFinalMargins(s *GameHostState) []int {
+	scores := []int{}
+	for i := range s.actorData {
+		scores = append(scores, calculateScore(s, i))
+	}
+	margins := []int{}
+	for i := range scores {
+		bestOther := math.MinInt
+		for j := range scores {
+			if j != i {
+				bestOther = max(bestOther, scores[j])
+			}
+		}
+		margins = append(margins, scores[i]-bestOther)
+	}
+	return margins
+}
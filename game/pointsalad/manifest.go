@@ -0,0 +1,351 @@
+package pointsalad
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// defaultManifest is the manifest of the base game, used when no manifest path is given.
+//
+//go:embed PointSaladManifest.json
+var defaultManifest []byte
+
+// ManifestError describes a criteria of a card in a manifest that cannot be parsed.
+type ManifestError struct {
+	CardId    int
+	Vegetable string
+	Criteria  string
+	Err       error
+}
+
+func (e ManifestError) Error() string {
+	return fmt.Sprintf("card %d %s %q: %v", e.CardId, e.Vegetable, e.Criteria, e.Err)
+}
+
+// baseManifest names the embedded manifest in a list of manifests.
+const baseManifest = "base"
+
+// DeckConfig is the game-config file given with -deck, which builds the deck of a game from
+// several manifests, ex. the base game and an expansion, and house rules.
+//
+// Fields:
+//   - Manifests: The manifests the cards are taken from, after the ones given with -manifest. Paths are relative
+//     to the game-config file, "base" is the embedded manifest.
+//   - Include: If set, only the cards with these ids are played.
+//   - Exclude: The ids of cards that are not played.
+//   - DeckSizes: How many cards of a vegetable are dealt, by vegetable name. Vegetables without a size get 3 cards per player.
+type DeckConfig struct {
+	Manifests []string       `json:"manifests,omitempty"`
+	Include   []int          `json:"include,omitempty"`
+	Exclude   []int          `json:"exclude,omitempty"`
+	DeckSizes map[string]int `json:"deckSizes,omitempty"`
+}
+
+// loadDeckConfig reads a game-config file and makes the paths of its manifests relative to the working directory.
+//
+// Parameters:
+//   - path: The game-config file, an empty config is returned if it is empty.
+//
+// Returns:
+//   - DeckConfig: The config.
+//   - error: An error if the file cannot be read or has unknown fields.
+func loadDeckConfig(path string) (DeckConfig, error) {
+	deck := DeckConfig{}
+	if path == "" {
+		return deck, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return deck, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&deck)
+	if err != nil {
+		return deck, fmt.Errorf("Failed to read game config %s: %v", path, err)
+	}
+	for i, manifest := range deck.Manifests {
+		if manifest != baseManifest && !filepath.IsAbs(manifest) {
+			deck.Manifests[i] = filepath.Join(filepath.Dir(path), manifest)
+		}
+	}
+	return deck, nil
+}
+
+// getManifestPaths returns the manifests of a game, the ones given with -manifest followed by the
+// ones of the game-config file.
+//
+// Parameters:
+//   - manifestPath: The -manifest flag, a comma separated list of manifests.
+//   - deck: The game-config file.
+//
+// Returns:
+//   - []string: The manifests, only the embedded manifest if none is given.
+func getManifestPaths(manifestPath string, deck DeckConfig) []string {
+	paths := []string{}
+	for _, path := range strings.Split(manifestPath, ",") {
+		path = strings.TrimSpace(path)
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	paths = append(paths, deck.Manifests...)
+	if len(paths) == 0 {
+		paths = append(paths, baseManifest)
+	}
+	return paths
+}
+
+// loadManifest reads the card manifest and returns the cards together with the hex encoded
+// sha256 hash of the file, which identifies the manifest in recordings. A manifest without a
+// list of vegetables is played with the vegetables of the base game.
+//
+// Parameters:
+//   - path: The manifest file, the embedded manifest is used if it is empty or "base".
+//
+// Returns:
+//   - JCards: The cards of the manifest.
+//   - string: The hash of the manifest.
+//   - error: An error if the file cannot be read, is not a manifest, its vegetables cannot be told apart or
+//     a card has a criteria for a vegetable that is not in the list.
+func loadManifest(path string) (JCards, string, error) {
+	jsonCards := JCards{}
+	data := defaultManifest
+	if path != "" && path != baseManifest {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return jsonCards, "", err
+		}
+	}
+	err := json.Unmarshal(data, &jsonCards)
+	if err != nil {
+		return jsonCards, "", fmt.Errorf("Failed to read manifest %s: %v", path, err)
+	}
+	if len(jsonCards.Vegetables) == 0 {
+		jsonCards.Vegetables = baseCatalogue
+	}
+	err = checkCatalogue(jsonCards.Vegetables)
+	if err != nil {
+		return jsonCards, "", fmt.Errorf("Manifest %s: %v", path, err)
+	}
+	for _, card := range jsonCards.Cards {
+		for name := range card.Criteria {
+			if !isVegetable(jsonCards.Vegetables, name) {
+				return jsonCards, "", fmt.Errorf("Manifest %s: card %d has a criteria for %s, which is not one of its vegetables", path, card.Id, name)
+			}
+		}
+	}
+	hash := sha256.Sum256(data)
+	return jsonCards, hex.EncodeToString(hash[:]), nil
+}
+
+// loadManifests reads several manifests and combines their cards. The hash of a single manifest is
+// the hash of its file, so recordings made before manifests could be combined keep matching.
+//
+// Parameters:
+//   - paths: The manifest files, "base" is the embedded manifest.
+//
+// Returns:
+//   - JCards: The cards of every manifest, in the order of the manifests.
+//   - string: The hash of the manifests.
+//   - error: An error if a manifest cannot be read, two manifests have a card with the same id or
+//     the manifests are played with different vegetables.
+func loadManifests(paths []string) (JCards, string, error) {
+	combined := JCards{}
+	hashes := []string{}
+	cardManifests := map[int]string{}
+	for _, path := range paths {
+		jsonCards, hash, err := loadManifest(path)
+		if err != nil {
+			return combined, "", err
+		}
+		if combined.Vegetables == nil {
+			combined.Vegetables = jsonCards.Vegetables
+		} else if !slices.Equal(combined.Vegetables, jsonCards.Vegetables) {
+			return combined, "", fmt.Errorf("Manifest %s is played with other vegetables than %s", path, paths[0])
+		}
+		for _, card := range jsonCards.Cards {
+			other, ok := cardManifests[card.Id]
+			if ok {
+				return combined, "", fmt.Errorf("Card id %d is in both %s and %s", card.Id, other, path)
+			}
+			cardManifests[card.Id] = path
+		}
+		combined.Cards = append(combined.Cards, jsonCards.Cards...)
+		hashes = append(hashes, hash)
+	}
+	if len(hashes) == 1 {
+		return combined, hashes[0], nil
+	}
+	hash := sha256.Sum256([]byte(strings.Join(hashes, ",")))
+	return combined, hex.EncodeToString(hash[:]), nil
+}
+
+// selectCards returns the cards that are played with the include and exclude lists of a game-config file.
+//
+// Parameters:
+//   - jsonCards: The cards of the manifests.
+//   - include: If not empty, only the cards with these ids are kept.
+//   - exclude: The ids of the cards that are left out.
+//
+// Returns:
+//   - JCards: The cards that are played, in the order of the manifests.
+//   - error: An error if an id is not in the manifests, since it is most likely a typo.
+func selectCards(jsonCards *JCards, include []int, exclude []int) (JCards, error) {
+	selected := JCards{Vegetables: jsonCards.Vegetables, Cards: []JCard{}}
+	ids := map[int]bool{}
+	for _, card := range jsonCards.Cards {
+		ids[card.Id] = true
+	}
+	for _, list := range [][]int{include, exclude} {
+		for _, id := range list {
+			if !ids[id] {
+				return selected, fmt.Errorf("There is no card with id %d in the manifests", id)
+			}
+		}
+	}
+	for _, card := range jsonCards.Cards {
+		if (len(include) == 0 || slices.Contains(include, card.Id)) && !slices.Contains(exclude, card.Id) {
+			selected.Cards = append(selected.Cards, card)
+		}
+	}
+	return selected, nil
+}
+
+// loadCards loads the cards a game is dealt from: the manifests given with -manifest and in the
+// game-config file, without the cards the game-config file leaves out.
+//
+// Parameters:
+//   - manifestPath: The -manifest flag, a comma separated list of manifests.
+//   - deckPath: The game-config file, empty if there is none.
+//
+// Returns:
+//   - JCards: The cards that are played.
+//   - DeckConfig: The game-config file, its deck sizes are needed to deal the cards.
+//   - string: The hash of the manifests, before any card is left out.
+//   - error: An error if a file cannot be read or the game-config file refers to unknown cards or vegetables.
+func loadCards(manifestPath string, deckPath string) (JCards, DeckConfig, string, error) {
+	deck, err := loadDeckConfig(deckPath)
+	if err != nil {
+		return JCards{}, deck, "", err
+	}
+	jsonCards, hash, err := loadManifests(getManifestPaths(manifestPath, deck))
+	if err != nil {
+		return JCards{}, deck, "", err
+	}
+	selected, err := selectCards(&jsonCards, deck.Include, deck.Exclude)
+	if err != nil {
+		return JCards{}, deck, "", err
+	}
+	err = checkDeckSizes(selected.Vegetables, deck.DeckSizes, len(selected.Cards))
+	if err != nil {
+		return JCards{}, deck, "", err
+	}
+	return selected, deck, hash, nil
+}
+
+// checkDeckSizes checks the deck sizes of a game-config file against the number of cards. Every
+// card has one side of every vegetable, so a vegetable cannot have more cards than there are cards.
+//
+// Parameters:
+//   - vegetables: The vegetables of the manifests.
+//   - deckSizes: The number of cards of a vegetable, by vegetable name.
+//   - cardNum: The number of cards that are played.
+//
+// Returns:
+//   - error: An error if a vegetable is unknown or its size is negative or larger than the number of cards.
+func checkDeckSizes(vegetables Catalogue, deckSizes map[string]int, cardNum int) error {
+	for name, size := range deckSizes {
+		if !isVegetable(vegetables, name) {
+			return fmt.Errorf("Unknown vegetable %q in the deck sizes", name)
+		}
+		if size < 0 || size > cardNum {
+			return fmt.Errorf("Expected between 0 and %d %s cards, got %d", cardNum, name, size)
+		}
+	}
+	return nil
+}
+
+// getDeckSizes returns how many cards of every vegetable are dealt in a game.
+//
+// Parameters:
+//   - vegetables: The vegetables of the manifests.
+//   - deckSizes: The sizes set in the game-config file, by vegetable name.
+//   - actorNum: The number of players and bots, vegetables without a size get 3 cards per actor.
+//   - cardNum: The number of cards that are played.
+//
+// Returns:
+//   - []int: The number of cards of every vegetable, indexed by VegType.
+//   - error: An error if there are not enough cards for a vegetable or no card is dealt at all.
+func getDeckSizes(vegetables Catalogue, deckSizes map[string]int, actorNum int, cardNum int) ([]int, error) {
+	sizes := make([]int, len(vegetables))
+	err := checkDeckSizes(vegetables, deckSizes, cardNum)
+	if err != nil {
+		return sizes, err
+	}
+	total := 0
+	for i, vegetable := range vegetables {
+		size, ok := deckSizes[vegetable.Name]
+		if !ok {
+			size = 3 * actorNum
+		}
+		if size > cardNum {
+			return sizes, fmt.Errorf("Expected at least %d cards to deal %d %s cards, got %d", size, size, vegetable.Name, cardNum)
+		}
+		sizes[i] = size
+		total += size
+	}
+	if total == 0 {
+		return sizes, fmt.Errorf("Expected a deck with at least one card")
+	}
+	return sizes, nil
+}
+
+// validateManifest parses the criteria of every vegetable of every card and collects all failures,
+// instead of stopping at the first one like createDeck does.
+//
+// Parameters:
+//   - jsonCards: The cards of the manifest.
+//
+// Returns:
+//   - []ManifestError: A failure for every criteria that cannot be parsed, empty if the manifest is valid.
+func validateManifest(jsonCards *JCards) []ManifestError {
+	errors := []ManifestError{}
+	for i, card := range jsonCards.Cards {
+		for vegType, vegetable := range jsonCards.Vegetables {
+			criteria := getJCriteria(jsonCards, VegType(vegType), i)
+			_, err := parseCriteria(criteria, jsonCards.Vegetables)
+			if err != nil {
+				errors = append(errors, ManifestError{CardId: card.Id, Vegetable: vegetable.Name, Criteria: criteria, Err: err})
+			}
+		}
+	}
+	return errors
+}
+
+// ValidateManifest reads the manifests of a game and checks that the criteria of every card can be parsed.
+//
+// Parameters:
+//   - manifestPath: A comma separated list of manifests, the embedded manifest is used if it is empty.
+//   - deckPath: A game-config file adding manifests and leaving out cards, empty if there is none.
+//
+// Returns:
+//   - int: The number of cards that are played.
+//   - []ManifestError: A failure for every criteria that cannot be parsed.
+//   - error: An error if a manifest or the game-config file cannot be read or used at all.
+func ValidateManifest(manifestPath string, deckPath string) (int, []ManifestError, error) {
+	jsonCards, _, _, err := loadCards(manifestPath, deckPath)
+	if err != nil {
+		return 0, nil, err
+	}
+	return len(jsonCards.Cards), validateManifest(&jsonCards), nil
+}
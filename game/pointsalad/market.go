@@ -1,10 +1,5 @@
 package pointsalad
 
-import (
-	"fmt"
-	"strings"
-)
-
 type CardSpot struct {
 	hasCard bool
 	card    Card
@@ -18,7 +13,7 @@ type Market struct {
 }
 
 // createMarket initializes a new Market with card piles and card spots based on the provided deck, width, and height.
-// The deck is evenly split into `playPilesNum` piles. Each pile gets an equal number of cards, and the remaining cards (if any) are asserted to be zero.
+// The deck is evenly split into `playPilesNum` piles. If the deck cannot be split evenly, the first piles get one card more than the others.
 // The function also sets up a grid of card spots with the specified width and height.
 //
 // Parameters:
@@ -37,11 +32,15 @@ func createMarket(width int, height int, deck []Card) Market {
 
 	pileSize := len(deck) / playPilesNum
 	pileSizeRemainder := len(deck) % playPilesNum
-	assert(pileSizeRemainder == 0)
 
+	start := 0
 	for i := range playPilesNum {
-		m.piles = append(m.piles, []Card{})
-		m.piles[i] = deck[i*pileSize : (i+1)*pileSize]
+		end := start + pileSize
+		if i < pileSizeRemainder {
+			end += 1
+		}
+		m.piles = append(m.piles, deck[start:end])
+		start = end
 	}
 
 	m.cardSpots = make([]CardSpot, width*height)
@@ -157,23 +156,6 @@ func getCardFromMarket(m *Market, id int) Card {
 	return m.cardSpots[id].card
 }
 
-func getMarketString(m *Market) string {
-	builder := strings.Builder{}
-	builder.WriteString("---- MARKET ----\n")
-	for i := range m.cardSpots {
-		if hasCard(m, i) {
-			card := getCardFromMarket(m, i)
-			builder.WriteString(fmt.Sprintf("[%c] %v\n", i+'A', card.vegType))
-		}
-	}
-	builder.WriteString("piles:\n")
-	for i, pile := range m.piles {
-		if len(pile) > 0 {
-			topCard := pile[len(pile)-1]
-			builder.WriteString(fmt.Sprintf("[%d] %s (%s)\n", i, topCard.criteria.String(), topCard.vegType))
-		} else {
-			builder.WriteString("\n")
-		}
-	}
-	return builder.String()
+func getMarketString(m *Market, vegetables Catalogue) string {
+	return renderMarket(getMarketView(m, vegetables))
 }
@@ -2,7 +2,7 @@ package pointsalad
 
 import (
 	"bufio"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -11,28 +11,14 @@ import (
 	"slices"
 	"strings"
 	"time"
-)
-
-type VegType int
-
-const (
-	PEPPER  VegType = iota
-	LETTUCE VegType = iota
-
-	CARROT  VegType = iota
-	CABBAGE VegType = iota
-
-	ONION  VegType = iota
-	TOMATO VegType = iota
-
-	vegetableTypeNum = iota
+	"unicode"
 )
 
 const (
 	playPilesNum          = 3
 	marketColumns         = 2
-	hostByteReceiveSize   = 4
-	clientByteReceiveSize = 1024
+	hostByteReceiveSize   = 1024
+	clientByteReceiveSize = 64 * 1024
 )
 
 type Card struct {
@@ -40,12 +26,9 @@ type Card struct {
 	vegType  VegType
 }
 
-func (c Card) String() string {
-	return fmt.Sprintf("%s (%s)", c.criteria, c.vegType)
-}
-
 type ActorData struct {
-	vegetableNum [vegetableTypeNum]int
+	// the number of vegetables of every type, indexed by VegType
+	vegetableNum []int
 	pointPile    []Card
 }
 
@@ -60,158 +43,548 @@ type GameHostState struct {
 	activeActor int
 	playerNum   int
 	botNum      int
+	// the vegetable types of the game, read from the manifests
+	vegetables Catalogue
+	// the strategies of the bots, indexed by actor id - playerNum
+	botStrategies []BotStrategy
+	// the names the players chose, indexed by actor id, empty for players without a name
+	names []string
+	// the strategies of the players that connected as bots, indexed by actor id, empty for humans
+	playerStrategies []string
+	// the names the players connected with as they sent them, indexed by actor id, a resumed game
+	// keeps every seat for the player connecting with its name
+	seatNames []string
+
+	// every random decision of the game is drawn from rng, so a game can be reproduced from its seed
+	seed int64
+	rng  *rand.Rand
+
+	// records the applied actions when the game is started with -record, nil otherwise
+	recorder *gameRecorder
+	// where the game is saved at the start of every turn, empty if it is not saved
+	savePath string
+
+	// how long a disconnected player keeps its seat before a bot takes over
+	gracePeriod time.Duration
+	// how long a player has to answer, 0 means no limit
+	turnTimeout time.Duration
+	// who scores a MOST or FEWEST criteria when it is tied, empty for the legacy TieFirstSeat
+	tiePolicy TiePolicy
 }
 
-// Init initializes the game state for a new game with the specified number of players and bots.
+// HostConfig holds the settings of a game hosted with GameHostState.
+//
+// Fields:
+//   - PlayerNum: The number of human players in the game.
+//   - BotNum: The number of bots in the game. The total number of players and bots must be between 2 and 6 (inclusive).
+//   - BotStrategies: The names of the strategies of the bots in seat order, bots without a strategy play random.
+//   - GracePeriod: How long a player whose connection dropped keeps its seat before a bot plays it.
+//   - TurnTimeout: How long a player has to answer an ActionRequest before the move is made automatically, 0 means no limit.
+//   - Seed: The seed of the game's random number generator, 0 picks a seed from the current time.
+//   - RecordPath: If set, the game is recorded and written to this file when it ends, see ReplayGame.
+//   - SavePath: If set, the game is saved to this file at the start of every turn.
+//   - ResumePath: If set, the game saved in this file is continued instead of starting a new game. The number of players and bots are taken from the save.
+//   - ManifestPath: A comma separated list of card manifests, "base" or an empty list is the manifest embedded in the program.
+//   - DeckPath: A game-config file adding manifests, leaving out cards and setting the number of cards of every vegetable, see DeckConfig. Empty if there is none.
+//   - TiePolicy: Who scores a tied MOST or FEWEST criteria, "first", "shared" or "none", see ParseTiePolicy. Empty is the legacy "first". A resumed game keeps the policy it was saved with.
+type HostConfig struct {
+	PlayerNum     int
+	BotNum        int
+	BotStrategies []string
+	GracePeriod   time.Duration
+	TurnTimeout   time.Duration
+	Seed          int64
+	RecordPath    string
+	SavePath      string
+	ResumePath    string
+	ManifestPath  string
+	DeckPath      string
+	TiePolicy     string
+}
+
+// Init initializes the game state for a new game with the settings in config.
 //
 // This function sets up the initial game state by:
 // 1. Verifying that the total number of players (human + bot) is between 2 and 6.
-// 2. Loading the game configuration and card data from the "pointsaladManifest.json" file.
-// 3. Creating a new game state based on the provided number of players and bots, and using the current time as a seed for randomization.
+// 2. Loading the card data from the configured manifests, or the embedded manifest, leaving out the cards the game-config file leaves out, and checking that every criteria can be parsed.
+// 3. Creating a new game state based on the provided number of players and bots, seeded with the configured seed or the current time if none is set. The seed is logged so the game can be reproduced.
+// 4. When resuming, loading the saved game instead of creating a new one. The random decisions after the save are drawn from the new seed.
 //
 // Parameters:
-//   - playerNum: The number of human players in the game.
-//   - botNum: The number of bots in the game. The total number of players and bots must be between 2 and 6 (inclusive).
+//   - config: The number of players and bots and the other settings of the game.
 //
 // Side effects:
 //   - The function modifies the `GameHostState` object (`state`) to reflect the initialized game state with players, bots, and cards.
 //
 // Returns:
-//   - None. In case of an error (e.g., invalid number of players/bots or failure to read the game manifest), the function will log the error and terminate the program.
+//   - error: An error if the settings are invalid, the save or the manifests cannot be read, or a criteria cannot be parsed.
+//     The state is left unusable then, but the program keeps running, so a lobby only has to close the one table.
 //
 // Example usage:
 //   - To start a new game with 2 human players and 1 bot:
-//     state.Init(2, 1)
-func (state *GameHostState) Init(playerNum int, botNum int) {
-	actorNum := playerNum + botNum
-
-	if !(actorNum >= 2 && actorNum <= 6) {
-		log.Fatalf("number of players + bots has to be between 2-6\n")
+//     err := state.Init(HostConfig{PlayerNum: 2, BotNum: 1, GracePeriod: time.Minute})
+func (state *GameHostState) Init(config HostConfig) error {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
 	}
+	log.Printf("INFO: game seed %d\n", seed)
 
-	data, err := os.ReadFile("pointsaladManifest.json")
-	if err != nil {
-		log.Fatal(err)
-	}
+	if config.ResumePath != "" {
+		if config.RecordPath != "" {
+			return fmt.Errorf("A resumed game cannot be recorded since it cannot be replayed from its seed")
+		}
+		saved, err := loadGame(config.ResumePath)
+		if err != nil {
+			return err
+		}
+		game_state, err := createGameHostStateFromSave(saved)
+		if err != nil {
+			return fmt.Errorf("Failed to resume game: %s", err)
+		}
+		*state = game_state
+		state.rng = rand.New(rand.NewSource(seed))
+		log.Printf("INFO: resuming game with %d players and %d bots\n", state.playerNum, state.botNum)
+	} else {
+		playerNum := config.PlayerNum
+		botNum := config.BotNum
+
+		err := CheckHostConfig(config)
+		if err != nil {
+			return err
+		}
 
-	jsonCards := JCards{}
+		jsonCards, deck, manifestHash, err := loadCards(config.ManifestPath, config.DeckPath)
+		if err != nil {
+			return err
+		}
+		manifestErrors := validateManifest(&jsonCards)
+		if len(manifestErrors) > 0 {
+			for _, e := range manifestErrors {
+				log.Printf("ERROR: %s\n", e)
+			}
+			return fmt.Errorf("The manifest has %d invalid criteria", len(manifestErrors))
+		}
 
-	err = json.Unmarshal(data, &jsonCards)
-	if err != nil {
-		log.Fatal(err)
+		game_state, err := createGameHostState(&jsonCards, deck.DeckSizes, playerNum, botNum, seed)
+		if err != nil {
+			return fmt.Errorf("Failed to create game state: %s", err)
+		}
+		*state = game_state
+		err = setBotStrategies(state, config.BotStrategies)
+		if err != nil {
+			return err
+		}
+		state.tiePolicy, err = ParseTiePolicy(config.TiePolicy)
+		if err != nil {
+			return err
+		}
+		if config.RecordPath != "" {
+			startRecording(state, config.RecordPath, manifestHash, deck)
+		}
 	}
+	state.gracePeriod = config.GracePeriod
+	state.turnTimeout = config.TurnTimeout
+	state.savePath = config.SavePath
+	return nil
+}
 
-	{
-		seed := time.Now().Unix()
-		game_state, err := createGameHostState(&jsonCards, playerNum, botNum, seed)
+// CheckHostConfig checks the settings of a new game the way Init does, so a config can be
+// rejected without terminating the program.
+//
+// Parameters:
+//   - config: The settings of the game.
+//
+// Returns:
+//   - error: An error if the number of players and bots is not between 2 and 6, a bot strategy is unknown or has no bot, the tie policy is unknown,
+//     or the cards cannot be loaded or are too few for the deck.
+func CheckHostConfig(config HostConfig) error {
+	actorNum := config.PlayerNum + config.BotNum
+	if config.PlayerNum < 0 || config.BotNum < 0 || !(actorNum >= 2 && actorNum <= 6) {
+		return fmt.Errorf("number of players + bots has to be between 2-6, got %d players and %d bots", config.PlayerNum, config.BotNum)
+	}
+	if len(config.BotStrategies) > config.BotNum {
+		return fmt.Errorf("Got %d bot strategies for %d bots", len(config.BotStrategies), config.BotNum)
+	}
+	for _, name := range config.BotStrategies {
+		_, err := GetBotStrategy(name)
 		if err != nil {
-			log.Fatalf("ERROR: Failed to create game state: %s\n", err)
-			return
+			return err
 		}
-		*state = game_state
 	}
+	_, err := ParseTiePolicy(config.TiePolicy)
+	if err != nil {
+		return err
+	}
+	jsonCards, deck, _, err := loadCards(config.ManifestPath, config.DeckPath)
+	if err != nil {
+		return err
+	}
+	_, err = getDeckSizes(jsonCards.Vegetables, deck.DeckSizes, actorNum, len(jsonCards.Cards))
+	return err
 }
 
 // RunHost runs the main game loop for the host, managing the game flow for both players and bots.
 //
 // This function orchestrates the core gameplay loop for the host by doing the following:
-// 1. **Market Actions**: Alternates between getting actions from either human players or bots. It provides game information to players (or gets automated actions from bots), and processes their market decisions (e.g., choosing vegetables or point cards).
-// 2. **Action Execution**: After getting the market action from the active player, the action is broadcast to all players, and the state is updated accordingly.
-// 3. **Swap Phase**: If the active player has point cards, it enters a swap phase where players/bots can choose to flip a point card into a vegetable card. The process is similar to the market action, where players can either make a decision or let the bot automatically choose.
-// 4. **Hand Sharing**: After each action, the host broadcasts the current state of the active player's hand to all other players. This ensures that each player is aware of others' progress.
-// 5. **Game End and Winner Announcement**: The game checks if a player has won, and if so, the host broadcasts the final scores and ends the game.
-// 6. **Actor Switching**: After every turn, the host moves to the next active player, cycling through all players and bots, until a winner is found.
+// 1. **State Update**: At the start of every turn the market and the hands of all actors are broadcast to everyone as a `StateUpdate` message.
+// 2. **Market Actions**: Alternates between getting actions from either human players or bots. Players are sent an `ActionRequest` for the market phase (bots get automated actions), and invalid answers are answered with an `Error` message and a new request.
+// 3. **Action Execution**: After getting the market action from the active player, the action is broadcast to all players as an `ActionResult`, and the state is updated accordingly.
+// 4. **Swap Phase**: If the active player has point cards, it enters a swap phase where players/bots can choose to flip a point card into a vegetable card. The process is similar to the market action, using a swap phase `ActionRequest`.
+// 5. **Hand Sharing**: Every `ActionResult` carries the updated hand of the active player. This ensures that each player is aware of others' progress.
+// 6. **Game End and Winner Announcement**: The game checks if a player has won, and if so, the host broadcasts the final scores in a `GameOver` message and ends the game.
+// 7. **Actor Switching**: After every turn, the host moves to the next active player, cycling through all players and bots, until a winner is found.
+// 8. **Reconnects**: When a player's connection drops everyone is told with a `Notice`, and the player keeps its seat for the grace period. A player that reconnects is sent the current state, a player that does not is replaced by a bot until it comes back.
+// 9. **Turn Timeout**: If a turn timeout is set, a player that does not answer in time has its move made by the bot logic, and the `ActionResult` is marked as automatic. Players that keep running out of time are marked AFK until they answer in time again.
+// 10. **Recording**: If the game is recorded, every applied action is logged and the recording is written when the game ends.
+// 11. **Saving**: If a save path is set, the full state is saved at the start of every turn so the game can be resumed later.
+// 12. **Spectators**: Spectators get every broadcast but are never asked for an action. A spectator that joins a running game is sent the market and every hand first.
 //
 // Parameters:
-//   - in: A map where the keys are actor IDs (player/bot), and the values are channels from which the host can receive input (commands) from the respective actors.
-//   - out: A map where the keys are actor IDs, and the values are channels to which the host can send output (game state information) to the respective actors.
+//   - in: A map where the keys are actor IDs (player/bot), and the values are channels from which the host can receive JSON encoded messages from the respective actors.
+//   - out: A map where the keys are actor IDs, and the values are channels to which the host sends JSON encoded messages to the respective actors.
+//   - status: A map where the keys are actor IDs, and the values are channels reporting whether the respective player is connected. May be nil if connections cannot drop.
+//   - spectators: A channel on which every new spectator arrives as the channel the host sends it JSON encoded messages on. The channels have to be buffered, a spectator whose buffer fills up is dropped and its channel closed. May be nil if the game cannot be watched.
 //
 // Side effects:
 //   - This function modifies the state of the game as actions are taken by players or bots, and broadcasts game state updates to all participants.
 //   - The game ends when a player wins, and the final scores are broadcast to all players/bots.
 //
 // Returns:
-//   - None. The game loop will continue until a winner is found or a player exits (e.g., by sending 'Q' to quit).
+//   - None. The game loop will continue until a winner is found or a player exits (e.g., by sending a Quit action).
 //
 // Example usage:
 //   - To start the host game loop with two human players and one bot:
-//     state.RunHost(playerInputChannels, botInputChannels)
-func (state *GameHostState) RunHost(in map[int]chan []byte, out map[int]chan []byte) {
-	var err error
+//     state.RunHost(playerInputChannels, playerOutputChannels, playerStatusChannels, spectatorChannel)
+func (state *GameHostState) RunHost(in map[int]chan []byte, out map[int]chan []byte, status map[int]chan bool, spectators chan chan []byte) {
 	for _, v := range in {
 		assert(v != nil)
 	}
 	for _, v := range out {
 		assert(v != nil)
 	}
+	conns := createHostConnections(in, out, status, spectators, getActorInfos(state), state.gracePeriod, state.turnTimeout)
+	defer conns.stop()
+	defer func() {
+		err := saveRecording(state)
+		if err != nil {
+			log.Printf("ERROR: Failed to write recording: %s\n", err)
+		}
+	}()
+
+	getAction := func(phase Phase) (ActorAction, bool, bool) {
+		if conns.isBot(state.activeActor) {
+			return getActionFromBot(state, phase), false, true
+		}
+		return getActionFromPlayer(state, conns, phase)
+	}
+	apply := func(phase Phase, action ActorAction, automatic bool) {
+		doActionAndBroadcast(state, conns, phase, action, automatic)
+	}
+
 	for {
 		flipCardsFromPiles(&state.market)
-		is_bot := in[state.activeActor] == nil
-
-		// get decisions from actor
-		var market_action ActorAction
-		if is_bot {
-			market_action = getMarketActionFromBot(state)
-		} else {
-			s := getActorCardsString(state, state.activeActor) + getMarketString(&state.market)
-			out[state.activeActor] <- []byte(s)
-			for {
-				out[state.activeActor] <- []byte("pick 1 or 2 vegetables example: AB or\npick 1 point card example: 0\n")
-				input := <-in[state.activeActor]
-				if len(input) == 0 || (len(input) == 1 && input[0] == 'Q') {
-					return
-				}
-				market_action, err = parseMarketActionFromPlayer(state, input)
-				if err != nil {
-					out[state.activeActor] <- []byte(fmt.Sprintf("%v\n", err))
-				} else {
-					break
-				}
+		conns.pollEvents(state)
+		if state.savePath != "" {
+			err := saveGame(state, state.savePath)
+			if err != nil {
+				log.Printf("ERROR: Failed to save game: %s\n", err)
 			}
 		}
-		broadcastToAll(out, getActionString(state, market_action))
-		doAction(state, market_action)
-
-		if len(state.actorData[state.activeActor].pointPile) > 0 {
-			var swap_action ActorAction
-			if is_bot {
-				swap_action = getSwapActionFromBot(state)
-			} else {
-				out[state.activeActor] <- []byte(getActorCardsString(state, state.activeActor))
-				for {
-					out[state.activeActor] <- []byte(fmt.Sprintf("pick 0-1 point card to flip to vegetable, type n to pick none example: 5\n"))
-					input := <-in[state.activeActor]
-					if len(input) == 0 || (len(input) == 1 && input[0] == 'Q') {
-						return
-					}
-					swap_action, err = parseSwapActionFromPlayer(state, input)
-					if err != nil {
-						out[state.activeActor] <- []byte(fmt.Sprintf("%v\n", err))
-					} else {
-						break
-					}
-				}
-			}
-			broadcastToAll(out, getActionString(state, swap_action))
-			doAction(state, swap_action)
+
+		market := getMarketView(&state.market, state.vegetables)
+		conns.broadcast(Message{Kind: MsgStateUpdate, ActorId: state.activeActor, Actor: getMessageActor(state, state.activeActor), Market: &market, Hands: getHandViews(state), Vegetables: state.vegetables, TiePolicy: state.tiePolicy})
+
+		ok, over := playTurn(state, getAction, apply)
+		if !ok {
+			return
+		}
+		if over {
+			conns.broadcast(Message{Kind: MsgGameOver, Scores: getScoreViews(state), Hands: getHandViews(state)})
+			break
+		}
+	}
+}
+
+// playTurn plays the turn of the active actor: its market action, and its swap action if it has
+// point cards. If the game is not over after the turn the next actor becomes the active actor.
+// The host, the simulator and the replay of recordings all play their games with it, they only
+// differ in where the actions come from and who is told about them.
+//
+// Parameters:
+//   - state: The game, the cards of the market have to be flipped already.
+//   - getAction: Returns the legal action of the active actor for the phase, true if it was made
+//     automatically for a player that ran out of time, and false if the actor quit.
+//   - apply: Applies the action of the active actor for the phase.
+//
+// Returns:
+//   - bool: false if the active actor quit, the turn is then left unfinished.
+//   - bool: true if the game is over after the turn.
+func playTurn(state *GameHostState, getAction func(phase Phase) (ActorAction, bool, bool), apply func(phase Phase, action ActorAction, automatic bool)) (bool, bool) {
+	action, automatic, ok := getAction(PhaseMarket)
+	if !ok {
+		return false, false
+	}
+	apply(PhaseMarket, action, automatic)
+
+	if len(state.actorData[state.activeActor].pointPile) > 0 {
+		action, automatic, ok := getAction(PhaseSwap)
+		if !ok {
+			return false, false
+		}
+		apply(PhaseSwap, action, automatic)
+	}
+
+	if hasWon(state) {
+		return true, true
+	}
+
+	// next actor
+	state.activeActor += 1
+	state.activeActor %= state.playerNum + state.botNum
+	return true, false
+}
+
+// getActionFromPlayer sends an ActionRequest for the given phase to the active player and waits for
+// a legal answer. Answers that cannot be decoded, belong to the wrong phase or are illegal are
+// answered with an Error message followed by a new request.
+//
+// While waiting, connection changes of all players are handled. If the active player reconnects
+// the request is sent again, and if its grace period runs out a bot picks the action instead.
+// If the turn timeout runs out before the player answers, the bot logic picks the action and
+// the player moves closer to being marked AFK. Answers to earlier requests are ignored.
+//
+// Parameters:
+//   - state: The current game state.
+//   - conns: The connections to the players.
+//   - phase: The phase the action is requested for.
+//
+// Returns:
+//   - ActorAction: The legal action chosen by the player, or by the bot playing its seat.
+//   - bool: true if the action was made automatically because the player ran out of time.
+//   - bool: false if the player quit.
+func getActionFromPlayer(state *GameHostState, conns *hostConnections, phase Phase) (ActorAction, bool, bool) {
+	actorId := state.activeActor
+	market := getMarketView(&state.market, state.vegetables)
+	conns.requestSeq += 1
+	request := Message{
+		Kind:       MsgActionRequest,
+		Phase:      phase,
+		ActorId:    actorId,
+		Actor:      getMessageActor(state, actorId),
+		Market:     &market,
+		Hands:      getHandViews(state),
+		Seq:        conns.requestSeq,
+		DeadlineMs: conns.turnTimeout.Milliseconds(),
+		Vegetables: state.vegetables,
+		TiePolicy:  state.tiePolicy,
+	}
+	conns.send(actorId, request)
+	turnTimeout := conns.turnTimer()
+	for {
+		if conns.botFallback[actorId] {
+			return getActionFromBot(state, phase), false, true
 		}
-		// show hand to all other players
-		for k, o := range out {
-			if k == state.activeActor {
+		select {
+		case input := <-conns.in[actorId]:
+			if expectQuit(input) {
+				return ActorAction{}, false, false
+			}
+			action, err := parseActionMessage(state, phase, request.Seq, input)
+			if err == errStaleAnswer {
 				continue
 			}
-			o <- []byte(getActorCardsString(state, state.activeActor))
+			if err == nil && action.kind == Quit {
+				return action, false, false
+			}
+			if err == nil {
+				conns.answeredInTime(actorId)
+				return action, false, true
+			}
+			conns.send(actorId, Message{Kind: MsgError, ActorId: actorId, Actor: getMessageActor(state, actorId), Error: err.Error()})
+			conns.send(actorId, request)
+		case e := <-conns.events:
+			conns.handleEvent(state, e)
+			if e.actorId == actorId && e.connected {
+				conns.send(actorId, request)
+			}
+		case <-conns.graceTimeout(actorId):
+			conns.fallBackToBot(actorId)
+		case <-turnTimeout:
+			conns.ranOutOfTime(actorId)
+			return getActionFromBot(state, phase), true, true
 		}
+	}
+}
 
-		if hasWon(state) {
-			broadcastToAll(out, getFinalScoresString(state))
-			break
+// getActionFromBot returns the action the strategy of the active actor picks in the given phase.
+// Players whose move is made by the host play with the random strategy.
+func getActionFromBot(state *GameHostState, phase Phase) ActorAction {
+	strategy := getBotStrategy(state, state.activeActor)
+	if phase == PhaseSwap {
+		return strategy.getSwapAction(BotView{s: state})
+	}
+	return strategy.getMarketAction(BotView{s: state})
+}
+
+// errStaleAnswer is returned by parseActionMessage for an action answering an earlier request,
+// such as an answer that arrives after the turn timeout ran out.
+var errStaleAnswer = errors.New("Action answers an earlier request")
+
+// parseActionMessage decodes an Action message from the active player and checks that it is legal in the given phase.
+//
+// Parameters:
+//   - state: The current game state.
+//   - phase: The phase the action was requested for.
+//   - seq: The sequence number of the request, every action but Quit has to carry it.
+//   - input: The raw message received from the player.
+//
+// Returns:
+//   - ActorAction: The decoded action, Quit is accepted in every phase.
+//   - error: errStaleAnswer if the action answers an earlier request, or an error if the message is malformed, is not
+//     of the active actor, has no sequence number, belongs to another phase or is illegal.
+func parseActionMessage(state *GameHostState, phase Phase, seq int, input []byte) (ActorAction, error) {
+	msg, err := decodeMessage(input)
+	if err != nil {
+		return ActorAction{}, err
+	}
+	if msg.Kind != MsgAction || msg.Action == nil {
+		return ActorAction{}, fmt.Errorf("Expected an action")
+	}
+	// only the active actor is asked for an action, so the message has to come from its seat
+	if msg.ActorId != state.activeActor {
+		return ActorAction{}, fmt.Errorf("Expected an action of actor %d, got one of actor %d", state.activeActor, msg.ActorId)
+	}
+	if msg.Action.Kind != Quit.String() {
+		if msg.Seq == 0 {
+			return ActorAction{}, fmt.Errorf("Expected the action to carry the seq %d of the request", seq)
+		}
+		if msg.Seq != seq {
+			return ActorAction{}, errStaleAnswer
+		}
+	}
+	action, err := actionFromView(*msg.Action)
+	if err != nil {
+		return action, err
+	}
+	if action.kind == Quit {
+		return action, nil
+	}
+	isSwap := action.kind == pickToSwap
+	if isSwap != (phase == PhaseSwap) {
+		return action, fmt.Errorf("Action %v is not allowed in the %v phase", action.kind, phase)
+	}
+	err = isActionLegal(state, action)
+	if err != nil {
+		return action, err
+	}
+	return action, nil
+}
+
+// doActionAndBroadcast applies a legal action for the active actor and reports it to everyone
+// as an ActionResult that carries the action and the updated hand of the active actor.
+// automatic marks actions the host made for a player that ran out of time.
+func doActionAndBroadcast(state *GameHostState, conns *hostConnections, phase Phase, action ActorAction, automatic bool) {
+	view := getActionView(state, action)
+	view.Automatic = automatic
+	recordAction(state, phase, view)
+	doAction(state, action)
+	conns.broadcast(Message{
+		Kind:    MsgActionResult,
+		Phase:   phase,
+		ActorId: state.activeActor,
+		Actor:   getMessageActor(state, state.activeActor),
+		Action:  &view,
+		Hands:   []HandView{getHandView(state, state.activeActor)},
+	})
+}
+
+// maxNameLength is the length in characters names of players are cut to.
+const maxNameLength = 24
+
+// SetPlayerNames sets the names the players chose when they connected, which are shown instead
+// of their actor ids. Names are cut to maxNameLength characters and stripped of characters that
+// cannot be printed, a name already taken by another player gets the actor id appended.
+// Must be called before RunHost.
+//
+// Parameters:
+//   - names: The names of the players by actor id, players without a name keep "Player <id>"
+//     or the name they had in a resumed game.
+func (state *GameHostState) SetPlayerNames(names map[int]string) {
+	for len(state.names) < state.playerNum {
+		state.names = append(state.names, "")
+	}
+	state.seatNames = make([]string, state.playerNum)
+	for id := range state.playerNum {
+		state.seatNames[id] = names[id]
+	}
+	for id := range state.playerNum {
+		name := cleanPlayerName(names[id])
+		if name != "" {
+			state.names[id] = name
+		}
+	}
+	taken := map[string]bool{}
+	for id := state.playerNum; id < state.playerNum+state.botNum; id += 1 {
+		taken[getActorInfo(state, id).Name] = true
+	}
+	for id := range state.playerNum {
+		name := getActorInfo(state, id).Name
+		if taken[name] {
+			name = fmt.Sprintf("%s (%d)", name, id)
+			state.names[id] = name
+		}
+		taken[name] = true
+	}
+}
+
+// SetPlayerBots marks the players that connected as bots, which are shown as bots playing their
+// strategy instead of as humans. Must be called before RunHost.
+//
+// Parameters:
+//   - strategies: The names of the strategies of the players that are bots by actor id, humans are missing.
+func (state *GameHostState) SetPlayerBots(strategies map[int]string) {
+	state.playerStrategies = make([]string, state.playerNum)
+	for id := range state.playerNum {
+		state.playerStrategies[id] = cleanPlayerName(strategies[id])
+	}
+}
+
+// cleanPlayerName removes the characters of a name that cannot be printed and cuts it to maxNameLength characters.
+func cleanPlayerName(name string) string {
+	runes := []rune{}
+	for _, r := range strings.TrimSpace(name) {
+		if unicode.IsPrint(r) && len(runes) < maxNameLength {
+			runes = append(runes, r)
 		}
+	}
+	return strings.TrimSpace(string(runes))
+}
 
-		// next actor
-		state.activeActor += 1
-		state.activeActor %= state.playerNum + state.botNum
+// GetPlayerNum returns the number of human players the host waits for, which is taken from the
+// saved game when a game is resumed.
+func (state *GameHostState) GetPlayerNum() int {
+	return state.playerNum
+}
+
+// GetSeatNames returns the names the players of a resumed game connected with, so every seat can
+// be kept for the player connecting with the same name.
+//
+// Returns:
+//   - map[int]string: The names by actor id, empty for players that connected without a name. nil for
+//     a new game and for games saved before the names were saved, whose seats anyone can take.
+func (state *GameHostState) GetSeatNames() map[int]string {
+	if state.seatNames == nil {
+		return nil
+	}
+	names := make(map[int]string)
+	for id := range state.playerNum {
+		names[id] = ""
+		if id < len(state.seatNames) {
+			names[id] = state.seatNames[id]
+		}
 	}
+	return names
 }
 
 // GetMaxHostDataSize returns the maximum size (in bytes) that the server (host) can receive from clients.
@@ -228,7 +601,9 @@ func (_ *GameHostState) GetMaxHostDataSize() int {
 }
 
 type GamePlayerState struct {
-	reader io.Reader
+	reader   io.Reader
+	strategy BotStrategy
+	rng      *rand.Rand
 }
 
 // Init initializes the GamePlayerState by setting up the input reader
@@ -245,9 +620,23 @@ func (s *GamePlayerState) Init() {
 	s.reader = bufio.NewReader(os.Stdin)
 }
 
-// RunPlayer starts the player game loop for human players, reading and writing data from/to the player's input and output channels.
+// InitBot turns the GamePlayerState into a remote bot, which answers the host with the actions
+// chosen by the given strategy instead of reading input from a human. The random decisions of
+// the bot are seeded from the current time.
 //
-// This function serves as an entry point for running a player in the game. It uses `runPlayerWithReader` to handle player interaction with the game through standard input and output channels.
+// Example usage:
+//
+//	strategy, _ := GetBotStrategy("random")
+//	playerState := &GamePlayerState{}
+//	playerState.InitBot(strategy)
+func (s *GamePlayerState) InitBot(strategy BotStrategy) {
+	s.strategy = strategy
+	s.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
+// RunPlayer starts the player game loop, for human players or remote bots, reading and writing data from/to the player's input and output channels.
+//
+// This function serves as an entry point for running a player in the game. It uses `runPlayerWithReader` to handle player interaction with the game through standard input and output channels, or `runPlayerWithStrategy` if the player was initialized as a bot with `InitBot`.
 //
 // Parameters:
 //   - in: A channel from which the function receives game data to present to the player.
@@ -259,6 +648,10 @@ func (s *GamePlayerState) Init() {
 // Returns:
 //   - None. The function loops indefinitely until the player quits (by sending a "quit" command).
 func (s *GamePlayerState) RunPlayer(in chan []byte, out chan []byte) {
+	if s.strategy != nil {
+		runPlayerWithStrategy(in, out, s.strategy, s.rng)
+		return
+	}
 	runPlayerWithReader(in, out, s.reader)
 }
 
@@ -276,17 +669,17 @@ func (_ *GamePlayerState) GetMaxPlayerDataSize() int {
 }
 
 // runPlayerWithReader handles player interaction with the game using a specified input reader (e.g., stdin).
-// It reads input from the player, processes it, and sends back the response through the output channel.
+// It renders the messages received from the host, reads the player's answers, and sends them back through the output channel.
 //
-// This function continuously listens for incoming game data (in the form of byte slices) and responds with player input. It uses a scanner to read lines of text input from the player. The function expects certain prompts to trigger player responses (such as "pick"), and it terminates when the player provides input that matches the quit condition (e.g., sending an empty byte slice).
+// This function continuously listens for incoming JSON encoded messages and prints them to the player. When the host sends an `ActionRequest` it uses a scanner to read lines of text input from the player until the input is a well formed action for the requested phase, which is then sent back as an `Action` message. Typing 'Q' quits the game. The function terminates when the game is over, the player quits or the input channel is closed.
 //
 // Parameters:
-//   - in: A channel from which the function receives game data to present to the player (e.g., prompts or information).
-//   - out: A channel to which the function sends player input back to the game (e.g., decisions or actions).
+//   - in: A channel from which the function receives messages from the host (e.g., state updates or action requests).
+//   - out: A channel to which the function sends the player's actions back to the game.
 //   - r: An `io.Reader` used for reading player input (typically `os.Stdin` for human players).
 //
 // Side effects:
-//   - The function expects the player to provide responses via standard input. Once the player inputs data, it is sent back to the game through the `out` channel.
+//   - The function expects the player to provide responses via standard input. Once the player inputs a valid action, it is sent back to the game through the `out` channel.
 //   - The function ends when the player gets a signal to quit
 //
 // Returns:
@@ -302,10 +695,18 @@ func runPlayerWithReader(in chan []byte, out chan []byte, r io.Reader) {
 		if expectQuit(data) {
 			return
 		}
-		fmt.Printf("%s", string(data))
-		if expectResponse(data) {
-			var str string
-			{
+		msg, err := decodeMessage(data)
+		if err != nil {
+			log.Printf("ERROR: %s\n", err)
+			continue
+		}
+		fmt.Printf("%s", renderMessage(msg))
+		if msg.Kind == MsgGameOver {
+			return
+		}
+		if expectResponse(msg) {
+			var action ActorAction
+			for {
 				if !scan.Scan() {
 					err := scan.Err()
 					if err != nil {
@@ -317,9 +718,18 @@ func runPlayerWithReader(in chan []byte, out chan []byte, r io.Reader) {
 				// should work for linux/macos too
 				s = strings.TrimSuffix(s, "\n")
 				s = strings.TrimSuffix(s, "\r")
-				str = s
+
+				action, err = parsePlayerInput(msg.Phase, []byte(s))
+				if err == nil {
+					break
+				}
+				fmt.Printf("%v\n", err)
+			}
+			view := actionToView(action)
+			out <- encodeMessage(Message{Kind: MsgAction, ActorId: msg.ActorId, Action: &view, Seq: msg.Seq})
+			if action.kind == Quit {
+				return
 			}
-			out <- []byte(str)
 		}
 	}
 }
@@ -328,8 +738,8 @@ func expectQuit(data []byte) bool {
 	return len(data) == 0
 }
 
-func expectResponse(data []byte) bool {
-	return strings.Contains(string(data), "pick")
+func expectResponse(msg Message) bool {
+	return msg.Kind == MsgActionRequest
 }
 
 func hasWon(state *GameHostState) bool {
@@ -347,35 +757,10 @@ func hasWon(state *GameHostState) bool {
 	return true
 }
 
+// getFinalScoresString renders the final scores of every actor from highest to lowest, each followed
+// by what every one of its point cards earned and why.
 func getFinalScoresString(state *GameHostState) string {
-	type Score struct {
-		score   int
-		actorId int
-	}
-	scores := []Score{}
-
-	for i := range state.playerNum + state.botNum {
-		scores = append(scores, Score{score: calculateScore(state, i), actorId: i})
-	}
-
-	slices.SortFunc(scores, func(a, b Score) int {
-		return b.score - a.score
-	})
-
-	highScore := scores[0].score
-
-	builder := strings.Builder{}
-
-	builder.WriteString("---- Final scores ----\n")
-	for _, s := range scores {
-		builder.WriteString(fmt.Sprintf("Player %d with score %d", s.actorId, s.score))
-		if s.score == highScore {
-			builder.WriteString(" Winner\n")
-		} else {
-			builder.WriteString("\n")
-		}
-	}
-	return builder.String()
+	return renderFinalScores(getScoreViews(state))
 }
 
 func assert(c bool) {
@@ -390,23 +775,24 @@ func assert(c bool) {
 //
 // Parameters:
 //   - jsonCards: A pointer to a `JCards` structure containing the JSON data for the available cards.
-//   - perVegetableNum: The number of cards to generate for each vegetable type.
+//   - sizes: The number of cards to generate for each vegetable type of the catalogue of jsonCards, at most the number of cards in jsonCards.
+//   - rng: The random number generator used to shuffle the card IDs.
 //
 // Returns:
 //   - A slice of `Card` structures representing the deck of cards.
-func createDeck(jsonCards *JCards, perVegetableNum int) []Card {
+func createDeck(jsonCards *JCards, sizes []int, rng *rand.Rand) []Card {
 	var deck []Card
 	var ids []int
 	for id, _ := range jsonCards.Cards {
 		ids = append(ids, id)
 	}
-	for i := range vegetableTypeNum {
-		rand.Shuffle(len(ids), func(i int, j int) {
+	for i := range jsonCards.Vegetables {
+		rng.Shuffle(len(ids), func(i int, j int) {
 			ids[i], ids[j] = ids[j], ids[i]
 		})
 
-		for j := 0; j < perVegetableNum; j += 1 {
-			criteria, err := parseCriteria(getJCriteria(jsonCards, VegType(i), ids[j]))
+		for j := 0; j < sizes[i]; j += 1 {
+			criteria, err := parseCriteria(getJCriteria(jsonCards, VegType(i), ids[j]), jsonCards.Vegetables)
 			if err != nil {
 				log.Fatalf("ERROR: while creating deck: %v\n", err)
 			}
@@ -423,37 +809,44 @@ func createDeck(jsonCards *JCards, perVegetableNum int) []Card {
 
 // createGameHostState initializes a new game state with a shuffled deck and a random seed for actor turns.
 // It creates the game market, assigns actors (players and bots), and sets up the initial conditions for the game based on the provided parameters.
+// The game gets its own random number generator, which is also used for the decisions of the bots.
 //
 // Parameters:
 //   - jsonCards: A pointer to a `JCards` structure containing the JSON data for the cards.
+//   - deckSizes: The number of cards of a vegetable by vegetable name, vegetables without a size get 3 cards per player and bot. May be nil.
 //   - playerNum: The number of players in the game.
 //   - botNum: The number of bots in the game.
-//   - seed: A seed for the random number generator, used to ensure consistent randomization across game sessions.
+//   - seed: A seed for the random number generator, the same seed and player inputs give the same game.
 //
 // Returns:
 //   - A `GameHostState` structure representing the initialized game state.
-//   - An error if the number of players + bots is out of the expected range (between 2 and 6).
-func createGameHostState(jsonCards *JCards, playerNum int, botNum int, seed int64) (GameHostState, error) {
+//   - An error if the number of players + bots is out of the expected range (between 2 and 6), or there are not enough cards for the deck sizes.
+func createGameHostState(jsonCards *JCards, deckSizes map[string]int, playerNum int, botNum int, seed int64) (GameHostState, error) {
 	actorNum := playerNum + botNum
 	if !(actorNum >= 2 && actorNum <= 6) {
 		return GameHostState{}, fmt.Errorf("Number of players + bots have to be between 2-6")
 	}
-	rand.Seed(seed)
-
+	sizes, err := getDeckSizes(jsonCards.Vegetables, deckSizes, actorNum, len(jsonCards.Cards))
+	if err != nil {
+		return GameHostState{}, err
+	}
 	s := GameHostState{}
+	s.vegetables = jsonCards.Vegetables
+	s.seed = seed
+	s.rng = rand.New(rand.NewSource(seed))
 
-	deck := createDeck(jsonCards, 3*actorNum)
-	rand.Shuffle(len(deck), func(i int, j int) {
+	deck := createDeck(jsonCards, sizes, s.rng)
+	s.rng.Shuffle(len(deck), func(i int, j int) {
 		deck[i], deck[j] = deck[j], deck[i]
 	})
 
 	s.market = createMarket(playPilesNum, marketColumns, deck)
 
 	for range actorNum {
-		s.actorData = append(s.actorData, ActorData{})
+		s.actorData = append(s.actorData, ActorData{vegetableNum: make([]int, len(s.vegetables))})
 	}
 
-	s.activeActor = rand.Intn(actorNum)
+	s.activeActor = s.rng.Intn(actorNum)
 	s.playerNum = playerNum
 	s.botNum = botNum
 	return s, nil
@@ -461,6 +854,7 @@ func createGameHostState(jsonCards *JCards, playerNum int, botNum int, seed int6
 
 // deepCloneGameHostState creates a deep copy of the provided game state, including all market piles, card spots, and actor data (vegetables and point piles).
 // The cloned game state will be an exact copy of the original, allowing for parallel game simulations or backups.
+// The clone shares the random number generator of the original, which is not safe for concurrent use.
 //
 // Parameters:
 //   - s: The original `GameHostState` structure to be cloned.
@@ -483,44 +877,44 @@ func deepCloneGameHostState(s *GameHostState) GameHostState {
 
 	for i := range s.actorData {
 		new.actorData = append(new.actorData, ActorData{})
-		new.actorData[i].vegetableNum = s.actorData[i].vegetableNum
+		new.actorData[i].vegetableNum = slices.Clone(s.actorData[i].vegetableNum)
 		for j := range s.actorData[i].pointPile {
 			new.actorData[i].pointPile = append(new.actorData[i].pointPile, s.actorData[i].pointPile[j])
 		}
 	}
 
+	new.vegetables = s.vegetables
 	new.activeActor = s.activeActor
 	new.playerNum = s.playerNum
 	new.botNum = s.botNum
+	new.botStrategies = s.botStrategies
+	new.names = s.names
+	new.playerStrategies = s.playerStrategies
+	new.seatNames = s.seatNames
+	new.gracePeriod = s.gracePeriod
+	new.turnTimeout = s.turnTimeout
+	new.tiePolicy = s.tiePolicy
+	new.seed = s.seed
+	new.rng = s.rng
+	new.recorder = s.recorder
+	new.savePath = s.savePath
 
 	return new
 }
 
-func broadcastToAll(out map[int]chan []byte, str string) {
-	fmt.Print(str)
+// broadcastToAll prints a message on the host and sends it to every player, returning the encoded message.
+func broadcastToAll(out map[int]chan []byte, msg Message) []byte {
+	fmt.Print(renderMessage(msg))
+	data := encodeMessage(msg)
 	for _, value := range out {
-		value <- []byte(str)
+		value <- data
 	}
+	return data
 }
 
+// getActorCardsString renders the hand of an actor, with what every point card earns it right now and why.
 func getActorCardsString(s *GameHostState, actorId int) string {
-	assert(actorId < len(s.actorData))
-	builder := strings.Builder{}
-	builder.WriteString(fmt.Sprintf("---- Player %d ----\n", actorId))
-
-	builder.WriteString(fmt.Sprintf("%d current score\n", calculateScore(s, actorId)))
-	builder.WriteString("--------\n")
-
-	for i, num := range s.actorData[actorId].vegetableNum {
-		builder.WriteString(fmt.Sprintf("%d %v\n", num, VegType(i)))
-	}
-
-	builder.WriteString("---- point cards ----\n")
-
-	for i, card := range s.actorData[actorId].pointPile {
-		builder.WriteString(fmt.Sprintf("%d: %s\n", i, card))
-	}
-	return builder.String()
+	return renderHand(getHandView(s, actorId))
 }
 
 func calculateScore(s *GameHostState, actorId int) int {
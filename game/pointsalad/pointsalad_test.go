@@ -2,11 +2,18 @@ package pointsalad
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"maps"
+	"math/rand"
 	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 var inited bool = false
@@ -16,7 +23,7 @@ func initJson() {
 	if inited {
 		return
 	}
-	data, err := os.ReadFile("../../pointsaladManifest.json")
+	data, err := os.ReadFile("PointSaladManifest.json")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -25,10 +32,20 @@ func initJson() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	jsonCards.Vegetables = baseCatalogue
+}
+
+// createEmptyActors returns actors without vegetables or point cards, for a state played with the base game.
+func createEmptyActors(num int) []ActorData {
+	actors := []ActorData{}
+	for range num {
+		actors = append(actors, ActorData{vegetableNum: make([]int, len(baseCatalogue))})
+	}
+	return actors
 }
 
 func CorrectParsing(t *testing.T, criteria string, expected Criteria) {
-	c, err := parseCriteria(criteria)
+	c, err := parseCriteria(criteria, baseCatalogue)
 	if err != nil {
 		t.Errorf("Error parsing %s, %s", criteria, err)
 		return
@@ -45,108 +62,108 @@ func TestCriteriaParsing(t *testing.T) {
 		criteria_str string
 		expected     Criteria
 	}{
-		{"MOST LETTUCE = 10", &CriteriaMost{vegType: LETTUCE, score: 10}},
-		{"MOST PEPPER = 10", &CriteriaMost{vegType: PEPPER, score: 10}},
-		{"MOST CABBAGE = 10", &CriteriaMost{vegType: CABBAGE, score: 10}},
-		{"MOST CARROT = 10", &CriteriaMost{vegType: CARROT, score: 10}},
-		{"MOST TOMATO = 10", &CriteriaMost{vegType: TOMATO, score: 10}},
-		{"MOST ONION = 10", &CriteriaMost{vegType: ONION, score: 10}},
-		{"FEWEST LETTUCE = 7", &CriteriaFewest{vegType: LETTUCE, score: 7}},
-		{"FEWEST PEPPER = 7", &CriteriaFewest{vegType: PEPPER, score: 7}},
-		{"FEWEST CABBAGE = 7", &CriteriaFewest{vegType: CABBAGE, score: 7}},
-		{"FEWEST CARROT = 7", &CriteriaFewest{vegType: CARROT, score: 7}},
-		{"FEWEST TOMATO = 7", &CriteriaFewest{vegType: TOMATO, score: 7}},
-		{"FEWEST ONION = 7", &CriteriaFewest{vegType: ONION, score: 7}},
-		{"LETTUCE: EVEN=7, ODD=3", &CriteriaEvenOdd{vegType: LETTUCE, evenScore: 7, oddScore: 3}},
-		{"PEPPER: EVEN=7, ODD=3", &CriteriaEvenOdd{vegType: PEPPER, evenScore: 7, oddScore: 3}},
-		{"CABBAGE: EVEN=7, ODD=3", &CriteriaEvenOdd{vegType: CABBAGE, evenScore: 7, oddScore: 3}},
-		{"CARROT: EVEN=7, ODD=3", &CriteriaEvenOdd{vegType: CARROT, evenScore: 7, oddScore: 3}},
-		{"TOMATO: EVEN=7, ODD=3", &CriteriaEvenOdd{vegType: TOMATO, evenScore: 7, oddScore: 3}},
-		{"ONION: EVEN=7, ODD=3", &CriteriaEvenOdd{vegType: ONION, evenScore: 7, oddScore: 3}},
-		{"2 / LETTUCE", &CriteriaPer{perScores: [6]int{0, 2, 0, 0, 0, 0}}},
-		{"2 / PEPPER", &CriteriaPer{perScores: [6]int{2, 0, 0, 0, 0, 0}}},
-		{"2 / CABBAGE", &CriteriaPer{perScores: [6]int{0, 0, 0, 2, 0, 0}}},
-		{"2 / CARROT", &CriteriaPer{perScores: [6]int{0, 0, 2, 0, 0, 0}}},
-		{"2 / TOMATO", &CriteriaPer{perScores: [6]int{0, 0, 0, 0, 0, 2}}},
-		{"2 / ONION", &CriteriaPer{perScores: [6]int{0, 0, 0, 0, 2, 0}}},
-		{"LETTUCE + LETTUCE = 5", &CriteriaSum{vegCount: [6]int{0, 2, 0, 0, 0, 0}, score: 5}},
-		{"PEPPER + PEPPER = 5", &CriteriaSum{vegCount: [6]int{2, 0, 0, 0, 0, 0}, score: 5}},
-		{"CABBAGE + CABBAGE = 5", &CriteriaSum{vegCount: [6]int{0, 0, 0, 2, 0, 0}, score: 5}},
-		{"CARROT + CARROT = 5", &CriteriaSum{vegCount: [6]int{0, 0, 2, 0, 0, 0}, score: 5}},
-		{"TOMATO + TOMATO = 5", &CriteriaSum{vegCount: [6]int{0, 0, 0, 0, 0, 2}, score: 5}},
-		{"ONION + ONION = 5", &CriteriaSum{vegCount: [6]int{0, 0, 0, 0, 2, 0}, score: 5}},
-		{"CARROT + ONION = 5", &CriteriaSum{vegCount: [6]int{0, 0, 1, 0, 1, 0}, score: 5}},
-		{"CABBAGE + ONION = 5", &CriteriaSum{vegCount: [6]int{0, 0, 0, 1, 1, 0}, score: 5}},
-		{"TOMATO + LETTUCE = 5", &CriteriaSum{vegCount: [6]int{0, 1, 0, 0, 0, 1}, score: 5}},
-		{"LETTUCE + ONION = 5", &CriteriaSum{vegCount: [6]int{0, 1, 0, 0, 1, 0}, score: 5}},
-		{"CABBAGE + LETTUCE = 5", &CriteriaSum{vegCount: [6]int{0, 1, 0, 1, 0, 0}, score: 5}},
-		{"CARROT + LETTUCE = 5", &CriteriaSum{vegCount: [6]int{0, 1, 1, 0, 0, 0}, score: 5}},
-		{"CABBAGE + TOMATO = 5", &CriteriaSum{vegCount: [6]int{0, 0, 0, 1, 0, 1}, score: 5}},
-		{"CARROT + TOMATO = 5", &CriteriaSum{vegCount: [6]int{0, 0, 1, 0, 0, 1}, score: 5}},
-		{"ONION + PEPPER = 5", &CriteriaSum{vegCount: [6]int{1, 0, 0, 0, 1, 0}, score: 5}},
-		{"TOMATO + PEPPER = 5", &CriteriaSum{vegCount: [6]int{1, 0, 0, 0, 0, 1}, score: 5}},
-		{"CARROT + PEPPER = 5", &CriteriaSum{vegCount: [6]int{1, 0, 1, 0, 0, 0}, score: 5}},
-		{"CABBAGE + PEPPER = 5", &CriteriaSum{vegCount: [6]int{1, 0, 0, 1, 0, 0}, score: 5}},
-		{"1 / LETTUCE,  1 / ONION", &CriteriaPer{perScores: [6]int{0, 1, 0, 0, 1, 0}}},
-		{"1 / PEPPER,  1 / TOMATO", &CriteriaPer{perScores: [6]int{1, 0, 0, 0, 0, 1}}},
-		{"1 / CABBAGE,  1 / LETTUCE", &CriteriaPer{perScores: [6]int{0, 1, 0, 1, 0, 0}}},
-		{"1 / CARROT,  1 / PEPPER", &CriteriaPer{perScores: [6]int{1, 0, 1, 0, 0, 0}}},
-		{"1 / TOMATO,  1 / CARROT", &CriteriaPer{perScores: [6]int{0, 0, 1, 0, 0, 1}}},
-		{"1 / ONION,  1 / CABBAGE", &CriteriaPer{perScores: [6]int{0, 0, 0, 1, 1, 0}}},
-		{"1 / LETTUCE,  1 / TOMATO", &CriteriaPer{perScores: [6]int{0, 1, 0, 0, 0, 1}}},
-		{"1 / PEPPER,  1 / ONION", &CriteriaPer{perScores: [6]int{1, 0, 0, 0, 1, 0}}},
-		{"1 / CABBAGE,  1 / PEPPER", &CriteriaPer{perScores: [6]int{1, 0, 0, 1, 0, 0}}},
-		{"1 / CARROT,  1 / LETTUCE", &CriteriaPer{perScores: [6]int{0, 1, 1, 0, 0, 0}}},
-		{"1 / TOMATO,  1 / CABBAGE", &CriteriaPer{perScores: [6]int{0, 0, 0, 1, 0, 1}}},
-		{"1 / ONION,  1 / CARROT", &CriteriaPer{perScores: [6]int{0, 0, 1, 0, 1, 0}}},
-		{"3 / LETTUCE,  -2 / CARROT", &CriteriaPer{perScores: [6]int{0, 3, -2, 0, 0, 0}}},
-		{"3 / PEPPER,  -2 / CABBAGE", &CriteriaPer{perScores: [6]int{3, 0, 0, -2, 0, 0}}},
-		{"3 / CABBAGE,  -2 / TOMATO", &CriteriaPer{perScores: [6]int{0, 0, 0, 3, 0, -2}}},
-		{"3 / CARROT,  -2 / ONION", &CriteriaPer{perScores: [6]int{0, 0, 3, 0, -2, 0}}},
-		{"3 / TOMATO,  -2 / LETTUCE", &CriteriaPer{perScores: [6]int{0, -2, 0, 0, 0, 3}}},
-		{"3 / ONION,  -2 / PEPPER", &CriteriaPer{perScores: [6]int{-2, 0, 0, 0, 3, 0}}},
-		{"LETTUCE + LETTUCE + LETTUCE = 8", &CriteriaSum{vegCount: [6]int{0, 3, 0, 0, 0, 0}, score: 8}},
-		{"PEPPER + PEPPER + PEPPER = 8", &CriteriaSum{vegCount: [6]int{3, 0, 0, 0, 0, 0}, score: 8}},
-		{"CABBAGE + CABBAGE + CABBAGE = 8", &CriteriaSum{vegCount: [6]int{0, 0, 0, 3, 0, 0}, score: 8}},
-		{"CARROT + CARROT + CARROT = 8", &CriteriaSum{vegCount: [6]int{0, 0, 3, 0, 0, 0}, score: 8}},
-		{"TOMATO + TOMATO + TOMATO = 8", &CriteriaSum{vegCount: [6]int{0, 0, 0, 0, 0, 3}, score: 8}},
-		{"ONION + ONION + ONION = 8", &CriteriaSum{vegCount: [6]int{0, 0, 0, 0, 3, 0}, score: 8}},
-		{"PEPPER + LETTUCE + CABBAGE = 8", &CriteriaSum{vegCount: [6]int{1, 1, 0, 1, 0, 0}, score: 8}},
-		{"LETTUCE + PEPPER + CARROT = 8", &CriteriaSum{vegCount: [6]int{1, 1, 1, 0, 0, 0}, score: 8}},
-		{"CARROT + CABBAGE + ONION = 8", &CriteriaSum{vegCount: [6]int{0, 0, 1, 1, 1, 0}, score: 8}},
-		{"CABBAGE + CARROT + TOMATO = 8", &CriteriaSum{vegCount: [6]int{0, 0, 1, 1, 0, 1}, score: 8}},
-		{"ONION + TOMATO + PEPPER = 8", &CriteriaSum{vegCount: [6]int{1, 0, 0, 0, 1, 1}, score: 8}},
-		{"TOMATO + ONION + LETTUCE = 8", &CriteriaSum{vegCount: [6]int{0, 1, 0, 0, 1, 1}, score: 8}},
-		{"TOMATO + LETTUCE + CARROT = 8", &CriteriaSum{vegCount: [6]int{0, 1, 1, 0, 0, 1}, score: 8}},
-		{"ONION + PEPPER + CABBAGE = 8", &CriteriaSum{vegCount: [6]int{1, 0, 0, 1, 1, 0}, score: 8}},
-		{"PEPPER + CABBAGE + TOMATO = 8", &CriteriaSum{vegCount: [6]int{1, 0, 0, 1, 0, 1}, score: 8}},
-		{"LETTUCE + CARROT + ONION = 8", &CriteriaSum{vegCount: [6]int{0, 1, 1, 0, 1, 0}, score: 8}},
-		{"CABBAGE + TOMATO + LETTUCE = 8", &CriteriaSum{vegCount: [6]int{0, 1, 0, 1, 0, 1}, score: 8}},
-		{"CARROT + ONION + PEPPER = 8", &CriteriaSum{vegCount: [6]int{1, 0, 1, 0, 1, 0}, score: 8}},
-		{"2/LETTUCE,  1/ONION,  -2/PEPPER", &CriteriaPer{perScores: [6]int{-2, 2, 0, 0, 1, 0}}},
-		{"2/PEPPER,  1/TOMATO,  -2/LETTUCE", &CriteriaPer{perScores: [6]int{2, -2, 0, 0, 0, 1}}},
-		{"2/CABBAGE,  1/LETTUCE,  -2/CARROT", &CriteriaPer{perScores: [6]int{0, 1, -2, 2, 0, 0}}},
-		{"2/CARROT,  1/PEPPER,  -2/CABBAGE", &CriteriaPer{perScores: [6]int{1, 0, 2, -2, 0, 0}}},
-		{"2/TOMATO,  1/CARROT,  -2/ONION", &CriteriaPer{perScores: [6]int{0, 0, 1, 0, -2, 2}}},
-		{"2/ONION,  1/CABBAGE,  -2/TOMATO", &CriteriaPer{perScores: [6]int{0, 0, 0, 1, 2, -2}}},
-		{"2/LETTUCE,  2/CARROT,  -4/ONION", &CriteriaPer{perScores: [6]int{0, 2, 2, 0, -4, 0}}},
-		{"2/PEPPER,  2/CABBAGE,  -4/TOMATO", &CriteriaPer{perScores: [6]int{2, 0, 0, 2, 0, -4}}},
-		{"2/CABBAGE,  2/TOMATO,  -4/LETTUCE", &CriteriaPer{perScores: [6]int{0, -4, 0, 2, 0, 2}}},
-		{"2/CARROT,  2/ONION,  -4/PEPPER", &CriteriaPer{perScores: [6]int{-4, 0, 2, 0, 2, 0}}},
-		{"2/TOMATO,  2/LETTUCE,  -4/CARROT", &CriteriaPer{perScores: [6]int{0, 2, -4, 0, 0, 2}}},
-		{"2/ONION,  2/PEPPER,  -4/CABBAGE", &CriteriaPer{perScores: [6]int{2, 0, 0, -4, 2, 0}}},
-		{"3/LETTUCE,  -1/ONION,  -1/PEPPER", &CriteriaPer{perScores: [6]int{-1, 3, 0, 0, -1, 0}}},
-		{"3/PEPPER,  -1/TOMATO,  -1/LETTUCE", &CriteriaPer{perScores: [6]int{3, -1, 0, 0, 0, -1}}},
-		{"3/CABBAGE,  -1/LETTUCE,  -1/CARROT", &CriteriaPer{perScores: [6]int{0, -1, -1, 3, 0, 0}}},
-		{"3/CARROT,  -1/PEPPER,  -1/CABBAGE", &CriteriaPer{perScores: [6]int{-1, 0, 3, -1, 0, 0}}},
-		{"3/TOMATO,  -1/CARROT,  -1/ONION", &CriteriaPer{perScores: [6]int{0, 0, -1, 0, -1, 3}}},
-		{"3/ONION,  -1/CABBAGE,  -1/TOMATO", &CriteriaPer{perScores: [6]int{0, 0, 0, -1, 3, -1}}},
-		{"4/LETTUCE,  -2/TOMATO,  -2/CABBAGE", &CriteriaPer{perScores: [6]int{0, 4, 0, -2, 0, -2}}},
-		{"4/PEPPER,  -2/ONION,  -2/CARROT", &CriteriaPer{perScores: [6]int{4, 0, -2, 0, -2, 0}}},
-		{"4/CABBAGE,  -2/PEPPER,  -2/ONION", &CriteriaPer{perScores: [6]int{-2, 0, 0, 4, -2, 0}}},
-		{"4/CARROT,  -2/LETTUCE,  -2/TOMATO", &CriteriaPer{perScores: [6]int{0, -2, 4, 0, 0, -2}}},
-		{"4/TOMATO,  -1/CABBAGE,  -2/PEPPER", &CriteriaPer{perScores: [6]int{-2, 0, 0, -1, 0, 4}}},
-		{"4/ONION,  -2/CARROT,  -2/LETTUCE", &CriteriaPer{perScores: [6]int{0, -2, -2, 0, 4, 0}}},
+		{"MOST LETTUCE = 10", &CriteriaMost{vegType: LETTUCE, score: 10, vegetables: baseCatalogue}},
+		{"MOST PEPPER = 10", &CriteriaMost{vegType: PEPPER, score: 10, vegetables: baseCatalogue}},
+		{"MOST CABBAGE = 10", &CriteriaMost{vegType: CABBAGE, score: 10, vegetables: baseCatalogue}},
+		{"MOST CARROT = 10", &CriteriaMost{vegType: CARROT, score: 10, vegetables: baseCatalogue}},
+		{"MOST TOMATO = 10", &CriteriaMost{vegType: TOMATO, score: 10, vegetables: baseCatalogue}},
+		{"MOST ONION = 10", &CriteriaMost{vegType: ONION, score: 10, vegetables: baseCatalogue}},
+		{"FEWEST LETTUCE = 7", &CriteriaFewest{vegType: LETTUCE, score: 7, vegetables: baseCatalogue}},
+		{"FEWEST PEPPER = 7", &CriteriaFewest{vegType: PEPPER, score: 7, vegetables: baseCatalogue}},
+		{"FEWEST CABBAGE = 7", &CriteriaFewest{vegType: CABBAGE, score: 7, vegetables: baseCatalogue}},
+		{"FEWEST CARROT = 7", &CriteriaFewest{vegType: CARROT, score: 7, vegetables: baseCatalogue}},
+		{"FEWEST TOMATO = 7", &CriteriaFewest{vegType: TOMATO, score: 7, vegetables: baseCatalogue}},
+		{"FEWEST ONION = 7", &CriteriaFewest{vegType: ONION, score: 7, vegetables: baseCatalogue}},
+		{"LETTUCE: EVEN=7, ODD=3", &CriteriaEvenOdd{vegType: LETTUCE, evenScore: 7, oddScore: 3, vegetables: baseCatalogue}},
+		{"PEPPER: EVEN=7, ODD=3", &CriteriaEvenOdd{vegType: PEPPER, evenScore: 7, oddScore: 3, vegetables: baseCatalogue}},
+		{"CABBAGE: EVEN=7, ODD=3", &CriteriaEvenOdd{vegType: CABBAGE, evenScore: 7, oddScore: 3, vegetables: baseCatalogue}},
+		{"CARROT: EVEN=7, ODD=3", &CriteriaEvenOdd{vegType: CARROT, evenScore: 7, oddScore: 3, vegetables: baseCatalogue}},
+		{"TOMATO: EVEN=7, ODD=3", &CriteriaEvenOdd{vegType: TOMATO, evenScore: 7, oddScore: 3, vegetables: baseCatalogue}},
+		{"ONION: EVEN=7, ODD=3", &CriteriaEvenOdd{vegType: ONION, evenScore: 7, oddScore: 3, vegetables: baseCatalogue}},
+		{"2 / LETTUCE", &CriteriaPer{perScores: []int{0, 2, 0, 0, 0, 0}, vegetables: baseCatalogue}},
+		{"2 / PEPPER", &CriteriaPer{perScores: []int{2, 0, 0, 0, 0, 0}, vegetables: baseCatalogue}},
+		{"2 / CABBAGE", &CriteriaPer{perScores: []int{0, 0, 0, 2, 0, 0}, vegetables: baseCatalogue}},
+		{"2 / CARROT", &CriteriaPer{perScores: []int{0, 0, 2, 0, 0, 0}, vegetables: baseCatalogue}},
+		{"2 / TOMATO", &CriteriaPer{perScores: []int{0, 0, 0, 0, 0, 2}, vegetables: baseCatalogue}},
+		{"2 / ONION", &CriteriaPer{perScores: []int{0, 0, 0, 0, 2, 0}, vegetables: baseCatalogue}},
+		{"LETTUCE + LETTUCE = 5", &CriteriaSum{vegCount: []int{0, 2, 0, 0, 0, 0}, score: 5, vegetables: baseCatalogue}},
+		{"PEPPER + PEPPER = 5", &CriteriaSum{vegCount: []int{2, 0, 0, 0, 0, 0}, score: 5, vegetables: baseCatalogue}},
+		{"CABBAGE + CABBAGE = 5", &CriteriaSum{vegCount: []int{0, 0, 0, 2, 0, 0}, score: 5, vegetables: baseCatalogue}},
+		{"CARROT + CARROT = 5", &CriteriaSum{vegCount: []int{0, 0, 2, 0, 0, 0}, score: 5, vegetables: baseCatalogue}},
+		{"TOMATO + TOMATO = 5", &CriteriaSum{vegCount: []int{0, 0, 0, 0, 0, 2}, score: 5, vegetables: baseCatalogue}},
+		{"ONION + ONION = 5", &CriteriaSum{vegCount: []int{0, 0, 0, 0, 2, 0}, score: 5, vegetables: baseCatalogue}},
+		{"CARROT + ONION = 5", &CriteriaSum{vegCount: []int{0, 0, 1, 0, 1, 0}, score: 5, vegetables: baseCatalogue}},
+		{"CABBAGE + ONION = 5", &CriteriaSum{vegCount: []int{0, 0, 0, 1, 1, 0}, score: 5, vegetables: baseCatalogue}},
+		{"TOMATO + LETTUCE = 5", &CriteriaSum{vegCount: []int{0, 1, 0, 0, 0, 1}, score: 5, vegetables: baseCatalogue}},
+		{"LETTUCE + ONION = 5", &CriteriaSum{vegCount: []int{0, 1, 0, 0, 1, 0}, score: 5, vegetables: baseCatalogue}},
+		{"CABBAGE + LETTUCE = 5", &CriteriaSum{vegCount: []int{0, 1, 0, 1, 0, 0}, score: 5, vegetables: baseCatalogue}},
+		{"CARROT + LETTUCE = 5", &CriteriaSum{vegCount: []int{0, 1, 1, 0, 0, 0}, score: 5, vegetables: baseCatalogue}},
+		{"CABBAGE + TOMATO = 5", &CriteriaSum{vegCount: []int{0, 0, 0, 1, 0, 1}, score: 5, vegetables: baseCatalogue}},
+		{"CARROT + TOMATO = 5", &CriteriaSum{vegCount: []int{0, 0, 1, 0, 0, 1}, score: 5, vegetables: baseCatalogue}},
+		{"ONION + PEPPER = 5", &CriteriaSum{vegCount: []int{1, 0, 0, 0, 1, 0}, score: 5, vegetables: baseCatalogue}},
+		{"TOMATO + PEPPER = 5", &CriteriaSum{vegCount: []int{1, 0, 0, 0, 0, 1}, score: 5, vegetables: baseCatalogue}},
+		{"CARROT + PEPPER = 5", &CriteriaSum{vegCount: []int{1, 0, 1, 0, 0, 0}, score: 5, vegetables: baseCatalogue}},
+		{"CABBAGE + PEPPER = 5", &CriteriaSum{vegCount: []int{1, 0, 0, 1, 0, 0}, score: 5, vegetables: baseCatalogue}},
+		{"1 / LETTUCE,  1 / ONION", &CriteriaPer{perScores: []int{0, 1, 0, 0, 1, 0}, vegetables: baseCatalogue}},
+		{"1 / PEPPER,  1 / TOMATO", &CriteriaPer{perScores: []int{1, 0, 0, 0, 0, 1}, vegetables: baseCatalogue}},
+		{"1 / CABBAGE,  1 / LETTUCE", &CriteriaPer{perScores: []int{0, 1, 0, 1, 0, 0}, vegetables: baseCatalogue}},
+		{"1 / CARROT,  1 / PEPPER", &CriteriaPer{perScores: []int{1, 0, 1, 0, 0, 0}, vegetables: baseCatalogue}},
+		{"1 / TOMATO,  1 / CARROT", &CriteriaPer{perScores: []int{0, 0, 1, 0, 0, 1}, vegetables: baseCatalogue}},
+		{"1 / ONION,  1 / CABBAGE", &CriteriaPer{perScores: []int{0, 0, 0, 1, 1, 0}, vegetables: baseCatalogue}},
+		{"1 / LETTUCE,  1 / TOMATO", &CriteriaPer{perScores: []int{0, 1, 0, 0, 0, 1}, vegetables: baseCatalogue}},
+		{"1 / PEPPER,  1 / ONION", &CriteriaPer{perScores: []int{1, 0, 0, 0, 1, 0}, vegetables: baseCatalogue}},
+		{"1 / CABBAGE,  1 / PEPPER", &CriteriaPer{perScores: []int{1, 0, 0, 1, 0, 0}, vegetables: baseCatalogue}},
+		{"1 / CARROT,  1 / LETTUCE", &CriteriaPer{perScores: []int{0, 1, 1, 0, 0, 0}, vegetables: baseCatalogue}},
+		{"1 / TOMATO,  1 / CABBAGE", &CriteriaPer{perScores: []int{0, 0, 0, 1, 0, 1}, vegetables: baseCatalogue}},
+		{"1 / ONION,  1 / CARROT", &CriteriaPer{perScores: []int{0, 0, 1, 0, 1, 0}, vegetables: baseCatalogue}},
+		{"3 / LETTUCE,  -2 / CARROT", &CriteriaPer{perScores: []int{0, 3, -2, 0, 0, 0}, vegetables: baseCatalogue}},
+		{"3 / PEPPER,  -2 / CABBAGE", &CriteriaPer{perScores: []int{3, 0, 0, -2, 0, 0}, vegetables: baseCatalogue}},
+		{"3 / CABBAGE,  -2 / TOMATO", &CriteriaPer{perScores: []int{0, 0, 0, 3, 0, -2}, vegetables: baseCatalogue}},
+		{"3 / CARROT,  -2 / ONION", &CriteriaPer{perScores: []int{0, 0, 3, 0, -2, 0}, vegetables: baseCatalogue}},
+		{"3 / TOMATO,  -2 / LETTUCE", &CriteriaPer{perScores: []int{0, -2, 0, 0, 0, 3}, vegetables: baseCatalogue}},
+		{"3 / ONION,  -2 / PEPPER", &CriteriaPer{perScores: []int{-2, 0, 0, 0, 3, 0}, vegetables: baseCatalogue}},
+		{"LETTUCE + LETTUCE + LETTUCE = 8", &CriteriaSum{vegCount: []int{0, 3, 0, 0, 0, 0}, score: 8, vegetables: baseCatalogue}},
+		{"PEPPER + PEPPER + PEPPER = 8", &CriteriaSum{vegCount: []int{3, 0, 0, 0, 0, 0}, score: 8, vegetables: baseCatalogue}},
+		{"CABBAGE + CABBAGE + CABBAGE = 8", &CriteriaSum{vegCount: []int{0, 0, 0, 3, 0, 0}, score: 8, vegetables: baseCatalogue}},
+		{"CARROT + CARROT + CARROT = 8", &CriteriaSum{vegCount: []int{0, 0, 3, 0, 0, 0}, score: 8, vegetables: baseCatalogue}},
+		{"TOMATO + TOMATO + TOMATO = 8", &CriteriaSum{vegCount: []int{0, 0, 0, 0, 0, 3}, score: 8, vegetables: baseCatalogue}},
+		{"ONION + ONION + ONION = 8", &CriteriaSum{vegCount: []int{0, 0, 0, 0, 3, 0}, score: 8, vegetables: baseCatalogue}},
+		{"PEPPER + LETTUCE + CABBAGE = 8", &CriteriaSum{vegCount: []int{1, 1, 0, 1, 0, 0}, score: 8, vegetables: baseCatalogue}},
+		{"LETTUCE + PEPPER + CARROT = 8", &CriteriaSum{vegCount: []int{1, 1, 1, 0, 0, 0}, score: 8, vegetables: baseCatalogue}},
+		{"CARROT + CABBAGE + ONION = 8", &CriteriaSum{vegCount: []int{0, 0, 1, 1, 1, 0}, score: 8, vegetables: baseCatalogue}},
+		{"CABBAGE + CARROT + TOMATO = 8", &CriteriaSum{vegCount: []int{0, 0, 1, 1, 0, 1}, score: 8, vegetables: baseCatalogue}},
+		{"ONION + TOMATO + PEPPER = 8", &CriteriaSum{vegCount: []int{1, 0, 0, 0, 1, 1}, score: 8, vegetables: baseCatalogue}},
+		{"TOMATO + ONION + LETTUCE = 8", &CriteriaSum{vegCount: []int{0, 1, 0, 0, 1, 1}, score: 8, vegetables: baseCatalogue}},
+		{"TOMATO + LETTUCE + CARROT = 8", &CriteriaSum{vegCount: []int{0, 1, 1, 0, 0, 1}, score: 8, vegetables: baseCatalogue}},
+		{"ONION + PEPPER + CABBAGE = 8", &CriteriaSum{vegCount: []int{1, 0, 0, 1, 1, 0}, score: 8, vegetables: baseCatalogue}},
+		{"PEPPER + CABBAGE + TOMATO = 8", &CriteriaSum{vegCount: []int{1, 0, 0, 1, 0, 1}, score: 8, vegetables: baseCatalogue}},
+		{"LETTUCE + CARROT + ONION = 8", &CriteriaSum{vegCount: []int{0, 1, 1, 0, 1, 0}, score: 8, vegetables: baseCatalogue}},
+		{"CABBAGE + TOMATO + LETTUCE = 8", &CriteriaSum{vegCount: []int{0, 1, 0, 1, 0, 1}, score: 8, vegetables: baseCatalogue}},
+		{"CARROT + ONION + PEPPER = 8", &CriteriaSum{vegCount: []int{1, 0, 1, 0, 1, 0}, score: 8, vegetables: baseCatalogue}},
+		{"2/LETTUCE,  1/ONION,  -2/PEPPER", &CriteriaPer{perScores: []int{-2, 2, 0, 0, 1, 0}, vegetables: baseCatalogue}},
+		{"2/PEPPER,  1/TOMATO,  -2/LETTUCE", &CriteriaPer{perScores: []int{2, -2, 0, 0, 0, 1}, vegetables: baseCatalogue}},
+		{"2/CABBAGE,  1/LETTUCE,  -2/CARROT", &CriteriaPer{perScores: []int{0, 1, -2, 2, 0, 0}, vegetables: baseCatalogue}},
+		{"2/CARROT,  1/PEPPER,  -2/CABBAGE", &CriteriaPer{perScores: []int{1, 0, 2, -2, 0, 0}, vegetables: baseCatalogue}},
+		{"2/TOMATO,  1/CARROT,  -2/ONION", &CriteriaPer{perScores: []int{0, 0, 1, 0, -2, 2}, vegetables: baseCatalogue}},
+		{"2/ONION,  1/CABBAGE,  -2/TOMATO", &CriteriaPer{perScores: []int{0, 0, 0, 1, 2, -2}, vegetables: baseCatalogue}},
+		{"2/LETTUCE,  2/CARROT,  -4/ONION", &CriteriaPer{perScores: []int{0, 2, 2, 0, -4, 0}, vegetables: baseCatalogue}},
+		{"2/PEPPER,  2/CABBAGE,  -4/TOMATO", &CriteriaPer{perScores: []int{2, 0, 0, 2, 0, -4}, vegetables: baseCatalogue}},
+		{"2/CABBAGE,  2/TOMATO,  -4/LETTUCE", &CriteriaPer{perScores: []int{0, -4, 0, 2, 0, 2}, vegetables: baseCatalogue}},
+		{"2/CARROT,  2/ONION,  -4/PEPPER", &CriteriaPer{perScores: []int{-4, 0, 2, 0, 2, 0}, vegetables: baseCatalogue}},
+		{"2/TOMATO,  2/LETTUCE,  -4/CARROT", &CriteriaPer{perScores: []int{0, 2, -4, 0, 0, 2}, vegetables: baseCatalogue}},
+		{"2/ONION,  2/PEPPER,  -4/CABBAGE", &CriteriaPer{perScores: []int{2, 0, 0, -4, 2, 0}, vegetables: baseCatalogue}},
+		{"3/LETTUCE,  -1/ONION,  -1/PEPPER", &CriteriaPer{perScores: []int{-1, 3, 0, 0, -1, 0}, vegetables: baseCatalogue}},
+		{"3/PEPPER,  -1/TOMATO,  -1/LETTUCE", &CriteriaPer{perScores: []int{3, -1, 0, 0, 0, -1}, vegetables: baseCatalogue}},
+		{"3/CABBAGE,  -1/LETTUCE,  -1/CARROT", &CriteriaPer{perScores: []int{0, -1, -1, 3, 0, 0}, vegetables: baseCatalogue}},
+		{"3/CARROT,  -1/PEPPER,  -1/CABBAGE", &CriteriaPer{perScores: []int{-1, 0, 3, -1, 0, 0}, vegetables: baseCatalogue}},
+		{"3/TOMATO,  -1/CARROT,  -1/ONION", &CriteriaPer{perScores: []int{0, 0, -1, 0, -1, 3}, vegetables: baseCatalogue}},
+		{"3/ONION,  -1/CABBAGE,  -1/TOMATO", &CriteriaPer{perScores: []int{0, 0, 0, -1, 3, -1}, vegetables: baseCatalogue}},
+		{"4/LETTUCE,  -2/TOMATO,  -2/CABBAGE", &CriteriaPer{perScores: []int{0, 4, 0, -2, 0, -2}, vegetables: baseCatalogue}},
+		{"4/PEPPER,  -2/ONION,  -2/CARROT", &CriteriaPer{perScores: []int{4, 0, -2, 0, -2, 0}, vegetables: baseCatalogue}},
+		{"4/CABBAGE,  -2/PEPPER,  -2/ONION", &CriteriaPer{perScores: []int{-2, 0, 0, 4, -2, 0}, vegetables: baseCatalogue}},
+		{"4/CARROT,  -2/LETTUCE,  -2/TOMATO", &CriteriaPer{perScores: []int{0, -2, 4, 0, 0, -2}, vegetables: baseCatalogue}},
+		{"4/TOMATO,  -1/CABBAGE,  -2/PEPPER", &CriteriaPer{perScores: []int{-2, 0, 0, -1, 0, 4}, vegetables: baseCatalogue}},
+		{"4/ONION,  -2/CARROT,  -2/LETTUCE", &CriteriaPer{perScores: []int{0, -2, -2, 0, 4, 0}, vegetables: baseCatalogue}},
 		{"MOST TOTAL VEGETABLE = 10", &CriteriaMostTotal{score: 10}},
 		{"FEWEST TOTAL VEGETABLE = 7", &CriteriaFewestTotal{score: 7}},
 		{"5 / VEGETABLE TYPE >=3", &CriteriaPerTypeGreaterThanEq{score: 5, greaterThanEq: 3}},
@@ -160,9 +177,385 @@ func TestCriteriaParsing(t *testing.T) {
 	}
 }
 
+func TestCriteriaGrammar(t *testing.T) {
+	test_table := []struct {
+		criteria_str string
+		expected     Criteria
+	}{
+		{"2 / PEPPER, 1 / LETTUCE, 1 / CARROT, -1 / CABBAGE, -1 / ONION, -2 / TOMATO", &CriteriaPer{perScores: []int{2, 1, 1, -1, -1, -2}, vegetables: baseCatalogue}},
+		{"2 PEPPER + ONION = 8", &CriteriaSum{vegCount: []int{2, 0, 0, 0, 1, 0}, score: 8, vegetables: baseCatalogue}},
+		{"3 TOMATO = 10", &CriteriaSum{vegCount: []int{0, 0, 0, 0, 0, 3}, score: 10, vegetables: baseCatalogue}},
+		{"CARROT + 2 CARROT = 9", &CriteriaSum{vegCount: []int{0, 0, 3, 0, 0, 0}, score: 9, vegetables: baseCatalogue}},
+		{"PEPPER >=4 = 5", &CriteriaThreshold{vegType: PEPPER, atLeast: 4, score: 5, vegetables: baseCatalogue}},
+		{"ONION > = 2 = -3", &CriteriaThreshold{vegType: ONION, atLeast: 2, score: -3, vegetables: baseCatalogue}},
+		{"TOTAL VEGETABLE >=12 = 10", &CriteriaThreshold{total: true, atLeast: 12, score: 10, vegetables: baseCatalogue}},
+		{"MOST PEPPER = -5", &CriteriaMost{vegType: PEPPER, score: -5, vegetables: baseCatalogue}},
+	}
+	for _, test := range test_table {
+		CorrectParsing(t, test.criteria_str, test.expected)
+	}
+
+	errors := []struct {
+		criteria_str string
+		column       int
+	}{
+		{"", 1},
+		{"MOST PEPPER =", 14},
+		{"MOST", 5},
+		{"MOST APPLE = 3", 6},
+		{"TOMATO: +", 9},
+		{"PEPPER: EVEN=7 ODD=3", 16},
+		{"2 / PEPPER, 1 / PEPPER", 17},
+		{"0 / PEPPER", 5},
+		{"2 / PEPPER,", 12},
+		{"21 PEPPER = 3", 1},
+		{"COMPLETE SET = 12 13", 19},
+		{"COMPLETE SET = 99999999999999999999", 16},
+		{"PEPPER + 99999999999999999999 LETTUCE = 5", 10},
+		{"5 / VEGETABLE TYPE >3", 21},
+		{"MOST PEPPER = 10 !", 18},
+	}
+	for _, test := range errors {
+		_, err := parseCriteria(test.criteria_str, baseCatalogue)
+		parseErr, ok := err.(*parseError)
+		if !ok {
+			t.Errorf("%q: expected a parse error got %v", test.criteria_str, err)
+			continue
+		}
+		if parseErr.pos+1 != test.column {
+			t.Errorf("%q: expected an error at column %d got %v", test.criteria_str, test.column, err)
+		}
+	}
+}
+
+func TestEveryCriteriaRoundTrip(t *testing.T) {
+	criteria := []Criteria{
+		&CriteriaMost{vegType: TOMATO, score: 10, vegetables: baseCatalogue},
+		&CriteriaFewest{vegType: ONION, score: 7, vegetables: baseCatalogue},
+		&CriteriaEvenOdd{vegType: CARROT, evenScore: 7, oddScore: -3, vegetables: baseCatalogue},
+		&CriteriaPer{perScores: []int{-1, 2, 0, 3, 0, -4}, vegetables: baseCatalogue},
+		&CriteriaSum{vegCount: []int{2, 0, 1, 0, 0, 3}, score: 12, vegetables: baseCatalogue},
+		&CriteriaMostTotal{score: 10},
+		&CriteriaFewestTotal{score: 7},
+		&CriteriaPerTypeGreaterThanEq{greaterThanEq: 2, score: 3},
+		&CriteriaPerMissingType{score: 5},
+		&CriteriaCompleteSet{score: 12},
+		&CriteriaThreshold{vegType: LETTUCE, atLeast: 4, score: 6, vegetables: baseCatalogue},
+		&CriteriaThreshold{total: true, atLeast: -1, score: 1, vegetables: baseCatalogue},
+	}
+	for _, c := range criteria {
+		CorrectParsing(t, c.String(), c)
+	}
+}
+
+func FuzzParseCriteria(f *testing.F) {
+	initJson()
+	for id := range jsonCards.Cards {
+		for i := range jsonCards.Vegetables {
+			f.Add(getJCriteria(&jsonCards, VegType(i), id))
+		}
+	}
+	for _, seed := range []string{"MOST", "3 /", "PEPPER +", "-", "", "2 PEPPER + ONION = 8", "TOTAL VEGETABLE >=12 = 10"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, criteria string) {
+		c, err := parseCriteria(criteria, baseCatalogue)
+		if err != nil {
+			if _, ok := err.(*parseError); !ok {
+				t.Errorf("%q: expected a parse error got %v", criteria, err)
+			}
+			return
+		}
+		again, err := parseCriteria(c.String(), baseCatalogue)
+		if err != nil {
+			t.Fatalf("%q: failed to parse its String %q: %v", criteria, c.String(), err)
+		}
+		if !reflect.DeepEqual(c, again) {
+			t.Errorf("%q: expected %q to parse to %#v got %#v", criteria, c.String(), c, again)
+		}
+	})
+}
+
+func TestValidateManifest(t *testing.T) {
+	initJson()
+	manifestErrors := validateManifest(&jsonCards)
+	if len(manifestErrors) != 0 {
+		t.Errorf("expected the manifest to be valid got %v", manifestErrors)
+	}
+
+	valid := jsonCards.Cards[0].Criteria
+	broken := JCards{Vegetables: baseCatalogue, Cards: []JCard{
+		{Id: 0, Criteria: maps.Clone(valid)},
+		{Id: 1, Criteria: maps.Clone(valid)},
+		{Id: 2, Criteria: maps.Clone(valid)},
+	}}
+	broken.Cards[1].Criteria["CARROT"] = "MOST"
+	broken.Cards[2].Criteria["PEPPER"] = "TOMATO: +"
+	delete(broken.Cards[2].Criteria, "ONION")
+
+	manifestErrors = validateManifest(&broken)
+	failures := []string{}
+	for _, e := range manifestErrors {
+		failures = append(failures, fmt.Sprintf("%d %s", e.CardId, e.Vegetable))
+	}
+	expected := []string{"1 CARROT", "2 PEPPER", "2 ONION"}
+	if !reflect.DeepEqual(failures, expected) {
+		t.Errorf("expected failures %v got %v", expected, manifestErrors)
+	}
+}
+
+func TestDeckConfig(t *testing.T) {
+	initJson()
+	dir := t.TempDir()
+	expansion := JCards{}
+	for i := range 4 {
+		card := jsonCards.Cards[i]
+		card.Id = 1000 + i
+		expansion.Cards = append(expansion.Cards, card)
+	}
+	writeJSON := func(name string, v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		err = os.WriteFile(path, data, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	writeJSON("expansion.json", expansion)
+	deckPath := writeJSON("deck.json", DeckConfig{
+		Manifests: []string{"base", "expansion.json"},
+		Exclude:   []int{jsonCards.Cards[0].Id, 1001},
+		DeckSizes: map[string]int{"PEPPER": 4, "ONION": 0},
+	})
+
+	cards, deck, hash, err := loadCards("", deckPath)
+	if err != nil {
+		t.Fatalf("Failed to load cards: %v", err)
+	}
+	if len(cards.Cards) != len(jsonCards.Cards)+len(expansion.Cards)-2 {
+		t.Errorf("expected the base game and expansion without 2 cards, got %d cards", len(cards.Cards))
+	}
+	_, baseHash, _ := loadManifest("")
+	if hash == baseHash {
+		t.Errorf("expected combined manifests to have another hash than the base game")
+	}
+
+	s, err := createGameHostState(&cards, deck.DeckSizes, 0, 2, 1)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState: %v", err)
+	}
+	counts := make([]int, len(cards.Vegetables))
+	for _, pile := range s.market.piles {
+		for _, card := range pile {
+			counts[card.vegType] += 1
+		}
+	}
+	expected := []int{4, 6, 6, 6, 0, 6}
+	if !slices.Equal(counts, expected) {
+		t.Errorf("expected %v cards of every vegetable got %v", expected, counts)
+	}
+	if len(s.market.piles[0]) != 10 || len(s.market.piles[2]) != 9 {
+		t.Errorf("expected 28 cards split 10, 9, 9 got %d, %d, %d", len(s.market.piles[0]), len(s.market.piles[1]), len(s.market.piles[2]))
+	}
+
+	// only the included cards are dealt, 2 players need 6 cards of every vegetable
+	includePath := writeJSON("include.json", DeckConfig{Include: []int{1, 2, 3, 4, 5}})
+	cards, deck, _, err = loadCards("", includePath)
+	if err != nil || len(cards.Cards) != 5 {
+		t.Fatalf("expected 5 included cards, got %d %v", len(cards.Cards), err)
+	}
+	_, err = createGameHostState(&cards, deck.DeckSizes, 0, 2, 1)
+	if err == nil {
+		t.Errorf("expected 5 cards to be too few for the default deck sizes")
+	}
+
+	broken := []struct {
+		name     string
+		manifest string
+		deck     DeckConfig
+	}{
+		{"duplicate ids", "base", DeckConfig{Manifests: []string{"base"}}},
+		{"unknown card", "", DeckConfig{Exclude: []int{999}}},
+		{"unknown vegetable", "", DeckConfig{DeckSizes: map[string]int{"APPLE": 3}}},
+		{"too many cards", "", DeckConfig{DeckSizes: map[string]int{"PEPPER": 19}}},
+	}
+	for _, test := range broken {
+		path := writeJSON("broken.json", test.deck)
+		_, _, _, err := loadCards(test.manifest, path)
+		if err == nil {
+			t.Errorf("%s: expected the game config to be rejected", test.name)
+		}
+	}
+
+	// a game played with a game config replays from the recording and the manifests
+	cards, deck, hash, err = loadCards("", deckPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = createGameHostState(&cards, deck.DeckSizes, 0, 3, 7)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState: %v", err)
+	}
+	recordPath := filepath.Join(dir, "game.json")
+	startRecording(&s, recordPath, hash, deck)
+	s.RunHost(map[int]chan []byte{}, map[int]chan []byte{}, nil, nil)
+	err = ReplayGame(recordPath, "", deckPath, io.Discard)
+	if err != nil {
+		t.Errorf("expected the replay to match, got %v", err)
+	}
+	err = ReplayGame(recordPath, "", "", io.Discard)
+	if err == nil {
+		t.Errorf("expected the replay without the expansion to be rejected")
+	}
+}
+
+func TestVegetableCatalogue(t *testing.T) {
+	fruits := Catalogue{
+		{Name: "APPLE", Code: "A", Colour: "red"},
+		{Name: "BANANA", Code: "B", Colour: "yellow"},
+		{Name: "CHERRY", Code: "C", Colour: "crimson"},
+		{Name: "GRAPE", Code: "G", Colour: "purple"},
+		{Name: "KIWI", Code: "K", Colour: "green"},
+		{Name: "LEMON", Code: "L"},
+		{Name: "MANGO", Code: "M", Colour: "#ff8c00"},
+	}
+	// FRUIT is the fruit of the side of the card, OTHER the next fruit
+	templates := []string{"MOST FRUIT = 10", "FEWEST OTHER = 7", "FRUIT: EVEN=7, ODD=3", "2 / FRUIT,  -1 / OTHER", "FRUIT + OTHER = 5",
+		"FRUIT >=3 = 5", "COMPLETE SET = 12", "3 / VEGETABLE TYPE >=2", "5 / MISSING VEGETABLE TYPE", "MOST TOTAL VEGETABLE = 10"}
+	manifest := JCards{Vegetables: fruits}
+	for id := range 12 {
+		card := JCard{Id: id, Criteria: map[string]string{}}
+		for i, fruit := range fruits {
+			other := fruits[(i+1)%len(fruits)]
+			replacer := strings.NewReplacer("FRUIT", fruit.Name, "OTHER", other.Name)
+			card.Criteria[fruit.Name] = replacer.Replace(templates[(id+i)%len(templates)])
+		}
+		manifest.Cards = append(manifest.Cards, card)
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fruit.json")
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cardNum, manifestErrors, err := ValidateManifest(path, "")
+	if err != nil || cardNum != 12 || len(manifestErrors) != 0 {
+		t.Fatalf("expected 12 valid cards got %d %v %v", cardNum, manifestErrors, err)
+	}
+	cards, _, _, err := loadCards(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cards.Vegetables, fruits) {
+		t.Errorf("expected the fruits of the manifest got %v", cards.Vegetables)
+	}
+	_, err = parseCriteria("MOST PEPPER = 10", fruits)
+	if err == nil {
+		t.Errorf("expected the vegetables of the base game to be unknown in a fruit salad")
+	}
+	_, _, err = loadManifests([]string{"base", path})
+	if err == nil {
+		t.Errorf("expected the fruit salad and the base game not to combine")
+	}
+
+	s, err := createGameHostState(&cards, nil, 0, 2, 3)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState: %v", err)
+	}
+	deckSize := 0
+	for _, pile := range s.market.piles {
+		deckSize += len(pile)
+	}
+	if deckSize != 7*6 {
+		t.Errorf("expected 6 cards of every fruit got %d cards", deckSize)
+	}
+
+	// remote bots and resumed games learn the fruits from the host
+	flipCardsFromPiles(&s.market)
+	market := getMarketView(&s.market, s.vegetables)
+	for _, spot := range market.Spots {
+		fruit, _ := getVegetableType(fruits, spot.Vegetable)
+		if spot.Code != fruits[fruit].Code || spot.Colour != fruits[fruit].Colour {
+			t.Errorf("expected the spot of %s to carry the code and colour of the fruit got %+v", spot.Vegetable, spot)
+		}
+		if !strings.Contains(renderMarket(market), fmt.Sprintf("%s [%s]", spot.Vegetable, spot.Code)) {
+			t.Errorf("expected the market to show %s with its code got\n%s", spot.Vegetable, renderMarket(market))
+		}
+	}
+	if hand := getHandView(&s, 0); hand.Vegetables[6].Code != "M" || hand.Vegetables[6].Colour != "#ff8c00" || !strings.Contains(renderHand(hand), "0 MANGO [M]") {
+		t.Errorf("expected the hand to show the fruits with their codes got %+v", hand.Vegetables)
+	}
+	msg, err := decodeMessage(encodeMessage(Message{Kind: MsgActionRequest, Market: &market, Hands: getHandViews(&s), Vegetables: s.vegetables}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	known, err := createGameHostStateFromMessage(msg, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("Failed to rebuild the state from a message: %v", err)
+	}
+	if !reflect.DeepEqual(known.vegetables, fruits) || len(known.actorData[0].vegetableNum) != 7 {
+		t.Errorf("expected the message to carry the fruits got %v", known.vegetables)
+	}
+	for i, pile := range known.market.piles {
+		if len(pile) != len(s.market.piles[i]) {
+			t.Errorf("expected pile %d of the rebuilt fruit salad to have %d cards got %d", i, len(s.market.piles[i]), len(pile))
+		}
+	}
+	data, err = json.Marshal(getSavedGame(&s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := SavedGame{}
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := createGameHostStateFromSave(saved)
+	if err != nil {
+		t.Fatalf("Failed to resume a fruit salad: %v", err)
+	}
+	if !reflect.DeepEqual(getHandViews(&resumed), getHandViews(&s)) || !reflect.DeepEqual(getMarketView(&resumed.market, resumed.vegetables), market) {
+		t.Errorf("expected the resumed fruit salad to be the saved one")
+	}
+
+	s.RunHost(map[int]chan []byte{}, map[int]chan []byte{}, nil, nil)
+	for _, hand := range getHandViews(&s) {
+		total := 0
+		for _, v := range hand.Vegetables {
+			total += v.Count
+		}
+		if len(hand.Vegetables) != 7 || total == 0 {
+			t.Errorf("expected a hand of fruits got %v", hand.Vegetables)
+		}
+	}
+
+	broken := []Catalogue{
+		{},
+		{{Name: "APPLE", Code: "A"}, {Name: "APPLE", Code: "B"}},
+		{{Name: "APPLE", Code: "A"}, {Name: "APRICOT", Code: "A"}},
+		{{Name: "RED APPLE", Code: "A"}},
+		{{Name: "TOTAL", Code: "T"}},
+		{{Name: "APPLE"}},
+	}
+	for _, vegetables := range broken {
+		if checkCatalogue(vegetables) == nil {
+			t.Errorf("expected %v to be rejected", vegetables)
+		}
+	}
+}
+
 // ---- Requirement 1 ----
 func correctPlayerAmount(t *testing.T, expected bool, playerNum int, botNum int) {
-	_, err := createGameHostState(&jsonCards, playerNum, botNum, 0)
+	_, err := createGameHostState(&jsonCards, nil, playerNum, botNum, 0)
 	value := err == nil
 	if expected != value {
 		t.Errorf("Expected %v got %v with %v %v\n", expected, value, playerNum, botNum)
@@ -217,12 +610,12 @@ func TestCardAmount(t *testing.T) {
 // ---- Requirement 3 ----
 
 func CorrectVegetableAmount(t *testing.T, actorNum int, expectedNumOfVegetablePerType int) {
-	s, err := createGameHostState(&jsonCards, 0, actorNum, 0)
+	s, err := createGameHostState(&jsonCards, nil, 0, actorNum, 0)
 	if err != nil {
 		t.Fatalf("Failed to create GameHostState")
 	}
 
-	vegetableNums := [vegetableTypeNum]int{}
+	vegetableNums := make([]int, len(s.vegetables))
 
 	for i1, pile := range s.market.piles {
 		for j1, card := range pile {
@@ -242,7 +635,7 @@ func CorrectVegetableAmount(t *testing.T, actorNum int, expectedNumOfVegetablePe
 
 	for i, vegetable_num := range vegetableNums {
 		if vegetable_num != expectedNumOfVegetablePerType {
-			t.Errorf("Expected %d %v got %d", expectedNumOfVegetablePerType, VegType(i), vegetable_num)
+			t.Errorf("Expected %d %s got %d", expectedNumOfVegetablePerType, s.vegetables[i].Name, vegetable_num)
 		}
 	}
 }
@@ -268,7 +661,7 @@ func TestCorrectVegetables(t *testing.T) {
 
 func TestCreate3DrawPiles(t *testing.T) {
 	initJson()
-	s, err := createGameHostState(&jsonCards, 0, 2, 0)
+	s, err := createGameHostState(&jsonCards, nil, 0, 2, 0)
 	if err != nil {
 		t.Fatalf("Failed to create GameHostState")
 	}
@@ -288,7 +681,7 @@ func TestCreate3DrawPiles(t *testing.T) {
 
 func TestCardFlipping(t *testing.T) {
 	initJson()
-	s, err := createGameHostState(&jsonCards, 0, 2, 0)
+	s, err := createGameHostState(&jsonCards, nil, 0, 2, 0)
 	if err != nil {
 		t.Fatalf("Failed to create GameHostState")
 	}
@@ -331,7 +724,7 @@ func TestRandomStartingPlayer(t *testing.T) {
 	testAmount := 10000
 
 	for i := range testAmount {
-		s, err := createGameHostState(&jsonCards, 0, 6, int64(i))
+		s, err := createGameHostState(&jsonCards, nil, 0, 6, int64(i))
 		if err != nil {
 			t.Fatalf("Failed to create GameHostState")
 		}
@@ -346,13 +739,32 @@ func TestRandomStartingPlayer(t *testing.T) {
 	}
 }
 
+func TestSeedReproducesGame(t *testing.T) {
+	initJson()
+
+	playGame := func(seed int64) []HandView {
+		s, err := createGameHostState(&jsonCards, nil, 0, 4, seed)
+		if err != nil {
+			t.Fatalf("Failed to create GameHostState")
+		}
+		s.RunHost(map[int]chan []byte{}, map[int]chan []byte{}, nil, nil)
+		return getHandViews(&s)
+	}
+
+	first := playGame(42)
+	second := playGame(42)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("expected games with the same seed to end the same, got %v and %v", first, second)
+	}
+}
+
 // ---- Requirement 7 & 8 ----
 
 func TestPlayerOptions(t *testing.T) {
 	initJson()
 	// drawing vegetables
 	{
-		host, err := createGameHostState(&jsonCards, 1, 1, 0)
+		host, err := createGameHostState(&jsonCards, nil, 1, 1, 0)
 		if err != nil {
 			t.Fatalf("Failed to create GameHostState")
 		}
@@ -371,7 +783,7 @@ func TestPlayerOptions(t *testing.T) {
 		card1 := getCardFromMarket(&host.market, 0)
 		card2 := getCardFromMarket(&host.market, 1)
 
-		host.RunHost(hostRead, hostWrite)
+		host.RunHost(hostRead, hostWrite, nil, nil)
 
 		if host.actorData[0].vegetableNum[int(card1.vegType)] == 0 {
 			t.Errorf("expected vegetable %v in actordata\n", card1.vegType)
@@ -385,7 +797,7 @@ func TestPlayerOptions(t *testing.T) {
 
 	// drawing point card, and swapping
 	{
-		host, err := createGameHostState(&jsonCards, 1, 1, 0)
+		host, err := createGameHostState(&jsonCards, nil, 1, 1, 0)
 		if err != nil {
 			t.Fatalf("Failed to create GameHostState")
 		}
@@ -402,7 +814,7 @@ func TestPlayerOptions(t *testing.T) {
 		p := host.market.piles[0]
 		card1 := p[len(p)-3]
 
-		host.RunHost(hostRead, hostWrite)
+		host.RunHost(hostRead, hostWrite, nil, nil)
 
 		if host.actorData[0].vegetableNum[int(card1.vegType)] == 0 {
 			t.Errorf("expected vegetable %v in actordata\n", card1.vegType)
@@ -415,7 +827,7 @@ func TestShowHandToOtherPlayers(t *testing.T) {
 	initJson()
 	// only works with 2 for now
 	playerAmount := 2
-	host, err := createGameHostState(&jsonCards, playerAmount, 0, 0)
+	host, err := createGameHostState(&jsonCards, nil, playerAmount, 0, 0)
 	if err != nil {
 		t.Fatalf("Failed to create GameHostState")
 	}
@@ -423,8 +835,9 @@ func TestShowHandToOtherPlayers(t *testing.T) {
 
 	hostRead := make(map[int]chan []byte)
 	hostWrite := make(map[int]chan []byte)
+	var shownHand []HandView
 
-	// create clients to check for hand string
+	// create clients to check for hand
 	for i := range playerAmount {
 		clientRead := make(chan []byte)
 		clientWrite := make(chan []byte)
@@ -433,19 +846,21 @@ func TestShowHandToOtherPlayers(t *testing.T) {
 		if i != 0 {
 			// dummyClient read
 			go func() {
-
-				// action
-				<-clientRead
-				// action cards
-				in := string(<-clientRead)
-				// player's turn
-				<-clientRead
-				<-clientRead
-				expected := getActorCardsString(&host, 0)
-				if in != expected {
-					t.Errorf("expected %v got %v for client %d\n", expected, in, i)
+				for {
+					msg, err := decodeMessage(<-clientRead)
+					if err != nil {
+						t.Errorf("failed to decode message: %v", err)
+						return
+					}
+					if msg.Kind == MsgActionResult && msg.ActorId == 0 {
+						shownHand = msg.Hands
+					}
+					if msg.Kind == MsgActionRequest {
+						view := ActionView{Kind: Quit.String()}
+						clientWrite <- encodeMessage(Message{Kind: MsgAction, ActorId: i, Action: &view})
+						return
+					}
 				}
-				clientWrite <- []byte("Q")
 			}()
 		}
 	}
@@ -453,14 +868,19 @@ func TestShowHandToOtherPlayers(t *testing.T) {
 	player0Input := "AB\n"
 	go runPlayerWithReader(hostWrite[0], hostRead[0], strings.NewReader(player0Input))
 
-	host.RunHost(hostRead, hostWrite)
+	host.RunHost(hostRead, hostWrite, nil, nil)
+
+	expected := []HandView{getHandView(&host, 0)}
+	if !reflect.DeepEqual(shownHand, expected) {
+		t.Errorf("expected %v got %v\n", expected, shownHand)
+	}
 }
 
 // ---- Requirement 10 ----
 
 func TestCardReplace(t *testing.T) {
 	initJson()
-	s, err := createGameHostState(&jsonCards, 0, 2, 0)
+	s, err := createGameHostState(&jsonCards, nil, 0, 2, 0)
 	if err != nil {
 		t.Fatalf("Failed to create GameHostState")
 	}
@@ -494,7 +914,7 @@ func TestCardReplace(t *testing.T) {
 // ---- Requirement 11 ----
 func TestSwitchingDrawPile(t *testing.T) {
 	initJson()
-	s, err := createGameHostState(&jsonCards, 0, 2, 0)
+	s, err := createGameHostState(&jsonCards, nil, 0, 2, 0)
 	if err != nil {
 		t.Fatalf("Failed to create GameHostState")
 	}
@@ -530,7 +950,7 @@ func TestSwitchingDrawPile(t *testing.T) {
 // ---- Requirement 12 & 14 ----
 func TestWinWhenEmpty(t *testing.T) {
 	initJson()
-	s, err := createGameHostState(&jsonCards, 0, 2, 0)
+	s, err := createGameHostState(&jsonCards, nil, 0, 2, 0)
 	if err != nil {
 		t.Fatalf("Failed to create GameHostState")
 	}
@@ -547,7 +967,11 @@ func TestWinWhenEmpty(t *testing.T) {
 			if len(in) == 0 {
 				break
 			}
-			if strings.Contains(string(in), "---- Final scores ----") {
+			msg, err := decodeMessage(in)
+			if err != nil {
+				break
+			}
+			if msg.Kind == MsgGameOver && len(msg.Scores) == 2 {
 				won = true
 				break
 			}
@@ -556,7 +980,7 @@ func TestWinWhenEmpty(t *testing.T) {
 	}()
 
 	hostRead := make(map[int]chan []byte)
-	s.RunHost(hostRead, hostWrite)
+	s.RunHost(hostRead, hostWrite, nil, nil)
 
 	for i, pile := range s.market.piles {
 		if len(pile) != 0 {
@@ -572,14 +996,14 @@ func TestWinWhenEmpty(t *testing.T) {
 
 // ---- Requirement 13 ----
 
-func CorrectCalculateScore(t *testing.T, expected_score int, vegetableNum [vegetableTypeNum]int, card_strs []string) {
-	s, err := createGameHostState(&jsonCards, 0, 2, 0)
+func CorrectCalculateScore(t *testing.T, expected_score int, vegetableNum []int, card_strs []string) {
+	s, err := createGameHostState(&jsonCards, nil, 0, 2, 0)
 	if err != nil {
 		t.Fatalf("Failed to create GameHostState")
 	}
 	s.actorData[0].vegetableNum = vegetableNum
 	for _, str := range card_strs {
-		c, err := parseCriteria(str)
+		c, err := parseCriteria(str, baseCatalogue)
 		if err != nil {
 			log.Fatalf("Failed to parse criteria %s", str)
 		}
@@ -601,12 +1025,12 @@ func TestCalculateScore(t *testing.T) {
 
 	test_table := []struct {
 		expected_score int
-		vegetableNum   [vegetableTypeNum]int
+		vegetableNum   []int
 		card_strs      []string
 	}{
 		{
 			13,
-			[vegetableTypeNum]int{6, 5, 4, 6, 2, 0},
+			[]int{6, 5, 4, 6, 2, 0},
 			[]string{
 				"4/ONION,  -2/CARROT,  -2/LETTUCE",
 				"4/LETTUCE,  -2/TOMATO,  -2/CABBAGE",
@@ -616,12 +1040,12 @@ func TestCalculateScore(t *testing.T) {
 		},
 		{
 			24,
-			[vegetableTypeNum]int{2, 15, 2, 2, 7, 2},
+			[]int{2, 15, 2, 2, 7, 2},
 			[]string{"COMPLETE SET = 12"},
 		},
 		{
 			0,
-			[vegetableTypeNum]int{0, 0, 0, 0, 0, 0},
+			[]int{0, 0, 0, 0, 0, 0},
 			[]string{"3 / VEGETABLE TYPE >=2"},
 		},
 	}
@@ -632,4 +1056,1019 @@ func TestCalculateScore(t *testing.T) {
 
 }
 
-// ---- End ----
+func TestTiePolicies(t *testing.T) {
+	s := GameHostState{vegetables: baseCatalogue, actorData: createEmptyActors(3)}
+	s.actorData[0].vegetableNum[CARROT] = 1
+	s.actorData[1].vegetableNum[CARROT] = 3
+	s.actorData[2].vegetableNum[CARROT] = 3
+	s.actorData[0].vegetableNum[ONION] = 2
+	s.actorData[1].vegetableNum[ONION] = 2
+	s.actorData[2].vegetableNum[ONION] = 5
+	most := &CriteriaMost{vegType: CARROT, score: 10, vegetables: baseCatalogue}
+	fewest := &CriteriaFewest{vegType: ONION, score: 7, vegetables: baseCatalogue}
+	// actor 0 has the fewest carrots alone, which pays out under every policy
+	alone := &CriteriaFewest{vegType: CARROT, score: 7, vegetables: baseCatalogue}
+
+	test_table := []struct {
+		policy       TiePolicy
+		mostScores   []int
+		fewestScores []int
+	}{
+		{"", []int{0, 10, 0}, []int{7, 0, 0}},
+		{TieFirstSeat, []int{0, 10, 0}, []int{7, 0, 0}},
+		{TieShared, []int{0, 10, 10}, []int{7, 7, 0}},
+		{TieNobody, []int{0, 0, 0}, []int{0, 0, 0}},
+	}
+	for _, test := range test_table {
+		s.tiePolicy = test.policy
+		for actorId := range s.actorData {
+			if score := most.calculateScore(&s, actorId); score != test.mostScores[actorId] {
+				t.Errorf("policy %q: expected %v to give actor %d %d points got %d", test.policy, most, actorId, test.mostScores[actorId], score)
+			}
+			if score := fewest.calculateScore(&s, actorId); score != test.fewestScores[actorId] {
+				t.Errorf("policy %q: expected %v to give actor %d %d points got %d", test.policy, fewest, actorId, test.fewestScores[actorId], score)
+			}
+			expected := 0
+			if actorId == 0 {
+				expected = 7
+			}
+			if score := alone.calculateScore(&s, actorId); score != expected {
+				t.Errorf("policy %q: expected %v to give actor %d %d points got %d", test.policy, alone, actorId, expected, score)
+			}
+		}
+	}
+
+	_, err := ParseTiePolicy("random")
+	if err == nil {
+		t.Errorf("expected an unknown tie policy to be rejected")
+	}
+	err = CheckHostConfig(HostConfig{PlayerNum: 1, BotNum: 1, TiePolicy: "everyone"})
+	if err == nil {
+		t.Errorf("expected a config with an unknown tie policy to be rejected")
+	}
+	host := GameHostState{}
+	err = host.Init(HostConfig{PlayerNum: 1, BotNum: 1, TiePolicy: "everyone"})
+	if err == nil {
+		t.Errorf("expected Init to reject an unknown tie policy")
+	}
+	err = host.Init(HostConfig{PlayerNum: 1, BotNum: 1, TiePolicy: "shared"})
+	if err != nil || host.tiePolicy != TieShared {
+		t.Errorf("expected Init to set the tie policy got %q and %v", host.tiePolicy, err)
+	}
+}
+
+func TestTotalVegetableCriteria(t *testing.T) {
+	most := &CriteriaMostTotal{score: 10}
+	fewest := &CriteriaFewestTotal{score: 7}
+
+	test_table := []struct {
+		name         string
+		policy       TiePolicy
+		totals       []int
+		mostScores   []int
+		fewestScores []int
+	}{
+		{"no tie", TieFirstSeat, []int{4, 9, 6}, []int{0, 10, 0}, []int{7, 0, 0}},
+		{"two players", TieShared, []int{3, 5}, []int{0, 10}, []int{7, 0}},
+		{"tie first seat", TieFirstSeat, []int{9, 2, 9, 2}, []int{10, 0, 0, 0}, []int{0, 7, 0, 0}},
+		{"tie shared", TieShared, []int{9, 2, 9, 2}, []int{10, 0, 10, 0}, []int{0, 7, 0, 7}},
+		{"tie nobody", TieNobody, []int{9, 2, 9, 2}, []int{0, 0, 0, 0}, []int{0, 0, 0, 0}},
+		{"everyone tied", TieShared, []int{5, 5, 5}, []int{10, 10, 10}, []int{7, 7, 7}},
+	}
+	for _, test := range test_table {
+		s := GameHostState{vegetables: baseCatalogue, actorData: createEmptyActors(len(test.totals)), tiePolicy: test.policy}
+		for i, total := range test.totals {
+			// spread the total over the vegetables, only the sum is compared
+			s.actorData[i].vegetableNum[PEPPER] = total / 2
+			s.actorData[i].vegetableNum[ONION] = total - total/2
+		}
+		for actorId := range test.totals {
+			if score := most.calculateScore(&s, actorId); score != test.mostScores[actorId] {
+				t.Errorf("%s: expected %v to give actor %d %d points got %d", test.name, most, actorId, test.mostScores[actorId], score)
+			}
+			if score := fewest.calculateScore(&s, actorId); score != test.fewestScores[actorId] {
+				t.Errorf("%s: expected %v to give actor %d %d points got %d", test.name, fewest, actorId, test.fewestScores[actorId], score)
+			}
+		}
+	}
+}
+
+func TestComparisonBreakdown(t *testing.T) {
+	s := GameHostState{vegetables: baseCatalogue, actorData: createEmptyActors(3), tiePolicy: TieNobody}
+	s.actorData[0].vegetableNum[PEPPER] = 4
+	s.actorData[1].vegetableNum[PEPPER] = 4
+	s.actorData[2].vegetableNum[PEPPER] = 1
+
+	test_table := []struct {
+		breakdown scoreBreakdown
+		expected  scoreBreakdown
+	}{
+		{(&CriteriaMost{vegType: PEPPER, score: 10, vegetables: baseCatalogue}).breakdown(&s, 0), scoreBreakdown{0, "MOST PEPPER: has 4, the most of the others is 4 (tie, ties score nothing) → 0"}},
+		{(&CriteriaMost{vegType: PEPPER, score: 10, vegetables: baseCatalogue}).breakdown(&s, 2), scoreBreakdown{0, "MOST PEPPER: has 1, the most of the others is 4 → 0"}},
+		{(&CriteriaFewest{vegType: PEPPER, score: 7, vegetables: baseCatalogue}).breakdown(&s, 2), scoreBreakdown{7, "FEWEST PEPPER: has 1, the fewest of the others is 4 → 7"}},
+		{(&CriteriaFewestTotal{score: 7}).breakdown(&s, 2), scoreBreakdown{7, "FEWEST TOTAL VEGETABLE: has 1, the fewest of the others is 4 → 7"}},
+		{(&CriteriaMostTotal{score: 10}).breakdown(&s, 1), scoreBreakdown{0, "MOST TOTAL VEGETABLE: has 4, the most of the others is 4 (tie, ties score nothing) → 0"}},
+	}
+	for _, test := range test_table {
+		if test.breakdown != test.expected {
+			t.Errorf("expected %+v got %+v", test.expected, test.breakdown)
+		}
+	}
+
+	// calculateScore does not go through the breakdown, both have to agree
+	for _, c := range []Criteria{&CriteriaMost{vegType: PEPPER, score: 10, vegetables: baseCatalogue}, &CriteriaFewest{vegType: PEPPER, score: 7, vegetables: baseCatalogue}, &CriteriaMostTotal{score: 10}, &CriteriaFewestTotal{score: 7}} {
+		for actorId := range 3 {
+			if score := c.calculateScore(&s, actorId); score != c.breakdown(&s, actorId).points {
+				t.Errorf("%v: expected actor %d to score %d like its breakdown got %d", c, actorId, c.breakdown(&s, actorId).points, score)
+			}
+		}
+	}
+
+	s.tiePolicy = TieShared
+	expected := "MOST PEPPER: has 4, the most of the others is 4 (tie, ties are shared) → 10"
+	if b := (&CriteriaMost{vegType: PEPPER, score: 10, vegetables: baseCatalogue}).breakdown(&s, 1); b.reason != expected || b.points != 10 {
+		t.Errorf("expected %q got %+v", expected, b)
+	}
+}
+
+func TestScoreBreakdown(t *testing.T) {
+	s := GameHostState{vegetables: baseCatalogue, actorData: createEmptyActors(2), playerNum: 1, botNum: 1}
+	s.actorData[0].vegetableNum = []int{3, 1, 0, 2, 3, 0}
+	s.actorData[1].vegetableNum = []int{1, 1, 1, 1, 1, 1}
+
+	test_table := []struct {
+		criteria string
+		expected CardScoreView
+	}{
+		{"MOST PEPPER = 10", CardScoreView{10, "MOST PEPPER: has 3, the most of the others is 1 → 10"}},
+		{"ONION: EVEN=7, ODD=3", CardScoreView{3, "ONION EVEN/ODD: has 3, odd → 3"}},
+		{"2 / PEPPER,  -1 / LETTUCE", CardScoreView{5, "PER VEGETABLE: 3 PEPPER × 2, 1 LETTUCE × -1 → 5"}},
+		{"PEPPER + ONION = 5", CardScoreView{15, "SET OF PEPPER + ONION: has 3 PEPPER, 3 ONION, 3 sets → 15"}},
+		{"CABBAGE + CABBAGE = 8", CardScoreView{8, "SET OF CABBAGE + CABBAGE: has 2 CABBAGE, 1 set → 8"}},
+		{"3 / VEGETABLE TYPE >=2", CardScoreView{9, "VEGETABLE TYPES >=2: has 3 (PEPPER, CABBAGE, ONION) → 9"}},
+		{"5 / MISSING VEGETABLE TYPE", CardScoreView{10, "MISSING VEGETABLE TYPES: misses 2 (CARROT, TOMATO) → 10"}},
+		{"COMPLETE SET = 12", CardScoreView{0, "COMPLETE SET: has 0 complete sets → 0"}},
+		{"FEWEST TOTAL VEGETABLE = 7", CardScoreView{0, "FEWEST TOTAL VEGETABLE: has 9, the fewest of the others is 6 → 0"}},
+	}
+	for _, test := range test_table {
+		c, err := parseCriteria(test.criteria, baseCatalogue)
+		if err != nil {
+			t.Fatalf("Failed to parse criteria %s", test.criteria)
+		}
+		s.actorData[0].pointPile = append(s.actorData[0].pointPile, Card{criteria: c, vegType: PEPPER})
+		b := c.breakdown(&s, 0)
+		got := CardScoreView{Points: b.points, Reason: b.reason}
+		if got != test.expected {
+			t.Errorf("%s: expected %+v got %+v", test.criteria, test.expected, got)
+		}
+		if strings.Contains(strings.ToLower(b.reason), "you") {
+			t.Errorf("%s: expected the reason to read right to the other players, got %q", test.criteria, b.reason)
+		}
+		if b.points != c.calculateScore(&s, 0) {
+			t.Errorf("%s: expected the breakdown to add up to the score %d got %d", test.criteria, c.calculateScore(&s, 0), b.points)
+		}
+	}
+
+	hand := getActorCardsString(&s, 0)
+	scores := getFinalScoresString(&s)
+	for _, test := range test_table {
+		if !strings.Contains(hand, test.expected.Reason) {
+			t.Errorf("expected the hand to explain %q, got\n%s", test.criteria, hand)
+		}
+		if !strings.Contains(scores, test.expected.Reason) {
+			t.Errorf("expected the final scores to explain %q, got\n%s", test.criteria, scores)
+		}
+	}
+}
+
+// ---- Protocol ----
+
+func TestActionMessages(t *testing.T) {
+	initJson()
+	s, err := createGameHostState(&jsonCards, nil, 1, 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState")
+	}
+	flipCardsFromPiles(&s.market)
+
+	test_table := []struct {
+		phase Phase
+		input string
+		valid bool
+	}{
+		{PhaseMarket, "AB", true},
+		{PhaseMarket, "0", true},
+		{PhaseMarket, "n", false},
+		{PhaseMarket, "AA", false},
+		{PhaseSwap, "n", true},
+		{PhaseSwap, "0", false},
+		{PhaseSwap, "AB", false},
+		{PhaseSwap, "Q", true},
+	}
+
+	for _, test := range test_table {
+		action, err := parsePlayerInput(test.phase, []byte(test.input))
+		if err == nil {
+			view := actionToView(action)
+			data := encodeMessage(Message{Kind: MsgAction, ActorId: s.activeActor, Action: &view, Seq: 1})
+			var decoded ActorAction
+			decoded, err = parseActionMessage(&s, test.phase, 1, data)
+			if err == nil && decoded != action {
+				t.Errorf("expected %v got %v for %s", action, decoded, test.input)
+			}
+		}
+		if (err == nil) != test.valid {
+			t.Errorf("expected valid = %v for %s in %v phase, got error %v", test.valid, test.input, test.phase, err)
+		}
+	}
+
+	// a legal action has to answer the current request, only a quit needs no seq
+	view := ActionView{Kind: pickPointFromMarket.String(), Ids: []int{0}}
+	_, err = parseActionMessage(&s, PhaseMarket, 2, encodeMessage(Message{Kind: MsgAction, ActorId: s.activeActor, Action: &view}))
+	if err == nil || err == errStaleAnswer {
+		t.Errorf("expected an action without seq to be rejected, got %v", err)
+	}
+	_, err = parseActionMessage(&s, PhaseMarket, 2, encodeMessage(Message{Kind: MsgAction, ActorId: s.activeActor, Action: &view, Seq: 1}))
+	if err != errStaleAnswer {
+		t.Errorf("expected an answer to an earlier request to be stale, got %v", err)
+	}
+	view = ActionView{Kind: Quit.String()}
+	_, err = parseActionMessage(&s, PhaseMarket, 2, encodeMessage(Message{Kind: MsgAction, ActorId: s.activeActor, Action: &view}))
+	if err != nil {
+		t.Errorf("expected a quit without seq to be accepted, got %v", err)
+	}
+	// an action naming another seat than the one asked is rejected
+	_, err = parseActionMessage(&s, PhaseMarket, 2, encodeMessage(Message{Kind: MsgAction, ActorId: 1 - s.activeActor, Action: &view}))
+	if err == nil {
+		t.Errorf("expected an action of another actor to be rejected")
+	}
+}
+
+func TestCriteriaStringRoundTrip(t *testing.T) {
+	initJson()
+	for id := range jsonCards.Cards {
+		for i := range jsonCards.Vegetables {
+			c, err := parseCriteria(getJCriteria(&jsonCards, VegType(i), id), jsonCards.Vegetables)
+			if err != nil {
+				t.Fatalf("Failed to parse criteria of card %d: %v", id, err)
+			}
+			CorrectParsing(t, c.String(), c)
+		}
+	}
+}
+
+func TestRemoteBots(t *testing.T) {
+	initJson()
+	playerAmount := 3
+	host, err := createGameHostState(&jsonCards, nil, playerAmount, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState")
+	}
+
+	hostRead := make(map[int]chan []byte)
+	hostWrite := make(map[int]chan []byte)
+	for i := range playerAmount {
+		hostRead[i] = make(chan []byte)
+		hostWrite[i] = make(chan []byte)
+		bot := GamePlayerState{}
+		bot.InitBot(&RandomStrategy{})
+		// seeded so every run plays the same game
+		bot.rng = rand.New(rand.NewSource(int64(i)))
+		go bot.RunPlayer(hostWrite[i], hostRead[i])
+	}
+
+	host.RunHost(hostRead, hostWrite, nil, nil)
+
+	if !hasWon(&host) {
+		t.Errorf("expected remote bots to play the game to the end")
+	}
+
+	// remote bots score a shared tie like the host
+	host, err = createGameHostState(&jsonCards, nil, 2, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState")
+	}
+	host.tiePolicy = TieShared
+	most, err := parseCriteria("MOST PEPPER = 10", host.vegetables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host.activeActor = 0
+	host.actorData[0].vegetableNum[PEPPER] = 2
+	host.actorData[1].vegetableNum[PEPPER] = 2
+	host.actorData[1].pointPile = []Card{{criteria: most, vegType: PEPPER}}
+	hostRead = map[int]chan []byte{0: make(chan []byte), 1: make(chan []byte)}
+	hostWrite = map[int]chan []byte{0: make(chan []byte, 16), 1: make(chan []byte, 16)}
+	done := make(chan bool)
+	go func() {
+		host.RunHost(hostRead, hostWrite, nil, nil)
+		done <- true
+	}()
+	for {
+		msg, err := decodeMessage(<-hostWrite[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Kind != MsgActionRequest {
+			continue
+		}
+		known, err := createGameHostStateFromMessage(msg, rand.New(rand.NewSource(1)))
+		if err != nil {
+			t.Fatalf("Failed to rebuild the state from a message: %v", err)
+		}
+		if known.tiePolicy != TieShared || calculateScore(&known, 1) != 10 {
+			t.Errorf("expected the remote bot to score the tie shared like the host got %q and %d points", known.tiePolicy, calculateScore(&known, 1))
+		}
+		break
+	}
+	view := actionToView(ActorAction{kind: Quit})
+	hostRead[0] <- encodeMessage(Message{Kind: MsgAction, ActorId: 0, Action: &view})
+	<-done
+}
+
+func TestRemoteStatePiles(t *testing.T) {
+	initJson()
+	host, err := createGameHostState(&jsonCards, nil, 2, 1, 5)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState")
+	}
+	flipCardsFromPiles(&host.market)
+	host.actorData[1].pointPile = append(host.actorData[1].pointPile, host.market.piles[0][len(host.market.piles[0])-1])
+	host.market.piles[0] = host.market.piles[0][:len(host.market.piles[0])-1]
+	host.market.piles[2] = host.market.piles[2][:3]
+
+	market := getMarketView(&host.market, host.vegetables)
+	msg, err := decodeMessage(encodeMessage(Message{Kind: MsgActionRequest, ActorId: 1, Market: &market, Hands: getHandViews(&host)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	known, err := createGameHostStateFromMessage(msg, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("Failed to rebuild the state from a message: %v", err)
+	}
+
+	// the piles have the sizes of the host, with the visible top cards on top
+	for i, pile := range known.market.piles {
+		hostPile := host.market.piles[i]
+		if len(pile) != len(hostPile) {
+			t.Fatalf("expected pile %d to have %d cards got %d", i, len(hostPile), len(pile))
+		}
+		top, hostTop := pile[len(pile)-1], hostPile[len(hostPile)-1]
+		if top.vegType != hostTop.vegType || top.criteria.String() != hostTop.criteria.String() {
+			t.Errorf("expected pile %d to keep its top card %v got %v", i, hostTop.criteria, top.criteria)
+		}
+	}
+
+	// the hidden cards are drawn from the base manifest without the visible cards
+	key := func(card Card) string {
+		return fmt.Sprintf("%d %s", card.vegType, card.criteria)
+	}
+	left := map[string]int{}
+	for id := range jsonCards.Cards {
+		for i := range jsonCards.Vegetables {
+			criteria, err := parseCriteria(getJCriteria(&jsonCards, VegType(i), id), jsonCards.Vegetables)
+			if err != nil {
+				t.Fatal(err)
+			}
+			left[key(Card{criteria: criteria, vegType: VegType(i)})] += 1
+		}
+	}
+	cards := slices.Concat(known.actorData[1].pointPile, slices.Concat(known.market.piles...))
+	for _, card := range cards {
+		left[key(card)] -= 1
+		if left[key(card)] < 0 {
+			t.Errorf("expected the hidden cards to be cards of the manifest that are not visible, got one too many %s", key(card))
+		}
+	}
+
+	// a remote ISMCTS bot searches the game with every hidden card
+	action := (&ISMCTSStrategy{Iterations: 50}).getMarketAction(BotView{s: &known})
+	if isActionLegal(&known, action) != nil {
+		t.Errorf("expected a legal action got %v", action)
+	}
+}
+
+func TestSpectators(t *testing.T) {
+	initJson()
+	host, err := createGameHostState(&jsonCards, nil, 2, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState")
+	}
+
+	hostRead := make(map[int]chan []byte)
+	hostWrite := make(map[int]chan []byte)
+	for i := range 2 {
+		hostRead[i] = make(chan []byte)
+		hostWrite[i] = make(chan []byte)
+		bot := GamePlayerState{}
+		bot.InitBot(&RandomStrategy{})
+		bot.rng = rand.New(rand.NewSource(int64(i)))
+		go bot.RunPlayer(hostWrite[i], hostRead[i])
+	}
+
+	spectators := make(chan chan []byte)
+	late := make(chan []byte, 256)
+	type watched struct {
+		name     string
+		messages []Message
+	}
+	done := make(chan watched)
+	watch := func(name string, ch chan []byte) {
+		w := watched{name: name}
+		for data := range ch {
+			msg, err := decodeMessage(data)
+			if err != nil {
+				t.Errorf("failed to decode message: %v", err)
+			}
+			w.messages = append(w.messages, msg)
+			// the second spectator joins a running game
+			if name == "early" && msg.Kind == MsgActionResult && late != nil {
+				spectators <- late
+				late = nil
+			}
+			if msg.Kind == MsgGameOver {
+				done <- w
+				return
+			}
+		}
+	}
+	early := make(chan []byte, 256)
+	go watch("early", early)
+	go watch("late", late)
+	go func() {
+		spectators <- early
+	}()
+
+	host.RunHost(hostRead, hostWrite, nil, spectators)
+	messages := map[string][]Message{}
+	for range 2 {
+		w := <-done
+		messages[w.name] = w.messages
+	}
+
+	if host.playerNum != 2 {
+		t.Errorf("expected spectators not to count as players, got %d players", host.playerNum)
+	}
+	for name, received := range messages {
+		for _, msg := range received {
+			if msg.Kind == MsgActionRequest {
+				t.Errorf("expected the %s spectator never to be asked for an action", name)
+			}
+		}
+		if len(received) < 2 || received[0].Kind != MsgNotice || received[1].Kind != MsgSnapshot {
+			t.Fatalf("expected the %s spectator to start with a notice and a snapshot", name)
+		}
+		snapshot := received[1]
+		if snapshot.Market == nil || len(snapshot.Hands) != 2 {
+			t.Errorf("expected the snapshot to hold the market and 2 hands")
+		}
+	}
+	// the late spectator joined after the first action, so its snapshot shows the cards taken
+	taken := 0
+	for _, hand := range messages["late"][1].Hands {
+		taken += len(hand.PointCards)
+		for _, v := range hand.Vegetables {
+			taken += v.Count
+		}
+	}
+	if taken == 0 {
+		t.Errorf("expected the snapshot of the late spectator to show the cards taken so far")
+	}
+}
+
+func TestStalledSpectator(t *testing.T) {
+	initJson()
+	host, err := createGameHostState(&jsonCards, nil, 2, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState")
+	}
+
+	hostRead := make(map[int]chan []byte)
+	hostWrite := make(map[int]chan []byte)
+	for i := range 2 {
+		hostRead[i] = make(chan []byte)
+		hostWrite[i] = make(chan []byte)
+		bot := GamePlayerState{}
+		bot.InitBot(&RandomStrategy{})
+		bot.rng = rand.New(rand.NewSource(int64(i)))
+		go bot.RunPlayer(hostWrite[i], hostRead[i])
+	}
+
+	// the spectator never reads, its buffer is full after the first message
+	stalled := make(chan []byte, 1)
+	spectators := make(chan chan []byte, 1)
+	spectators <- stalled
+	finished := make(chan bool)
+	go func() {
+		host.RunHost(hostRead, hostWrite, nil, spectators)
+		finished <- true
+	}()
+	select {
+	case <-finished:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected a stalled spectator not to hold up the game")
+	}
+
+	if !hasWon(&host) {
+		t.Errorf("expected the game to be played to the end")
+	}
+	if _, ok := <-stalled; !ok {
+		t.Fatalf("expected the stalled spectator to get the first message")
+	}
+	if _, ok := <-stalled; ok {
+		t.Errorf("expected the channel of the stalled spectator to be closed")
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	initJson()
+	host, err := createGameHostState(&jsonCards, nil, 2, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState")
+	}
+	host.activeActor = 0
+	host.gracePeriod = time.Minute
+
+	hostRead := map[int]chan []byte{0: make(chan []byte), 1: make(chan []byte)}
+	hostWrite := map[int]chan []byte{0: make(chan []byte), 1: make(chan []byte)}
+	status := map[int]chan bool{0: make(chan bool, 1), 1: make(chan bool, 1)}
+
+	go func() {
+		for range hostWrite[1] {
+		}
+	}()
+
+	kinds := []MessageKind{}
+	go func() {
+		requests := 0
+		for data := range hostWrite[0] {
+			msg, err := decodeMessage(data)
+			if err != nil {
+				t.Errorf("failed to decode message: %v", err)
+				return
+			}
+			kinds = append(kinds, msg.Kind)
+			if msg.Kind != MsgActionRequest {
+				continue
+			}
+			requests += 1
+			if requests == 1 {
+				status[0] <- false
+				status[0] <- true
+			} else {
+				view := ActionView{Kind: Quit.String()}
+				hostRead[0] <- encodeMessage(Message{Kind: MsgAction, Action: &view})
+				return
+			}
+		}
+	}()
+
+	host.RunHost(hostRead, hostWrite, status, nil)
+
+	expected := []MessageKind{MsgStateUpdate, MsgActionRequest, MsgNotice, MsgNotice, MsgStateUpdate, MsgActionRequest}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("expected %v got %v", expected, kinds)
+	}
+}
+
+func TestBotFallback(t *testing.T) {
+	initJson()
+	host, err := createGameHostState(&jsonCards, nil, 1, 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState")
+	}
+	host.gracePeriod = time.Millisecond
+
+	hostRead := map[int]chan []byte{0: make(chan []byte)}
+	hostWrite := map[int]chan []byte{0: make(chan []byte)}
+	status := map[int]chan bool{0: make(chan bool, 1)}
+	status[0] <- false
+
+	fallback := make(chan bool)
+	go func() {
+		seenFallback := false
+		for data := range hostWrite[0] {
+			msg, _ := decodeMessage(data)
+			if msg.Kind == MsgNotice && strings.Contains(msg.Text, "a bot plays the seat") {
+				seenFallback = true
+			}
+			if msg.Kind == MsgGameOver {
+				break
+			}
+		}
+		fallback <- seenFallback
+	}()
+
+	host.RunHost(hostRead, hostWrite, status, nil)
+
+	if !<-fallback {
+		t.Errorf("expected the seat of the disconnected player to fall back to a bot")
+	}
+	if !hasWon(&host) {
+		t.Errorf("expected the game to be played to the end")
+	}
+}
+
+func TestTurnTimeout(t *testing.T) {
+	initJson()
+	host, err := createGameHostState(&jsonCards, nil, 1, 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState")
+	}
+	host.turnTimeout = 5 * time.Millisecond
+
+	// buffered since the host only reads the answers of the player during its turn
+	hostRead := map[int]chan []byte{0: make(chan []byte, 2)}
+	hostWrite := map[int]chan []byte{0: make(chan []byte)}
+
+	automatic := 0
+	afk := false
+	back := false
+	done := make(chan bool)
+	go func() {
+		for data := range hostWrite[0] {
+			msg, _ := decodeMessage(data)
+			if msg.Kind == MsgActionResult && msg.ActorId == 0 && msg.Action.Automatic {
+				automatic += 1
+			}
+			if msg.Kind == MsgNotice && strings.Contains(msg.Text, "is AFK") {
+				afk = true
+			}
+			if msg.Kind == MsgNotice && strings.Contains(msg.Text, "is back") {
+				back = true
+			}
+			if msg.Kind == MsgActionRequest && afk {
+				// an answer to the first request is ignored, the quit ends the game
+				view := ActionView{Kind: pickPointFromMarket.String(), Ids: []int{0}}
+				hostRead[0] <- encodeMessage(Message{Kind: MsgAction, Action: &view, Seq: 1})
+				view = ActionView{Kind: Quit.String()}
+				hostRead[0] <- encodeMessage(Message{Kind: MsgAction, Action: &view, Seq: msg.Seq})
+				break
+			}
+		}
+		done <- true
+		for range hostWrite[0] {
+		}
+	}()
+
+	host.RunHost(hostRead, hostWrite, nil, nil)
+	<-done
+
+	if automatic < afkTimeouts {
+		t.Errorf("expected at least %d automatic moves got %d", afkTimeouts, automatic)
+	}
+	if !afk {
+		t.Errorf("expected the player to be marked AFK")
+	}
+	if back {
+		t.Errorf("expected the stale answer to be ignored")
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	initJson()
+	s, err := createGameHostState(&jsonCards, nil, 0, 3, 42)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState")
+	}
+	path := filepath.Join(t.TempDir(), "game.json")
+	startRecording(&s, path, "", DeckConfig{})
+	s.RunHost(map[int]chan []byte{}, map[int]chan []byte{}, nil, nil)
+
+	recording, err := loadRecording(path)
+	if err != nil {
+		t.Fatalf("Failed to load recording: %v", err)
+	}
+	if recording.Seed != 42 || len(recording.Actions) == 0 {
+		t.Fatalf("expected the seed and actions to be recorded, got seed %d and %d actions", recording.Seed, len(recording.Actions))
+	}
+
+	err = replayRecording(&jsonCards, recording, io.Discard)
+	if err != nil {
+		t.Errorf("expected replay to match the recording, got %v", err)
+	}
+
+	tampered := recording
+	tampered.Scores = slices.Clone(recording.Scores)
+	tampered.Scores[0].Score += 1
+	err = replayRecording(&jsonCards, tampered, io.Discard)
+	if err == nil {
+		t.Errorf("expected replay to detect scores that do not match")
+	}
+
+	tampered = recording
+	tampered.Actions = slices.Clone(recording.Actions)
+	tampered.Actions[0].ActorId = (tampered.Actions[0].ActorId + 1) % 3
+	err = replayRecording(&jsonCards, tampered, io.Discard)
+	if err == nil {
+		t.Errorf("expected replay to detect an action out of turn")
+	}
+
+	// a game that was quit stops before it is over
+	tampered = recording
+	tampered.Actions = recording.Actions[:len(recording.Actions)/2]
+	err = replayRecording(&jsonCards, tampered, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "quit") {
+		t.Errorf("expected replay to report a game that was quit, got %v", err)
+	}
+}
+
+func TestSaveAndResume(t *testing.T) {
+	initJson()
+	s, err := createGameHostState(&jsonCards, nil, 1, 2, 42)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState")
+	}
+	s.tiePolicy = TieShared
+	for range 5 {
+		flipCardsFromPiles(&s.market)
+		doAction(&s, getMarketActionFromBot(&s))
+		s.activeActor = (s.activeActor + 1) % 3
+	}
+
+	path := filepath.Join(t.TempDir(), "save.json")
+	err = saveGame(&s, path)
+	if err != nil {
+		t.Fatalf("Failed to save game: %v", err)
+	}
+	saved, err := loadGame(path)
+	if err != nil {
+		t.Fatalf("Failed to load game: %v", err)
+	}
+	resumed, err := createGameHostStateFromSave(saved)
+	if err != nil {
+		t.Fatalf("Failed to resume game: %v", err)
+	}
+
+	if !reflect.DeepEqual(s.market, resumed.market) {
+		t.Errorf("expected the market to survive saving")
+	}
+	if !reflect.DeepEqual(s.actorData, resumed.actorData) {
+		t.Errorf("expected the hands to survive saving")
+	}
+	if s.activeActor != resumed.activeActor || s.playerNum != resumed.playerNum || s.botNum != resumed.botNum {
+		t.Errorf("expected the active actor and player counts to survive saving")
+	}
+	if resumed.tiePolicy != TieShared {
+		t.Errorf("expected the tie policy to survive saving, got %q", resumed.tiePolicy)
+	}
+
+	saved.Spots[0] = &CardView{Vegetable: "PEPPER", Criteria: "NOT A CRITERIA"}
+	_, err = createGameHostStateFromSave(saved)
+	if err == nil {
+		t.Errorf("expected a save with an invalid criteria to be rejected")
+	}
+}
+
+func TestRandomBotWhenEveryActionLowersScore(t *testing.T) {
+	initJson()
+	s, err := createGameHostState(&jsonCards, nil, 0, 2, 0)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState")
+	}
+	c, err := parseCriteria("3 / CARROT,  -2 / ONION", baseCatalogue)
+	if err != nil {
+		t.Fatalf("Failed to parse criteria: %v", err)
+	}
+	s.actorData[s.activeActor].pointPile = []Card{{criteria: c, vegType: CARROT}}
+
+	// only onions are left, taking any of them lowers the score
+	for i := range s.market.piles {
+		s.market.piles[i] = []Card{}
+	}
+	for i := range s.market.cardSpots {
+		s.market.cardSpots[i] = CardSpot{hasCard: true, card: Card{criteria: c, vegType: ONION}}
+	}
+
+	action := getMarketActionFromBot(&s)
+	if isActionLegal(&s, action) != nil {
+		t.Errorf("expected a legal action got %v", action)
+	}
+	if action.kind != pickVegFromMarket || action.amount != 1 {
+		t.Errorf("expected the bot to settle for the action lowering its score the least, got %v", action)
+	}
+}
+
+func TestBotStrategies(t *testing.T) {
+	initJson()
+	for _, name := range []string{"random", "greedy", "lookahead"} {
+		strategy, err := GetBotStrategy(name)
+		if err != nil {
+			t.Fatalf("expected strategy %s to exist: %v", name, err)
+		}
+		if strategy.String() != name {
+			t.Errorf("expected strategy %s got %s", name, strategy)
+		}
+	}
+	_, err := GetBotStrategy("unknown")
+	if err == nil {
+		t.Errorf("expected an unknown strategy to be rejected")
+	}
+
+	s, err := createGameHostState(&jsonCards, nil, 0, 3, 0)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState")
+	}
+	flipCardsFromPiles(&s.market)
+	greedy := (&GreedyStrategy{}).getMarketAction(BotView{s: &s})
+	best := getScoreAfterAction(&s, greedy)
+	for _, action := range getLegalMarketActions(&s) {
+		if getScoreAfterAction(&s, action) > best {
+			t.Errorf("expected greedy to pick the highest scoring action, %v beats %v", action, greedy)
+		}
+	}
+
+	err = setBotStrategies(&s, []string{"greedy", "lookahead", "random"})
+	if err != nil {
+		t.Fatalf("Failed to set bot strategies: %v", err)
+	}
+	s.RunHost(map[int]chan []byte{}, map[int]chan []byte{}, nil, nil)
+	if !hasWon(&s) {
+		t.Errorf("expected the bots to play the game to the end")
+	}
+
+	err = setBotStrategies(&s, []string{"greedy", "greedy", "greedy", "greedy"})
+	if err == nil {
+		t.Errorf("expected more strategies than bots to be rejected")
+	}
+}
+
+func TestLookaheadReplies(t *testing.T) {
+	s := GameHostState{vegetables: baseCatalogue, actorData: createEmptyActors(2)}
+	mostLettuce, err := parseCriteria("MOST LETTUCE = 10", baseCatalogue)
+	if err != nil {
+		t.Fatalf("Failed to parse criteria: %v", err)
+	}
+	perPepper, err := parseCriteria("2 / PEPPER", baseCatalogue)
+	if err != nil {
+		t.Fatalf("Failed to parse criteria: %v", err)
+	}
+	s.actorData[0].vegetableNum[LETTUCE] = 1
+	s.actorData[0].pointPile = []Card{{criteria: mostLettuce, vegType: ONION}}
+	s.actorData[1].pointPile = []Card{{criteria: perPepper, vegType: ONION}}
+	s.market = Market{piles: [][]Card{{}, {}, {}}, cardSpots: make([]CardSpot, 6)}
+	s.market.cardSpots[0] = CardSpot{hasCard: true, card: Card{criteria: perPepper, vegType: PEPPER}}
+	s.market.cardSpots[1] = CardSpot{hasCard: true, card: Card{criteria: perPepper, vegType: LETTUCE}}
+	s.market.cardSpots[2] = CardSpot{hasCard: true, card: Card{criteria: perPepper, vegType: LETTUCE}}
+
+	// taking the pepper scores the other actor the most right away, but taking both lettuces
+	// takes MOST LETTUCE away from actor 0 and leaves it further ahead
+	margin := getMarginAfterReplies(BotView{s: &s}, 0)
+	if margin != 0 {
+		t.Errorf("expected the other actor to answer with both lettuces leaving a margin of 0, got %d", margin)
+	}
+}
+
+func TestISMCTSStrategy(t *testing.T) {
+	initJson()
+	test_table := []struct {
+		name       string
+		iterations int
+		duration   time.Duration
+		valid      bool
+	}{
+		{"ismcts", ismctsDefaultIterations, ismctsDefaultDuration, true},
+		{"ismcts:500", 500, ismctsDefaultDuration, true},
+		{"ismcts:0:10ms", 0, 10 * time.Millisecond, true},
+		{"ismcts:x", 0, 0, false},
+		{"ismcts:0:0s", 0, 0, false},
+		{"ismctsfoo", 0, 0, false},
+	}
+	for _, test := range test_table {
+		strategy, err := GetBotStrategy(test.name)
+		if (err == nil) != test.valid {
+			t.Errorf("expected valid = %v for %s, got error %v", test.valid, test.name, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		ismcts := strategy.(*ISMCTSStrategy)
+		if ismcts.Iterations != test.iterations || ismcts.Duration != test.duration {
+			t.Errorf("expected budgets %d and %v for %s got %d and %v", test.iterations, test.duration, test.name, ismcts.Iterations, ismcts.Duration)
+		}
+		// the name given by String selects the same strategy
+		again, err := GetBotStrategy(strategy.String())
+		if err != nil || !reflect.DeepEqual(again, strategy) {
+			t.Errorf("expected %s to select the same strategy got %v", strategy, err)
+		}
+	}
+
+	// with only an iteration budget the search is reproducible
+	s, err := createGameHostState(&jsonCards, nil, 0, 3, 0)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState")
+	}
+	flipCardsFromPiles(&s.market)
+	strategy := &ISMCTSStrategy{Iterations: 200, Workers: 2}
+	s.rng = rand.New(rand.NewSource(1))
+	first := strategy.getMarketAction(BotView{s: &s})
+	s.rng = rand.New(rand.NewSource(1))
+	second := strategy.getMarketAction(BotView{s: &s})
+	if first != second {
+		t.Errorf("expected the same action with the same seed, got %v and %v", first, second)
+	}
+	if isActionLegal(&s, first) != nil {
+		t.Errorf("expected a legal action got %v", first)
+	}
+
+	// the search plays much better than picking random actions
+	wins := 0
+	for seed := range 4 {
+		s, err := createGameHostState(&jsonCards, nil, 0, 2, int64(seed))
+		if err != nil {
+			t.Fatalf("Failed to create GameHostState")
+		}
+		s.botStrategies = []BotStrategy{&ISMCTSStrategy{Iterations: 100, Workers: 2}, &RandomStrategy{}}
+		s.RunHost(map[int]chan []byte{}, map[int]chan []byte{}, nil, nil)
+		if calculateScore(&s, 0) > calculateScore(&s, 1) {
+			wins += 1
+		}
+	}
+	if wins < 3 {
+		t.Errorf("expected ismcts to win at least 3 of 4 games against random, won %d", wins)
+	}
+}
+
+// ---- End ----
+
+func TestPlayerNames(t *testing.T) {
+	initJson()
+	s, err := createGameHostState(&jsonCards, nil, 3, 1, 1)
+	if err != nil {
+		t.Fatalf("Failed to create GameHostState")
+	}
+	err = setBotStrategies(&s, []string{"greedy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetPlayerNames(map[int]string{0: " Ada\x07 ", 1: "Ada", 2: strings.Repeat("x", 40)})
+
+	expected := []ActorInfo{
+		{Name: "Ada", Kind: ActorHuman},
+		{Name: "Ada (1)", Kind: ActorHuman},
+		{Name: strings.Repeat("x", maxNameLength), Kind: ActorHuman},
+		{Name: "Bot 3", Kind: ActorBot, Strategy: "greedy"},
+	}
+	if !reflect.DeepEqual(getActorInfos(&s), expected) {
+		t.Errorf("expected %v got %v", expected, getActorInfos(&s))
+	}
+
+	scores := getFinalScoresString(&s)
+	for _, name := range []string{"Ada (human) with score", "Ada (1) (human) with score", "Bot 3 (greedy bot) with score"} {
+		if !strings.Contains(scores, name) {
+			t.Errorf("expected %q in the final scores\n%s", name, scores)
+		}
+	}
+	if strings.Contains(scores, "Player") {
+		t.Errorf("expected only names in the final scores\n%s", scores)
+	}
+	if cards := getActorCardsString(&s, 3); !strings.HasPrefix(cards, "---- Bot 3 (greedy bot) ----\n") {
+		t.Errorf("expected the hand of the bot to start with its name got\n%s", cards)
+	}
+
+	view := ActionView{Kind: pickToSwap.String()}
+	text := renderMessage(Message{Kind: MsgActionResult, ActorId: 1, Actor: getMessageActor(&s, 1), Action: &view})
+	if !strings.Contains(text, "Ada (1) did not swap any card") {
+		t.Errorf("expected the action to show the name got\n%s", text)
+	}
+	// messages of hosts that send no actor still show the actor id
+	text = renderMessage(Message{Kind: MsgStateUpdate, ActorId: 2})
+	if text != "---- Player 2's turn ----\n" {
+		t.Errorf("expected the actor id without a name got %q", text)
+	}
+
+	// players that connected as bots are shown as bots with their strategy
+	s.SetPlayerBots(map[int]string{1: "lookahead", 2: "greedy"})
+	if got := getActorInfo(&s, 1); got != (ActorInfo{Name: "Ada (1)", Kind: ActorBot, Strategy: "lookahead"}) {
+		t.Errorf("expected a remote bot with its name and strategy got %+v", got)
+	}
+	if got := getActorInfo(&s, 0); got.Kind != ActorHuman {
+		t.Errorf("expected a player that did not connect as a bot to be human got %+v", got)
+	}
+	if scores := getFinalScoresString(&s); !strings.Contains(scores, "Ada (1) (lookahead bot) with score") {
+		t.Errorf("expected the remote bot to be shown with its strategy\n%s", scores)
+	}
+	s.SetPlayerBots(nil)
+
+	// names are kept when the game is saved and resumed
+	resumed, err := createGameHostStateFromSave(getSavedGame(&s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every seat is kept for the name its player connected with
+	expectedSeats := map[int]string{0: " Ada\x07 ", 1: "Ada", 2: strings.Repeat("x", 40)}
+	if seats := resumed.GetSeatNames(); !reflect.DeepEqual(seats, expectedSeats) {
+		t.Errorf("expected the seats to be kept for %q got %q", expectedSeats, seats)
+	}
+	if seats := (&GameHostState{playerNum: 2}).GetSeatNames(); seats != nil {
+		t.Errorf("expected the seats of a new game to be free for anyone got %v", seats)
+	}
+	resumed.SetPlayerNames(map[int]string{2: "Grace"})
+	if got := getActorInfo(&resumed, 0).Name; got != "Ada" {
+		t.Errorf("expected the saved name %q got %q", "Ada", got)
+	}
+	if got := getActorInfo(&resumed, 2).Name; got != "Grace" {
+		t.Errorf("expected the new name %q got %q", "Grace", got)
+	}
+}
@@ -0,0 +1,526 @@
+package pointsalad
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MessageKind identifies what a Message carries. Host messages use every kind except
+// MsgAction, which is the only kind sent by players.
+type MessageKind string
+
+const (
+	MsgStateUpdate   MessageKind = "StateUpdate"
+	MsgActionRequest MessageKind = "ActionRequest"
+	MsgActionResult  MessageKind = "ActionResult"
+	MsgError         MessageKind = "Error"
+	MsgGameOver      MessageKind = "GameOver"
+	MsgNotice        MessageKind = "Notice"
+	MsgSnapshot      MessageKind = "Snapshot"
+	MsgAction        MessageKind = "Action"
+)
+
+type Phase string
+
+const (
+	PhaseMarket Phase = "market"
+	PhaseSwap   Phase = "swap"
+)
+
+// ActorKind tells whether an actor is a human player or a bot run by the host.
+type ActorKind string
+
+const (
+	ActorHuman ActorKind = "human"
+	ActorBot   ActorKind = "bot"
+)
+
+// ActorInfo describes who plays a seat. Name is the display name, chosen by the player when
+// connecting or given by the host, and Strategy is the name of the strategy of a bot.
+type ActorInfo struct {
+	Name     string    `json:"name"`
+	Kind     ActorKind `json:"kind"`
+	Strategy string    `json:"strategy,omitempty"`
+}
+
+// String returns the name together with the kind of the actor, ex. "Ada (human)" or "Bot 2 (greedy bot)".
+func (a ActorInfo) String() string {
+	if a.Kind == ActorBot {
+		return fmt.Sprintf("%s (%s bot)", a.Name, a.Strategy)
+	}
+	return fmt.Sprintf("%s (%s)", a.Name, a.Kind)
+}
+
+// CardView is the wire form of a card. Vegetable is the name of the vegetable side, Code and
+// Colour are the code and colour the catalogue gives it, missing in messages of older hosts.
+type CardView struct {
+	Vegetable string `json:"vegetable"`
+	Code      string `json:"code,omitempty"`
+	Colour    string `json:"colour,omitempty"`
+	Criteria  string `json:"criteria"`
+}
+
+type SpotView struct {
+	HasCard   bool   `json:"hasCard"`
+	Vegetable string `json:"vegetable,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Colour    string `json:"colour,omitempty"`
+}
+
+type PileView struct {
+	Size int       `json:"size"`
+	Top  *CardView `json:"top,omitempty"`
+}
+
+type MarketView struct {
+	Spots []SpotView `json:"spots"`
+	Piles []PileView `json:"piles"`
+}
+
+type VegetableCount struct {
+	Vegetable string `json:"vegetable"`
+	Code      string `json:"code,omitempty"`
+	Colour    string `json:"colour,omitempty"`
+	Count     int    `json:"count"`
+}
+
+type HandView struct {
+	ActorId    int              `json:"actorId"`
+	Actor      ActorInfo        `json:"actor"`
+	Score      int              `json:"score"`
+	Vegetables []VegetableCount `json:"vegetables"`
+	PointCards []CardView       `json:"pointCards"`
+	// what every point card earned, in the order of PointCards, missing in messages of older hosts
+	Breakdown []CardScoreView `json:"breakdown"`
+}
+
+// CardScoreView is what a point card earned an actor, with a short explanation of why,
+// ex. "MOST PEPPER: has 4, the most of the others is 4 (tie, ties score nothing) → 0".
+type CardScoreView struct {
+	Points int    `json:"points"`
+	Reason string `json:"reason"`
+}
+
+// ActionView is the wire form of an ActorAction. Kind is the name of the ActorActionType,
+// Ids are the market spots, piles or point cards the action refers to and Cards are the
+// cards affected by the action, filled in by the host when reporting a result. Automatic
+// is set by the host when it made the move for a player that ran out of time.
+type ActionView struct {
+	Kind      string     `json:"kind"`
+	Ids       []int      `json:"ids,omitempty"`
+	Cards     []CardView `json:"cards,omitempty"`
+	Automatic bool       `json:"automatic,omitempty"`
+}
+
+type ScoreView struct {
+	ActorId int       `json:"actorId"`
+	Actor   ActorInfo `json:"actor"`
+	Score   int       `json:"score"`
+	Winner  bool      `json:"winner"`
+	// what every point card of the actor earned, missing in messages of older hosts
+	Breakdown []CardScoreView `json:"breakdown"`
+}
+
+// Message is the unit of communication between host and players, every value sent over
+// the network is a single JSON encoded Message.
+//
+// Fields:
+//   - Kind: What the message carries.
+//   - Phase: The phase an ActionRequest or ActionResult belongs to.
+//   - ActorId: The active actor for StateUpdate, Snapshot and ActionResult, the receiving actor for ActionRequest and Error,
+//     the actor a Notice is about.
+//   - Actor: Who plays the actor ActorId refers to, its name is shown instead of the id.
+//   - Market: The market, sent with StateUpdate, Snapshot and ActionRequest.
+//   - Hands: The hands of the actors, all of them for StateUpdate, Snapshot, ActionRequest and GameOver,
+//     only the acting actor for ActionResult.
+//   - Action: The action taken for ActionResult and MsgAction.
+//   - Scores: The final scores sorted from highest to lowest, sent with GameOver.
+//   - Error: The reason an action was rejected, sent with Error.
+//   - Text: A human readable notice, sent with Notice.
+//   - Seq: Numbers every ActionRequest, an Action has to carry the number of the request it answers.
+//   - DeadlineMs: How many milliseconds the player has to answer an ActionRequest, counted from when it is sent, 0 if there is no limit.
+//   - Vegetables: The vegetable types of the game, sent with every message carrying the market. Messages of older
+//     hosts have none, they played the base game.
+//   - TiePolicy: Who scores a tied MOST or FEWEST criteria, sent with every message carrying the market so remote
+//     bots score the game like the host. Messages of older hosts have none, they played the legacy "first".
+type Message struct {
+	Kind       MessageKind `json:"kind"`
+	Phase      Phase       `json:"phase,omitempty"`
+	ActorId    int         `json:"actorId"`
+	Actor      *ActorInfo  `json:"actor,omitempty"`
+	Market     *MarketView `json:"market,omitempty"`
+	Hands      []HandView  `json:"hands,omitempty"`
+	Action     *ActionView `json:"action,omitempty"`
+	Scores     []ScoreView `json:"scores,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Text       string      `json:"text,omitempty"`
+	Seq        int         `json:"seq,omitempty"`
+	DeadlineMs int64       `json:"deadlineMs,omitempty"`
+	Vegetables Catalogue   `json:"vegetables,omitempty"`
+	TiePolicy  TiePolicy   `json:"tiePolicy,omitempty"`
+}
+
+func encodeMessage(msg Message) []byte {
+	data, err := json.Marshal(msg)
+	// messages only contain plain data so encoding cannot fail
+	assert(err == nil)
+	return data
+}
+
+func decodeMessage(data []byte) (Message, error) {
+	msg := Message{}
+	err := json.Unmarshal(data, &msg)
+	if err != nil {
+		return msg, fmt.Errorf("Invalid message: %v", err)
+	}
+	return msg, nil
+}
+
+func getCardView(c Card, vegetables Catalogue) CardView {
+	vegetable := vegetables[c.vegType]
+	return CardView{Vegetable: vegetable.Name, Code: vegetable.Code, Colour: vegetable.Colour, Criteria: c.criteria.String()}
+}
+
+func getMarketView(m *Market, vegetables Catalogue) MarketView {
+	view := MarketView{}
+	for i := range m.cardSpots {
+		spot := SpotView{}
+		if hasCard(m, i) {
+			vegetable := vegetables[getCardFromMarket(m, i).vegType]
+			spot.HasCard = true
+			spot.Vegetable = vegetable.Name
+			spot.Code = vegetable.Code
+			spot.Colour = vegetable.Colour
+		}
+		view.Spots = append(view.Spots, spot)
+	}
+	for _, pile := range m.piles {
+		p := PileView{Size: len(pile)}
+		if len(pile) > 0 {
+			top := getCardView(pile[len(pile)-1], vegetables)
+			p.Top = &top
+		}
+		view.Piles = append(view.Piles, p)
+	}
+	return view
+}
+
+// getActorInfo returns who plays an actor. Players without a name are called after their
+// actor id, bots after their actor id and strategy. Players that connected as bots are
+// bots with the strategy they told.
+func getActorInfo(s *GameHostState, actorId int) ActorInfo {
+	if actorId >= s.playerNum {
+		strategy := getBotStrategy(s, actorId).String()
+		return ActorInfo{Name: fmt.Sprintf("Bot %d", actorId), Kind: ActorBot, Strategy: strategy}
+	}
+	info := ActorInfo{Name: fmt.Sprintf("Player %d", actorId), Kind: ActorHuman}
+	if actorId < len(s.playerStrategies) && s.playerStrategies[actorId] != "" {
+		info = ActorInfo{Name: fmt.Sprintf("Bot %d", actorId), Kind: ActorBot, Strategy: s.playerStrategies[actorId]}
+	}
+	if actorId < len(s.names) && s.names[actorId] != "" {
+		info.Name = s.names[actorId]
+	}
+	return info
+}
+
+// getMessageActor returns who plays an actor, to be sent as the Actor of a Message.
+func getMessageActor(s *GameHostState, actorId int) *ActorInfo {
+	actor := getActorInfo(s, actorId)
+	return &actor
+}
+
+func getActorInfos(s *GameHostState) []ActorInfo {
+	infos := []ActorInfo{}
+	for i := range s.playerNum + s.botNum {
+		infos = append(infos, getActorInfo(s, i))
+	}
+	return infos
+}
+
+func getHandView(s *GameHostState, actorId int) HandView {
+	assert(actorId < len(s.actorData))
+	view := HandView{
+		ActorId:    actorId,
+		Actor:      getActorInfo(s, actorId),
+		Score:      calculateScore(s, actorId),
+		Vegetables: []VegetableCount{},
+		PointCards: []CardView{},
+		Breakdown:  getCardScoreViews(s, actorId),
+	}
+	for i, num := range s.actorData[actorId].vegetableNum {
+		vegetable := s.vegetables[i]
+		view.Vegetables = append(view.Vegetables, VegetableCount{Vegetable: vegetable.Name, Code: vegetable.Code, Colour: vegetable.Colour, Count: num})
+	}
+	for _, card := range s.actorData[actorId].pointPile {
+		view.PointCards = append(view.PointCards, getCardView(card, s.vegetables))
+	}
+	return view
+}
+
+// getCardScoreViews returns what every point card of an actor earned and why, in the order of its point pile.
+func getCardScoreViews(s *GameHostState, actorId int) []CardScoreView {
+	views := []CardScoreView{}
+	for _, card := range s.actorData[actorId].pointPile {
+		b := card.criteria.breakdown(s, actorId)
+		views = append(views, CardScoreView{Points: b.points, Reason: b.reason})
+	}
+	return views
+}
+
+func getHandViews(s *GameHostState) []HandView {
+	views := []HandView{}
+	for i := range s.actorData {
+		views = append(views, getHandView(s, i))
+	}
+	return views
+}
+
+// getActionView describes a legal action together with the cards it affects. It has to be
+// called before the action is applied with doAction, since the cards are looked up in the
+// current state.
+func getActionView(s *GameHostState, action ActorAction) ActionView {
+	assert(isActionLegal(s, action) == nil)
+	view := actionToView(action)
+	for i := range action.amount {
+		var card Card
+		switch action.kind {
+		case pickVegFromMarket:
+			card = getCardFromMarket(&s.market, action.ids[i])
+		case pickPointFromMarket:
+			pile := s.market.piles[action.ids[i]]
+			card = pile[len(pile)-1]
+		case pickToSwap:
+			card = s.actorData[s.activeActor].pointPile[action.ids[i]]
+		}
+		view.Cards = append(view.Cards, getCardView(card, s.vegetables))
+	}
+	return view
+}
+
+func getScoreViews(s *GameHostState) []ScoreView {
+	scores := []ScoreView{}
+	for i := range s.playerNum + s.botNum {
+		scores = append(scores, ScoreView{ActorId: i, Actor: getActorInfo(s, i), Score: calculateScore(s, i), Breakdown: getCardScoreViews(s, i)})
+	}
+
+	slices.SortStableFunc(scores, func(a, b ScoreView) int {
+		return b.Score - a.Score
+	})
+
+	for i := range scores {
+		scores[i].Winner = scores[i].Score == scores[0].Score
+	}
+	return scores
+}
+
+func actionToView(action ActorAction) ActionView {
+	return ActionView{Kind: action.kind.String(), Ids: slices.Clone(action.ids[:action.amount])}
+}
+
+// actionFromView converts the wire form of an action back into an ActorAction. It only checks
+// that the action is well formed, whether it is legal depends on the game state and is up to the caller.
+//
+// Parameters:
+//   - view: The action as received from a player.
+//
+// Returns:
+//   - ActorAction: The decoded action.
+//   - error: An error if the kind is unknown or too many ids are given.
+func actionFromView(view ActionView) (ActorAction, error) {
+	action := ActorAction{}
+	for kind := Invalid; kind <= Quit; kind += 1 {
+		if kind.String() == view.Kind {
+			action.kind = kind
+		}
+	}
+	if action.kind == Invalid {
+		return action, fmt.Errorf("Unknown action kind: %s", view.Kind)
+	}
+	if len(view.Ids) > len(action.ids) {
+		return action, fmt.Errorf("Too many ids in action: %d", len(view.Ids))
+	}
+	action.amount = len(view.Ids)
+	copy(action.ids[:], view.Ids)
+	return action, nil
+}
+
+// labelVegetable returns how a vegetable is shown to humans, its name followed by its code,
+// ex. "PEPPER [P]". Vegetables of older hosts have no code and are shown by name.
+func labelVegetable(name string, code string) string {
+	if code == "" {
+		return name
+	}
+	return fmt.Sprintf("%s [%s]", name, code)
+}
+
+func renderMarket(m MarketView) string {
+	builder := strings.Builder{}
+	builder.WriteString("---- MARKET ----\n")
+	for i, spot := range m.Spots {
+		if spot.HasCard {
+			builder.WriteString(fmt.Sprintf("[%c] %v\n", i+'A', labelVegetable(spot.Vegetable, spot.Code)))
+		}
+	}
+	builder.WriteString("piles:\n")
+	for i, pile := range m.Piles {
+		if pile.Top != nil {
+			builder.WriteString(fmt.Sprintf("[%d] %s (%s)\n", i, pile.Top.Criteria, labelVegetable(pile.Top.Vegetable, pile.Top.Code)))
+		} else {
+			builder.WriteString("\n")
+		}
+	}
+	return builder.String()
+}
+
+func renderHand(h HandView) string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("---- %s ----\n", getActorName(h.Actor, h.ActorId, true)))
+
+	builder.WriteString(fmt.Sprintf("%d current score\n", h.Score))
+	builder.WriteString("--------\n")
+
+	for _, v := range h.Vegetables {
+		builder.WriteString(fmt.Sprintf("%d %v\n", v.Count, labelVegetable(v.Vegetable, v.Code)))
+	}
+
+	builder.WriteString("---- point cards ----\n")
+
+	for i, card := range h.PointCards {
+		builder.WriteString(fmt.Sprintf("%d: %s (%s)\n", i, card.Criteria, labelVegetable(card.Vegetable, card.Code)))
+		if i < len(h.Breakdown) {
+			builder.WriteString(fmt.Sprintf("   %s\n", h.Breakdown[i].Reason))
+		}
+	}
+	return builder.String()
+}
+
+func renderAction(name string, a ActionView) string {
+	builder := strings.Builder{}
+
+	builder.WriteString("---- Action ----\n")
+	switch a.Kind {
+	case pickVegFromMarket.String():
+		for _, card := range a.Cards {
+			builder.WriteString(fmt.Sprintf("%s drew %v from market\n", name, labelVegetable(card.Vegetable, card.Code)))
+		}
+	case pickPointFromMarket.String():
+		for _, card := range a.Cards {
+			builder.WriteString(fmt.Sprintf("%s drew %v (%v) from market\n", name, card.Criteria, labelVegetable(card.Vegetable, card.Code)))
+		}
+	case pickToSwap.String():
+		if len(a.Cards) == 0 {
+			builder.WriteString(fmt.Sprintf("%s did not swap any card\n", name))
+		}
+		for _, card := range a.Cards {
+			builder.WriteString(fmt.Sprintf("%s swapped %v to %v\n", name, card.Criteria, labelVegetable(card.Vegetable, card.Code)))
+		}
+	}
+	return builder.String()
+}
+
+func renderFinalScores(scores []ScoreView) string {
+	builder := strings.Builder{}
+
+	builder.WriteString("---- Final scores ----\n")
+	for _, s := range scores {
+		builder.WriteString(fmt.Sprintf("%s with score %d", getActorName(s.Actor, s.ActorId, true), s.Score))
+		if s.Winner {
+			builder.WriteString(" Winner\n")
+		} else {
+			builder.WriteString("\n")
+		}
+		for _, card := range s.Breakdown {
+			builder.WriteString(fmt.Sprintf("   %s\n", card.Reason))
+		}
+	}
+	return builder.String()
+}
+
+// getActorName returns the name of an actor as it is shown to humans, with its kind if withKind
+// is set. Actors without a name, as in messages of older hosts, are called after their actor id.
+func getActorName(actor ActorInfo, actorId int, withKind bool) string {
+	if actor.Name == "" {
+		return fmt.Sprintf("Player %d", actorId)
+	}
+	if withKind {
+		return actor.String()
+	}
+	return actor.Name
+}
+
+// getMessageActorName returns the name of the actor a message refers to.
+func getMessageActorName(msg Message) string {
+	if msg.Actor == nil {
+		return getActorName(ActorInfo{}, msg.ActorId, false)
+	}
+	return getActorName(*msg.Actor, msg.ActorId, false)
+}
+
+func findHand(hands []HandView, actorId int) (HandView, bool) {
+	for _, h := range hands {
+		if h.ActorId == actorId {
+			return h, true
+		}
+	}
+	return HandView{}, false
+}
+
+// renderMessage turns a message into the text shown to a human, this is what the host prints
+// to its console and what the player client prints to the terminal.
+//
+// Parameters:
+//   - msg: The message to render.
+//
+// Returns:
+//   - string: The human readable form of the message, the prompt included for ActionRequest.
+func renderMessage(msg Message) string {
+	builder := strings.Builder{}
+	switch msg.Kind {
+	case MsgStateUpdate:
+		builder.WriteString(fmt.Sprintf("---- %s's turn ----\n", getMessageActorName(msg)))
+	case MsgActionRequest:
+		hand, ok := findHand(msg.Hands, msg.ActorId)
+		if ok {
+			builder.WriteString(renderHand(hand))
+		}
+		if msg.DeadlineMs > 0 {
+			builder.WriteString(fmt.Sprintf("you have %v to answer\n", time.Duration(msg.DeadlineMs)*time.Millisecond))
+		}
+		if msg.Phase == PhaseMarket {
+			if msg.Market != nil {
+				builder.WriteString(renderMarket(*msg.Market))
+			}
+			builder.WriteString("pick 1 or 2 vegetables example: AB or\npick 1 point card example: 0\n")
+		} else {
+			builder.WriteString("pick 0-1 point card to flip to vegetable, type n to pick none example: 5\n")
+		}
+	case MsgActionResult:
+		if msg.Action != nil {
+			builder.WriteString(renderAction(getMessageActorName(msg), *msg.Action))
+			if msg.Action.Automatic {
+				builder.WriteString(fmt.Sprintf("The move was made automatically since %s ran out of time\n", getMessageActorName(msg)))
+			}
+		}
+		for _, h := range msg.Hands {
+			builder.WriteString(renderHand(h))
+		}
+	case MsgError:
+		builder.WriteString(fmt.Sprintf("%s\n", msg.Error))
+	case MsgGameOver:
+		builder.WriteString(renderFinalScores(msg.Scores))
+	case MsgNotice:
+		builder.WriteString(fmt.Sprintf("%s\n", msg.Text))
+	case MsgSnapshot:
+		builder.WriteString(fmt.Sprintf("---- %s's turn ----\n", getMessageActorName(msg)))
+		if msg.Market != nil {
+			builder.WriteString(renderMarket(*msg.Market))
+		}
+		for _, h := range msg.Hands {
+			builder.WriteString(renderHand(h))
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,256 @@
+package pointsalad
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Recording is the game log written with -record. It holds everything needed to rebuild
+// a game: the seed and configuration it was created with, a hash of the manifests the cards
+// were read from, the cards the game-config file left out, the deck sizes and every action applied to the state. The strategies of the bots and the
+// names of the players are kept for post-mortems, a replay does not need them since their
+// actions are recorded.
+type Recording struct {
+	Seed          int64    `json:"seed"`
+	ManifestHash  string   `json:"manifestHash"`
+	PlayerNum     int      `json:"playerNum"`
+	BotNum        int      `json:"botNum"`
+	BotStrategies []string `json:"botStrategies"`
+	Names         []string `json:"names,omitempty"`
+	// the strategies of the players that connected as bots, indexed by actor id
+	PlayerStrategies []string         `json:"playerStrategies,omitempty"`
+	TiePolicy        TiePolicy        `json:"tiePolicy,omitempty"`
+	Include          []int            `json:"include,omitempty"`
+	Exclude          []int            `json:"exclude,omitempty"`
+	DeckSizes        map[string]int   `json:"deckSizes,omitempty"`
+	Actions          []RecordedAction `json:"actions"`
+	Scores           []ScoreView      `json:"scores"`
+}
+
+// RecordedAction is an action in a Recording, together with the phase it was played in and
+// the actor that played it.
+type RecordedAction struct {
+	Phase   Phase      `json:"phase"`
+	ActorId int        `json:"actorId"`
+	Action  ActionView `json:"action"`
+}
+
+type gameRecorder struct {
+	path      string
+	recording Recording
+}
+
+// startRecording makes the host record every action applied to the state, the recording is
+// written to path once the game ends.
+//
+// Parameters:
+//   - state: The freshly created game state.
+//   - path: The file to write the recording to.
+//   - manifestHash: The hash of the manifests the cards were read from.
+//   - deck: The game-config file, the cards it leaves out and its deck sizes are recorded.
+//
+// Returns:
+//   - None
+func startRecording(state *GameHostState, path string, manifestHash string, deck DeckConfig) {
+	state.recorder = &gameRecorder{
+		path: path,
+		recording: Recording{
+			Seed:          state.seed,
+			ManifestHash:  manifestHash,
+			Include:       deck.Include,
+			Exclude:       deck.Exclude,
+			DeckSizes:     deck.DeckSizes,
+			PlayerNum:     state.playerNum,
+			BotNum:        state.botNum,
+			BotStrategies: getBotStrategyNames(state),
+			TiePolicy:     state.tiePolicy,
+			Actions:       []RecordedAction{},
+		},
+	}
+}
+
+// recordAction adds an action of the active actor to the recording, if the game is recorded.
+func recordAction(state *GameHostState, phase Phase, view ActionView) {
+	if state.recorder == nil {
+		return
+	}
+	state.recorder.recording.Actions = append(state.recorder.recording.Actions, RecordedAction{
+		Phase:   phase,
+		ActorId: state.activeActor,
+		Action:  view,
+	})
+}
+
+// saveRecording writes the recording together with the current scores, if the game is recorded.
+func saveRecording(state *GameHostState) error {
+	if state.recorder == nil {
+		return nil
+	}
+	state.recorder.recording.Names = state.names
+	state.recorder.recording.PlayerStrategies = state.playerStrategies
+	state.recorder.recording.Scores = getScoreViews(state)
+	data, err := json.MarshalIndent(state.recorder.recording, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(state.recorder.path, data, 0644)
+}
+
+func loadRecording(path string) (Recording, error) {
+	recording := Recording{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return recording, err
+	}
+	err = json.Unmarshal(data, &recording)
+	if err != nil {
+		return recording, fmt.Errorf("Failed to read recording %s: %v", path, err)
+	}
+	return recording, nil
+}
+
+// ReplayGame rebuilds a recorded game from the manifest and prints every step of it.
+//
+// Parameters:
+//   - path: The recording written with -record.
+//   - manifestPath: A comma separated list of the manifests the game was recorded with, the embedded manifest is used if it is empty.
+//   - deckPath: The game-config file the game was played with, only its manifests are used, the cards it left out and
+//     the deck sizes are taken from the recording. Empty if there is none.
+//   - w: Where the steps of the game are printed.
+//
+// Returns:
+//   - error: An error if the recording or manifests cannot be read, the manifests differ from the
+//     ones the game was recorded with, an action is illegal or the final scores do not match.
+func ReplayGame(path string, manifestPath string, deckPath string, w io.Writer) error {
+	recording, err := loadRecording(path)
+	if err != nil {
+		return err
+	}
+	deck, err := loadDeckConfig(deckPath)
+	if err != nil {
+		return err
+	}
+	jsonCards, manifestHash, err := loadManifests(getManifestPaths(manifestPath, deck))
+	if err != nil {
+		return err
+	}
+	if manifestHash != recording.ManifestHash {
+		return fmt.Errorf("The game was recorded with other manifests, expected hash %s got %s", recording.ManifestHash, manifestHash)
+	}
+	return replayRecording(&jsonCards, recording, w)
+}
+
+// replayRecording re-applies the actions of a recording to a fresh game state, turn by turn with
+// playTurn like RunHost, and renders every step like a player would see it.
+//
+// Parameters:
+//   - jsonCards: The cards of the manifests the game was recorded with, before the recorded cards are left out.
+//   - recording: The recording to replay.
+//   - w: Where the steps of the game are printed.
+//
+// Returns:
+//   - error: An error if an action is out of turn or illegal, the recording stops before the game is over because
+//     it was quit, or the final scores differ from the recorded scores.
+func replayRecording(jsonCards *JCards, recording Recording, w io.Writer) error {
+	selected, err := selectCards(jsonCards, recording.Include, recording.Exclude)
+	if err != nil {
+		return err
+	}
+	state, err := createGameHostState(&selected, recording.DeckSizes, recording.PlayerNum, recording.BotNum, recording.Seed)
+	if err != nil {
+		return err
+	}
+	// only shown, the actions of the bots are recorded
+	err = setBotStrategies(&state, recording.BotStrategies)
+	if err != nil {
+		return err
+	}
+	if len(recording.Names) > recording.PlayerNum {
+		return fmt.Errorf("Got %d names for %d players", len(recording.Names), recording.PlayerNum)
+	}
+	state.names = recording.Names
+	if len(recording.PlayerStrategies) > recording.PlayerNum {
+		return fmt.Errorf("Got %d player strategies for %d players", len(recording.PlayerStrategies), recording.PlayerNum)
+	}
+	state.playerStrategies = recording.PlayerStrategies
+	state.tiePolicy, err = ParseTiePolicy(string(recording.TiePolicy))
+	if err != nil {
+		return err
+	}
+
+	// the recorded actions are played with playTurn like RunHost plays the actions of the actors
+	i := 0
+	var replayErr error
+	getAction := func(phase Phase) (ActorAction, bool, bool) {
+		if i >= len(recording.Actions) {
+			return ActorAction{}, false, false
+		}
+		recorded := recording.Actions[i]
+		if recorded.Phase != phase || recorded.ActorId != state.activeActor {
+			replayErr = fmt.Errorf("Action %d: expected the %v phase of player %d, got the %v phase of player %d", i, phase, state.activeActor, recorded.Phase, recorded.ActorId)
+			return ActorAction{}, false, false
+		}
+		action, err := actionFromView(recorded.Action)
+		if err == nil {
+			err = isActionLegal(&state, action)
+		}
+		if err != nil {
+			replayErr = fmt.Errorf("Action %d: %v", i, err)
+			return ActorAction{}, false, false
+		}
+		i += 1
+		return action, recorded.Action.Automatic, true
+	}
+	apply := func(phase Phase, action ActorAction, automatic bool) {
+		view := getActionView(&state, action)
+		view.Automatic = automatic
+		doAction(&state, action)
+		fmt.Fprintf(w, "%s", renderMessage(Message{Kind: MsgActionResult, Phase: phase, ActorId: state.activeActor, Actor: getMessageActor(&state, state.activeActor), Action: &view}))
+	}
+
+	for {
+		flipCardsFromPiles(&state.market)
+		market := getMarketView(&state.market, state.vegetables)
+		fmt.Fprintf(w, "%s", renderMessage(Message{Kind: MsgStateUpdate, ActorId: state.activeActor, Actor: getMessageActor(&state, state.activeActor), Market: &market, Hands: getHandViews(&state)}))
+		fmt.Fprintf(w, "%s", renderMarket(market))
+
+		actorId := state.activeActor
+		ok, over := playTurn(&state, getAction, apply)
+		if replayErr != nil {
+			return replayErr
+		}
+		if !ok {
+			return fmt.Errorf("The recording stops after %d actions before the game is over, the game was quit", i)
+		}
+		fmt.Fprintf(w, "%s", renderHand(getHandView(&state, actorId)))
+		if over {
+			break
+		}
+	}
+	if i != len(recording.Actions) {
+		return fmt.Errorf("The game ended after %d of %d actions", i, len(recording.Actions))
+	}
+
+	scores := getScoreViews(&state)
+	fmt.Fprintf(w, "%s", renderFinalScores(scores))
+	if !sameScores(scores, recording.Scores) {
+		return fmt.Errorf("Final scores do not match the recording, expected %v got %v", recording.Scores, scores)
+	}
+	return nil
+}
+
+// sameScores returns true if both have the same scores and winners for the same actors.
+// Who played the actors is not compared, recordings made before actors had names have none.
+func sameScores(a []ScoreView, b []ScoreView) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].ActorId != b[i].ActorId || a[i].Score != b[i].Score || a[i].Winner != b[i].Winner {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,190 @@
+package pointsalad
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// SavedGame is the full state of a game in progress, written with -save and read back with
+// -resume. Cards are stored as their vegetable and the canonical string of their criteria,
+// which parses back into the same criteria with the vegetables of the save.
+type SavedGame struct {
+	Seed          int64     `json:"seed"`
+	Vegetables    Catalogue `json:"vegetables,omitempty"`
+	PlayerNum     int       `json:"playerNum"`
+	BotNum        int       `json:"botNum"`
+	BotStrategies []string  `json:"botStrategies,omitempty"`
+	Names         []string  `json:"names,omitempty"`
+	// the names the players connected with, a resumed game keeps every seat for its player
+	Seats       []string     `json:"seats,omitempty"`
+	TiePolicy   TiePolicy    `json:"tiePolicy,omitempty"`
+	ActiveActor int          `json:"activeActor"`
+	Piles       [][]CardView `json:"piles"`
+	Spots       []*CardView  `json:"spots"`
+	Actors      []SavedActor `json:"actors"`
+}
+
+type SavedActor struct {
+	Vegetables []VegetableCount `json:"vegetables"`
+	PointCards []CardView       `json:"pointCards"`
+}
+
+// getSavedGame converts the state into its serializable form.
+func getSavedGame(s *GameHostState) SavedGame {
+	saved := SavedGame{
+		Seed:          s.seed,
+		Vegetables:    s.vegetables,
+		PlayerNum:     s.playerNum,
+		BotNum:        s.botNum,
+		BotStrategies: getBotStrategyNames(s),
+		Names:         s.names,
+		Seats:         s.seatNames,
+		TiePolicy:     s.tiePolicy,
+		ActiveActor:   s.activeActor,
+		Piles:         [][]CardView{},
+		Spots:         []*CardView{},
+		Actors:        []SavedActor{},
+	}
+	for _, pile := range s.market.piles {
+		cards := []CardView{}
+		for _, card := range pile {
+			cards = append(cards, getCardView(card, s.vegetables))
+		}
+		saved.Piles = append(saved.Piles, cards)
+	}
+	for _, spot := range s.market.cardSpots {
+		if !spot.hasCard {
+			saved.Spots = append(saved.Spots, nil)
+			continue
+		}
+		card := getCardView(spot.card, s.vegetables)
+		saved.Spots = append(saved.Spots, &card)
+	}
+	for i := range s.actorData {
+		hand := getHandView(s, i)
+		saved.Actors = append(saved.Actors, SavedActor{Vegetables: hand.Vegetables, PointCards: hand.PointCards})
+	}
+	return saved
+}
+
+// createGameHostStateFromSave rebuilds the game state from a saved game. The state has no random
+// number generator, the caller has to set one before the game continues.
+//
+// Parameters:
+//   - saved: The saved game.
+//
+// Returns:
+//   - GameHostState: The state the game was in when it was saved.
+//   - error: An error if the saved game is inconsistent or contains unknown vegetables or criteria.
+//     Saves made before the vegetables were saved are read with the vegetables of the base game.
+func createGameHostStateFromSave(saved SavedGame) (GameHostState, error) {
+	s := GameHostState{}
+	vegetables, err := getCatalogueOrBase(saved.Vegetables)
+	if err != nil {
+		return s, err
+	}
+	s.vegetables = vegetables
+	actorNum := saved.PlayerNum + saved.BotNum
+	if !(actorNum >= 2 && actorNum <= 6) || len(saved.Actors) != actorNum {
+		return s, fmt.Errorf("Expected 2-6 actors matching the number of players and bots, got %d players, %d bots and %d actors", saved.PlayerNum, saved.BotNum, len(saved.Actors))
+	}
+	if saved.ActiveActor < 0 || saved.ActiveActor >= actorNum {
+		return s, fmt.Errorf("Active actor %d does not exist", saved.ActiveActor)
+	}
+	if len(saved.Piles) == 0 || len(saved.Spots)%len(saved.Piles) != 0 {
+		return s, fmt.Errorf("Expected the number of market spots to be a multiple of the %d piles, got %d", len(saved.Piles), len(saved.Spots))
+	}
+
+	for _, pileViews := range saved.Piles {
+		pile := []Card{}
+		for _, view := range pileViews {
+			card, err := createCardFromView(view, vegetables)
+			if err != nil {
+				return s, err
+			}
+			pile = append(pile, card)
+		}
+		s.market.piles = append(s.market.piles, pile)
+	}
+	for _, view := range saved.Spots {
+		spot := CardSpot{}
+		if view != nil {
+			card, err := createCardFromView(*view, vegetables)
+			if err != nil {
+				return s, err
+			}
+			spot = CardSpot{hasCard: true, card: card}
+		}
+		s.market.cardSpots = append(s.market.cardSpots, spot)
+	}
+	for _, actor := range saved.Actors {
+		actorData := ActorData{vegetableNum: make([]int, len(vegetables))}
+		for _, v := range actor.Vegetables {
+			vegType, ok := getVegetableType(vegetables, v.Vegetable)
+			if !ok {
+				return s, fmt.Errorf("Unknown vegetable: %s", v.Vegetable)
+			}
+			actorData.vegetableNum[vegType] = v.Count
+		}
+		for _, view := range actor.PointCards {
+			card, err := createCardFromView(view, vegetables)
+			if err != nil {
+				return s, err
+			}
+			actorData.pointPile = append(actorData.pointPile, card)
+		}
+		s.actorData = append(s.actorData, actorData)
+	}
+
+	s.seed = saved.Seed
+	s.playerNum = saved.PlayerNum
+	s.botNum = saved.BotNum
+	s.activeActor = saved.ActiveActor
+	if len(saved.Names) > saved.PlayerNum {
+		return s, fmt.Errorf("Got %d names for %d players", len(saved.Names), saved.PlayerNum)
+	}
+	s.names = saved.Names
+	if len(saved.Seats) > saved.PlayerNum {
+		return s, fmt.Errorf("Got %d seats for %d players", len(saved.Seats), saved.PlayerNum)
+	}
+	s.seatNames = saved.Seats
+	tiePolicy, err := ParseTiePolicy(string(saved.TiePolicy))
+	if err != nil {
+		return s, err
+	}
+	s.tiePolicy = tiePolicy
+	err = setBotStrategies(&s, saved.BotStrategies)
+	if err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// saveGame writes the state to path. The file is replaced in one step so an interrupted
+// write never leaves a broken save behind.
+func saveGame(s *GameHostState, path string) error {
+	data, err := json.MarshalIndent(getSavedGame(s), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func loadGame(path string) (SavedGame, error) {
+	saved := SavedGame{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return saved, err
+	}
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		return saved, fmt.Errorf("Failed to read saved game %s: %v", path, err)
+	}
+	return saved, nil
+}
@@ -0,0 +1,291 @@
+package pointsalad
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// SimulationConfig holds the settings of a batch of bot games run with SimulateGames.
+//
+// Fields:
+//   - Games: The number of games to play.
+//   - Strategies: The strategies of the bots, one per bot. Between 2 and 6 bots play every game.
+//   - Workers: The number of games played in parallel, at least 1.
+//   - Seed: The seed of the first game, game i is played with Seed + i.
+//   - ManifestPath: A comma separated list of card manifests, the embedded manifest is used if it is empty.
+//   - DeckPath: A game-config file adding manifests, leaving out cards and setting deck sizes, empty if there is none.
+//   - TiePolicy: Who scores a tied MOST or FEWEST criteria, see ParseTiePolicy.
+type SimulationConfig struct {
+	Games        int
+	Strategies   []string
+	Workers      int
+	Seed         int64
+	ManifestPath string
+	DeckPath     string
+	TiePolicy    string
+}
+
+// SimulationStats summarizes one group of results of a simulation, a strategy or a seat.
+//
+// Fields:
+//   - Name: The strategy, or the position of the seat in the turn order starting at 1.
+//   - Games: The number of games the group played.
+//   - WinRate: The share of the games won, a win shared by k actors counts as 1/k.
+//   - MeanScore: The mean final score.
+//   - StdScore: The standard deviation of the final score.
+type SimulationStats struct {
+	Name      string
+	Games     int
+	WinRate   float64
+	MeanScore float64
+	StdScore  float64
+}
+
+// SimulationReport is the result of SimulateGames.
+//
+// Fields:
+//   - Strategies: The stats of every bot, in the order of the strategies in the config.
+//   - Seats: The stats of every position in the turn order, the first seat starts the game.
+//   - Games: The number of games played.
+//   - MeanTurns: The mean number of turns of a game.
+//   - StdTurns: The standard deviation of the number of turns of a game.
+type SimulationReport struct {
+	Strategies []SimulationStats
+	Seats      []SimulationStats
+	Games      int
+	MeanTurns  float64
+	StdTurns   float64
+}
+
+type simulatedGame struct {
+	turns int
+	// by bot, in the order of the strategies in the config
+	scores []int
+	wins   []float64
+	// the position of every bot in the turn order
+	seats []int
+}
+
+// SimulateGames plays games between bots in-process, without any network, and summarizes the results.
+// The bots change seats every game so every strategy plays from every seat.
+//
+// Parameters:
+//   - config: The number of games, the strategies of the bots and the other settings of the simulation.
+//
+// Returns:
+//   - SimulationReport: The win rates and scores of the strategies and seats and the length of the games.
+//   - error: An error if the config is invalid or the manifest cannot be read.
+func SimulateGames(config SimulationConfig) (SimulationReport, error) {
+	report := SimulationReport{}
+	botNum := len(config.Strategies)
+	if !(botNum >= 2 && botNum <= 6) {
+		return report, fmt.Errorf("Number of bots has to be between 2-6, got %d", botNum)
+	}
+	if config.Games < 1 || config.Workers < 1 {
+		return report, fmt.Errorf("Expected at least 1 game and 1 worker, got %d games and %d workers", config.Games, config.Workers)
+	}
+	for _, name := range config.Strategies {
+		_, err := GetBotStrategy(name)
+		if err != nil {
+			return report, err
+		}
+	}
+	tiePolicy, err := ParseTiePolicy(config.TiePolicy)
+	if err != nil {
+		return report, err
+	}
+	jsonCards, deck, _, err := loadCards(config.ManifestPath, config.DeckPath)
+	if err != nil {
+		return report, err
+	}
+	_, err = getDeckSizes(jsonCards.Vegetables, deck.DeckSizes, botNum, len(jsonCards.Cards))
+	if err != nil {
+		return report, err
+	}
+
+	games := make([]simulatedGame, config.Games)
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for range config.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				games[i] = playSimulatedGame(&jsonCards, deck.DeckSizes, config.Strategies, tiePolicy, i, config.Seed+int64(i))
+			}
+		}()
+	}
+	for i := range config.Games {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return getSimulationReport(config.Strategies, games), nil
+}
+
+// playSimulatedGame plays one game between bots the way RunHost does, without broadcasting anything.
+//
+// Parameters:
+//   - jsonCards: The cards of the manifests.
+//   - deckSizes: The number of cards of a vegetable by vegetable name, checked to fit the cards.
+//   - strategies: The strategies of the bots.
+//   - tiePolicy: Who scores a tied MOST or FEWEST criteria.
+//   - rotation: How many seats the bots are moved, bot i plays seat (i + rotation) % len(strategies).
+//   - seed: The seed of the game.
+//
+// Returns:
+//   - simulatedGame: The number of turns and the scores and wins of the bots.
+func playSimulatedGame(jsonCards *JCards, deckSizes map[string]int, strategies []string, tiePolicy TiePolicy, rotation int, seed int64) simulatedGame {
+	botNum := len(strategies)
+	s, err := createGameHostState(jsonCards, deckSizes, 0, botNum, seed)
+	assert(err == nil)
+	s.tiePolicy = tiePolicy
+
+	seatNames := make([]string, botNum)
+	for i, name := range strategies {
+		seatNames[(i+rotation)%botNum] = name
+	}
+	err = setBotStrategies(&s, seatNames)
+	assert(err == nil)
+
+	startingActor := s.activeActor
+	turns := 0
+	getAction := func(phase Phase) (ActorAction, bool, bool) {
+		return getActionFromBot(&s, phase), false, true
+	}
+	apply := func(phase Phase, action ActorAction, automatic bool) {
+		doAction(&s, action)
+	}
+	for {
+		flipCardsFromPiles(&s.market)
+		_, over := playTurn(&s, getAction, apply)
+		turns += 1
+		if over {
+			break
+		}
+	}
+
+	game := simulatedGame{turns: turns}
+	best := math.MinInt
+	for i := range botNum {
+		best = max(best, calculateScore(&s, i))
+	}
+	winners := 0
+	for i := range botNum {
+		if calculateScore(&s, i) == best {
+			winners += 1
+		}
+	}
+	for i := range strategies {
+		actorId := (i + rotation) % botNum
+		score := calculateScore(&s, actorId)
+		game.scores = append(game.scores, score)
+		game.seats = append(game.seats, (actorId-startingActor+botNum)%botNum)
+		if score == best {
+			game.wins = append(game.wins, 1/float64(winners))
+		} else {
+			game.wins = append(game.wins, 0)
+		}
+	}
+	return game
+}
+
+func getSimulationReport(strategies []string, games []simulatedGame) SimulationReport {
+	report := SimulationReport{Games: len(games)}
+
+	turns := []float64{}
+	for _, game := range games {
+		turns = append(turns, float64(game.turns))
+	}
+	report.MeanTurns, report.StdTurns = getMeanAndStd(turns)
+
+	for i, name := range strategies {
+		scores := []float64{}
+		wins := 0.0
+		for _, game := range games {
+			scores = append(scores, float64(game.scores[i]))
+			wins += game.wins[i]
+		}
+		stats := SimulationStats{Name: name, Games: len(games), WinRate: wins / float64(len(games))}
+		stats.MeanScore, stats.StdScore = getMeanAndStd(scores)
+		report.Strategies = append(report.Strategies, stats)
+	}
+
+	for seat := range strategies {
+		scores := []float64{}
+		wins := 0.0
+		for _, game := range games {
+			for i := range strategies {
+				if game.seats[i] == seat {
+					scores = append(scores, float64(game.scores[i]))
+					wins += game.wins[i]
+				}
+			}
+		}
+		stats := SimulationStats{Name: strconv.Itoa(seat + 1), Games: len(scores), WinRate: wins / float64(len(scores))}
+		stats.MeanScore, stats.StdScore = getMeanAndStd(scores)
+		report.Seats = append(report.Seats, stats)
+	}
+	return report
+}
+
+// getMeanAndStd returns the mean and the population standard deviation of the values.
+func getMeanAndStd(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
+
+// Table renders the report as aligned text tables.
+func (r SimulationReport) Table() string {
+	builder := strings.Builder{}
+	w := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%d games, %.1f ± %.1f turns per game\n\n", r.Games, r.MeanTurns, r.StdTurns)
+	fmt.Fprintf(w, "strategy\twin rate\tmean score\tstd score\n")
+	for _, stats := range r.Strategies {
+		fmt.Fprintf(w, "%s\t%.1f%%\t%.1f\t%.1f\n", stats.Name, 100*stats.WinRate, stats.MeanScore, stats.StdScore)
+	}
+	fmt.Fprintf(w, "\nseat\twin rate\tmean score\tstd score\n")
+	for _, stats := range r.Seats {
+		fmt.Fprintf(w, "%s\t%.1f%%\t%.1f\t%.1f\n", stats.Name, 100*stats.WinRate, stats.MeanScore, stats.StdScore)
+	}
+	w.Flush()
+	return builder.String()
+}
+
+// CSV renders the report as CSV with the columns group, name, games, win_rate, mean and std. The
+// group is strategy or seat, where mean and std are about the score, or length where they are
+// about the number of turns.
+func (r SimulationReport) CSV() string {
+	builder := strings.Builder{}
+	w := csv.NewWriter(&builder)
+	format := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', 4, 64)
+	}
+	w.Write([]string{"group", "name", "games", "win_rate", "mean", "std"})
+	for _, stats := range r.Strategies {
+		w.Write([]string{"strategy", stats.Name, strconv.Itoa(stats.Games), format(stats.WinRate), format(stats.MeanScore), format(stats.StdScore)})
+	}
+	for _, stats := range r.Seats {
+		w.Write([]string{"seat", stats.Name, strconv.Itoa(stats.Games), format(stats.WinRate), format(stats.MeanScore), format(stats.StdScore)})
+	}
+	w.Write([]string{"length", "turns", strconv.Itoa(r.Games), "", format(r.MeanTurns), format(r.StdTurns)})
+	w.Flush()
+	return builder.String()
+}
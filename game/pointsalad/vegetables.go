@@ -0,0 +1,116 @@
+package pointsalad
+
+import (
+	"fmt"
+	"slices"
+)
+
+// VegType is a vegetable type, the index of the vegetable in the catalogue of the game.
+type VegType int
+
+// The vegetable types of the base game, in the order of baseCatalogue.
+const (
+	PEPPER  VegType = iota
+	LETTUCE VegType = iota
+
+	CARROT  VegType = iota
+	CABBAGE VegType = iota
+
+	ONION  VegType = iota
+	TOMATO VegType = iota
+)
+
+// Vegetable describes a vegetable type of a catalogue.
+//
+// Fields:
+//   - Name: How the vegetable is written in criteria, card views and deck sizes, ex. "PEPPER". Only letters.
+//   - Code: A short code for clients with little room, ex. "P".
+//   - Colour: The colour clients draw the vegetable in, ex. "red" or "#d62d20". May be empty.
+type Vegetable struct {
+	Name   string `json:"name"`
+	Code   string `json:"code"`
+	Colour string `json:"colour,omitempty"`
+}
+
+// Catalogue is the list of vegetable types a game is played with, indexed by VegType. It is read
+// from the manifests, every card has a side of every vegetable of the catalogue.
+type Catalogue []Vegetable
+
+// baseCatalogue is the catalogue of the base game, used by manifests that do not list their vegetables.
+var baseCatalogue = Catalogue{
+	PEPPER:  {Name: "PEPPER", Code: "P", Colour: "red"},
+	LETTUCE: {Name: "LETTUCE", Code: "L", Colour: "green"},
+	CARROT:  {Name: "CARROT", Code: "C", Colour: "orange"},
+	CABBAGE: {Name: "CABBAGE", Code: "B", Colour: "purple"},
+	ONION:   {Name: "ONION", Code: "O", Colour: "yellow"},
+	TOMATO:  {Name: "TOMATO", Code: "T", Colour: "crimson"},
+}
+
+// reservedWords are the words of the criteria grammar, a vegetable with one of these names could not be parsed.
+var reservedWords = []string{"MOST", "FEWEST", "TOTAL", "VEGETABLE", "TYPE", "MISSING", "COMPLETE", "SET", "EVEN", "ODD"}
+
+// maxVegetableTypes is the most vegetable types a catalogue can have.
+const maxVegetableTypes = 26
+
+// checkCatalogue checks that every vegetable of a catalogue can be told apart from the others and
+// written in a criteria.
+//
+// Parameters:
+//   - vegetables: The catalogue.
+//
+// Returns:
+//   - error: An error if the catalogue is empty or too large, a name is not a single word of letters or
+//     a word of the criteria grammar, or a name or code is missing or used twice.
+func checkCatalogue(vegetables Catalogue) error {
+	if len(vegetables) == 0 || len(vegetables) > maxVegetableTypes {
+		return fmt.Errorf("Expected between 1 and %d vegetables, got %d", maxVegetableTypes, len(vegetables))
+	}
+	names := map[string]bool{}
+	codes := map[string]bool{}
+	for _, vegetable := range vegetables {
+		if vegetable.Name == "" {
+			return fmt.Errorf("Expected every vegetable to have a name")
+		}
+		for i := range len(vegetable.Name) {
+			if !isAlpha(vegetable.Name[i]) {
+				return fmt.Errorf("Expected the name of vegetable %q to only have letters", vegetable.Name)
+			}
+		}
+		if slices.Contains(reservedWords, vegetable.Name) {
+			return fmt.Errorf("The vegetable %q is named like a word of the criteria", vegetable.Name)
+		}
+		if vegetable.Code == "" {
+			return fmt.Errorf("Expected the vegetable %q to have a code", vegetable.Name)
+		}
+		if names[vegetable.Name] || codes[vegetable.Code] {
+			return fmt.Errorf("The name or code of vegetable %q is used twice", vegetable.Name)
+		}
+		names[vegetable.Name] = true
+		codes[vegetable.Code] = true
+	}
+	return nil
+}
+
+// getVegetableType returns the vegetable type with the given name, false if there is none.
+func getVegetableType(vegetables Catalogue, name string) (VegType, bool) {
+	for i, vegetable := range vegetables {
+		if vegetable.Name == name {
+			return VegType(i), true
+		}
+	}
+	return -1, false
+}
+
+func isVegetable(vegetables Catalogue, name string) bool {
+	_, ok := getVegetableType(vegetables, name)
+	return ok
+}
+
+// getCatalogueOrBase returns the catalogue of a save or message, or the base catalogue for saves and
+// messages of older hosts, which did not have one.
+func getCatalogueOrBase(vegetables Catalogue) (Catalogue, error) {
+	if len(vegetables) == 0 {
+		return baseCatalogue, nil
+	}
+	return vegetables, checkCatalogue(vegetables)
+}
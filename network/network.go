@@ -2,42 +2,125 @@ package network
 
 import (
 	"HomeExam/network/tcp"
+	"HomeExam/network/websocket"
 )
 
 // Client defines the interface for a network client in the game.
 // The client is responsible for connecting to a server, sending and receiving data over the network,
-// and properly closing the connection when done.
+// and properly closing the connection when done. Every value written to the write channel arrives
+// as exactly one value on the server's read channel and vice versa.
 //
 // Methods:
 //   - Connect(hostname string, port string, clientMaxReceiveSize int): Establishes a connection to the server
-//     on the given hostname and port, with a maximum data receive size for incoming messages.
+//     on the given hostname and port, with a maximum size for a single incoming message.
 //   - Close(): Closes the connection to the server.
 //   - GetReadChannel(): Returns the channel for receiving data from the server (as a byte slice).
 //   - GetWriteChannel(): Returns the channel for sending data to the server (as a byte slice).
+//   - SetSessionToken(token string): Sets the session token to resume a seat with, must be called before Connect.
+//   - GetSessionToken(): Returns the session token issued by the server for the client's seat.
+//   - CreateTable(config TableConfig): Makes Connect create a table in a lobby and take its first seat, must be called before Connect.
+//   - JoinTable(id int): Makes Connect take a seat at an open table of a lobby, must be called before Connect.
+//   - GetTableId(): Returns the id of the lobby table the client's seat belongs to, 0 if the server is not a lobby.
+//   - ListTables(hostname string, port string): Returns the tables of a lobby without taking a seat.
+//   - Spectate(): Makes Connect join as a spectator without a seat, who is sent everything broadcast to the players.
+//     Combined with JoinTable it watches a lobby table. Must be called before Connect.
+//   - SetTLS(caFile string): Makes Connect use TLS and only trust servers whose certificate is signed by a
+//     certificate in caFile. Must be called before Connect.
+//   - SetPassword(password string): Sets the table password sent during the handshake, must be called before Connect.
+//   - SetName(name string): Sets the name the player is shown under, must be called before Connect.
+//   - SetBot(strategy string): Tells the server the seat is played by a bot with the named strategy, must be
+//     called before Connect.
+//   - RequestSeat(seat int): Makes Connect ask for the seat with the actor id instead of the next free one,
+//     must be called before Connect.
+//   - GetActorId(): Returns the actor id the server assigned to the client's seat, -1 for spectators.
 type Client interface {
 	Connect(hostname string, port string, clientMaxReceiveSize int) error
 	Close()
 	GetReadChannel() chan []byte
 	GetWriteChannel() chan []byte
+	SetSessionToken(token string)
+	GetSessionToken() string
+	CreateTable(config TableConfig)
+	JoinTable(id int)
+	GetTableId() int
+	Spectate()
+	ListTables(hostname string, port string) ([]TableInfo, error)
+	SetTLS(caFile string) error
+	SetPassword(password string)
+	SetName(name string)
+	SetBot(strategy string)
+	RequestSeat(seat int)
+	GetActorId() int
 }
 
 // Server defines the interface for a network server in the game.
 // The server is responsible for accepting client connections, handling communication with clients,
-// and managing multiple connections at once.
+// and managing multiple connections at once. Clients whose connection drops can reconnect to
+// their seat with the session token they were issued when joining.
 //
 // Methods:
 //   - Listen(port string, playerNum int, serverMaxReceiveSize int): Initializes the server to listen on the given
-//     port with the specified number of players and maximum receive size for incoming data.
+//     port with the specified number of players and maximum size for a single incoming message.
 //   - Close(): Closes the server, stopping all communication and accepting no further connections.
 //   - GetReadChannels(): Returns a map of channels used for receiving data from each connected client,
 //     keyed by the client ID.
 //   - GetWriteChannels(): Returns a map of channels used for sending data to each connected client,
 //     keyed by the client ID.
+//   - GetStatusChannels(): Returns a map of channels reporting whether each client is connected (true)
+//     or has dropped (false), keyed by the client ID.
+//   - GetSpectatorChannel(): Returns the channel on which every spectator arrives as the channel to send it data on.
+//     Spectators do not take a seat, so they do not count toward the number of players.
+//   - GetPlayerNames(): Returns the names the players sent when connecting, keyed by the client ID.
+//   - GetBotStrategies(): Returns the strategies of the players that connected as bots, keyed by the client ID.
+//   - ReserveSeats(names map[int]string): Keeps every seat for the player connecting with the given name, keyed by
+//     the client ID, and rejects players whose name no free seat is kept for. Must be called before Listen.
+//   - SetTLS(certFile string, keyFile string): Makes the server accept only TLS connections presenting the
+//     certificate in certFile, must be called before Listen.
+//   - SetPassword(password string): Makes the server reject clients without the password, must be called before Listen.
 type Server interface {
 	Listen(port string, playerNum int, serverMaxReceiveSize int) error
 	Close()
 	GetReadChannels() map[int]chan []byte
 	GetWriteChannels() map[int]chan []byte
+	GetStatusChannels() map[int]chan bool
+	GetSpectatorChannel() chan chan []byte
+	GetPlayerNames() map[int]string
+	GetBotStrategies() map[int]string
+	ReserveSeats(names map[int]string)
+	SetTLS(certFile string, keyFile string) error
+	SetPassword(password string)
+}
+
+// TableConfig holds the settings a lobby table is created with, the number of players and bots.
+type TableConfig = tcp.TableConfig
+
+// TableInfo describes a lobby table as it is listed to clients.
+type TableInfo = tcp.TableInfo
+
+// Table holds the seats of the players at a lobby table. Its read, write and status channels
+// work like the ones of a Server, and it is closed once the game at the table is over.
+type Table = tcp.Table
+
+// LobbyServer defines the interface for a server hosting many tables at once.
+// Clients list the tables, create one or join one when they connect, and every table whose
+// seats have all been taken is handed out to run a game at. The lobby stays up across games.
+//
+// Methods:
+//   - Listen(port string, serverMaxReceiveSize int, validate func(config TableConfig) error): Starts accepting
+//     connections on the given port, tables are only created if validate accepts their config.
+//   - Close(): Closes the lobby and every table.
+//   - GetTableChannel(): Returns the channel of the tables whose seats have all been taken.
+//   - GetTables(): Returns a description of every table.
+//   - SetTLS(certFile string, keyFile string): Makes the lobby accept only TLS connections presenting the
+//     certificate in certFile, must be called before Listen.
+//   - SetPassword(password string): Makes the lobby reject clients without the password, must be called before Listen.
+type LobbyServer interface {
+	Listen(port string, serverMaxReceiveSize int, validate func(config TableConfig) error) error
+	Close()
+	GetTableChannel() chan *Table
+	GetTables() []TableInfo
+	SetTLS(certFile string, keyFile string) error
+	SetPassword(password string)
 }
 
 // CreateTCPServer creates and returns a new instance of a TCP server.
@@ -63,3 +146,38 @@ func CreateTCPServer() Server {
 func CreateTCPClient() Client {
 	return &tcp.Client{}
 }
+
+// CreateTCPLobby creates and returns a new instance of a TCP lobby server.
+//
+// Returns:
+// - A `LobbyServer` interface which represents the TCP lobby instance.
+func CreateTCPLobby() LobbyServer {
+	return &tcp.Lobby{}
+}
+
+// CreateWebSocketServer creates and returns a new instance of a websocket server.
+//
+// The server carries the same messages as the TCP server, one per websocket message,
+// so browser clients can join the game at ws://host:port/.
+//
+// Returns:
+// - A `Server` interface which represents the websocket server instance.
+func CreateWebSocketServer() Server {
+	return &websocket.Server{}
+}
+
+// CreateWebSocketClient creates and returns a new instance of a websocket client.
+//
+// Returns:
+// - A `Client` interface which represents the websocket client instance.
+func CreateWebSocketClient() Client {
+	return &websocket.Client{}
+}
+
+// CreateWebSocketLobby creates and returns a new instance of a websocket lobby server.
+//
+// Returns:
+// - A `LobbyServer` interface which represents the websocket lobby instance.
+func CreateWebSocketLobby() LobbyServer {
+	return &websocket.Lobby{}
+}
@@ -0,0 +1,111 @@
+package tcp
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+)
+
+const (
+	// featureCompression compresses every message after the handshake with DEFLATE
+	featureCompression = "compression"
+)
+
+// supportedFeatures are the features this package offers in the hello and accepts from clients.
+var supportedFeatures = []string{featureCompression}
+
+// errLegacyClient is returned for clients that opened the handshake the way protocol version 1 did.
+var errLegacyClient = errors.New("protocol version 1 is not supported, please update the client")
+
+// negotiateFeatures returns the features of the client that the server supports too,
+// unknown features are ignored.
+//
+// Parameters:
+// - requested: The features sent in the hello.
+//
+// Returns:
+// - []string: The features used from then on.
+func negotiateFeatures(requested []string) []string {
+	features := []string{}
+	for _, feature := range supportedFeatures {
+		if slices.Contains(requested, feature) {
+			features = append(features, feature)
+		}
+	}
+	return features
+}
+
+// useFeatures wraps a connection that finished the handshake so it speaks the negotiated features.
+//
+// Parameters:
+// - conn: The connection.
+// - features: The features both sides agreed on in the welcome.
+//
+// Returns:
+// - net.Conn: The connection to read and write messages on.
+func useFeatures(conn net.Conn, features []string) net.Conn {
+	if slices.Contains(features, featureCompression) {
+		return &compressedConn{Conn: conn}
+	}
+	return conn
+}
+
+// compressedConn compresses every message with DEFLATE before it is framed, or sent as a
+// websocket message if the connection below is one.
+type compressedConn struct {
+	net.Conn
+}
+
+// ReadMessage reads a compressed message and returns it decompressed.
+//
+// Parameters:
+// - maxSize: The largest decompressed message accepted, larger messages are rejected.
+//
+// Returns:
+// - []byte: The message data.
+// - error: An error if reading or decompressing fails or the message is larger than maxSize.
+func (c *compressedConn) ReadMessage(maxSize int) ([]byte, error) {
+	// DEFLATE adds at most 5 bytes per 16KB block to data it cannot compress
+	data, err := readMessage(c.Conn, maxSize+maxSize/1024+64)
+	if err != nil {
+		return nil, err
+	}
+	reader := flate.NewReader(bytes.NewReader(data))
+	defer reader.Close()
+	message, err := io.ReadAll(io.LimitReader(reader, int64(maxSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress message: %w", err)
+	}
+	if len(message) > maxSize {
+		return nil, fmt.Errorf("message exceeds max size of %d bytes", maxSize)
+	}
+	return message, nil
+}
+
+// WriteMessage compresses data and sends it as one message.
+//
+// Parameters:
+// - data: The message to send, may be empty.
+//
+// Returns:
+// - error: An error if the message could not be written.
+func (c *compressedConn) WriteMessage(data []byte) error {
+	buf := bytes.Buffer{}
+	writer, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	return writeMessage(c.Conn, buf.Bytes())
+}
@@ -0,0 +1,440 @@
+package tcp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"sync"
+)
+
+const (
+	lobbyList   = "list"
+	lobbyCreate = "create"
+	lobbyJoin   = "join"
+
+	// most tables waiting for players a lobby holds at once, running tables do not count
+	maxOpenTables = 32
+)
+
+// lobbyRequest is sent in the hello by clients of a Lobby. The action is either list, which
+// answers with the open tables and closes the connection, create, which creates a table with
+// Config and takes its first seat, or join, which takes the next free seat of Table. A spectator
+// joining a table watches it, whether its game has started or not.
+type lobbyRequest struct {
+	Action string       `json:"action"`
+	Table  int          `json:"table,omitempty"`
+	Config *TableConfig `json:"config,omitempty"`
+}
+
+// TableConfig holds the settings a table of a Lobby is created with.
+//
+// Fields:
+//   - Players: The number of seats of the table, at least 1 since the creator takes the first one.
+//   - Bots: The number of bots playing at the table.
+//   - BotStrategies: The names of the strategies of the bots in seat order.
+type TableConfig struct {
+	Players       int      `json:"players"`
+	Bots          int      `json:"bots"`
+	BotStrategies []string `json:"botStrategies,omitempty"`
+}
+
+// TableInfo describes a table of a Lobby as it is listed to clients.
+//
+// Fields:
+//   - Id: The id to join the table with.
+//   - Config: The settings the table was created with.
+//   - Seated: The number of seats taken.
+//   - Started: true once every seat has been taken and the game is running.
+type TableInfo struct {
+	Id      int         `json:"id"`
+	Config  TableConfig `json:"config"`
+	Seated  int         `json:"seated"`
+	Started bool        `json:"started"`
+}
+
+// Table is a table of a Lobby. It holds the seats of the players at the table like a Server
+// does, and is handed out on the lobby's table channel once every seat has been taken.
+type Table struct {
+	Id      int
+	Config  TableConfig
+	server  *Server
+	lobby   *Lobby
+	started bool
+}
+
+// GetReadChannels returns the read channels of the seats of the table, see Server.GetReadChannels.
+func (t *Table) GetReadChannels() map[int]chan []byte {
+	return t.server.GetReadChannels()
+}
+
+// GetWriteChannels returns the write channels of the seats of the table, see Server.GetWriteChannels.
+func (t *Table) GetWriteChannels() map[int]chan []byte {
+	return t.server.GetWriteChannels()
+}
+
+// GetStatusChannels returns the status channels of the seats of the table, see Server.GetStatusChannels.
+func (t *Table) GetStatusChannels() map[int]chan bool {
+	return t.server.GetStatusChannels()
+}
+
+// GetSpectatorChannel returns the channel the spectators of the table arrive on, see Server.GetSpectatorChannel.
+func (t *Table) GetSpectatorChannel() chan chan []byte {
+	return t.server.GetSpectatorChannel()
+}
+
+// GetPlayerNames returns the names the players of the table sent in their hello, see Server.GetPlayerNames.
+func (t *Table) GetPlayerNames() map[int]string {
+	return t.server.GetPlayerNames()
+}
+
+// GetBotStrategies returns the strategies of the players of the table that connected as bots, see Server.GetBotStrategies.
+func (t *Table) GetBotStrategies() map[int]string {
+	return t.server.GetBotStrategies()
+}
+
+// Close removes the table from the lobby and closes the connections of its players,
+// to be called once the game at the table is over. Closing a table twice, or a table of
+// a closed lobby, does nothing.
+//
+// Returns:
+// - None
+func (t *Table) Close() {
+	t.lobby.mutex.Lock()
+	_, open := t.lobby.tables[t.Id]
+	delete(t.lobby.tables, t.Id)
+	t.lobby.mutex.Unlock()
+	if open {
+		t.server.Close()
+	}
+}
+
+// Lobby is a server that hosts any number of tables at once. Clients list the open tables,
+// create a table or join one during the handshake, and every table is handed out on the
+// table channel once its seats have all been taken, so a game can be run at it. The lobby
+// keeps accepting connections until it is closed.
+type Lobby struct {
+	tables map[int]*Table
+	nextId int
+	ready  chan *Table
+	quit   chan bool
+	mutex  sync.Mutex
+
+	validate             func(config TableConfig) error
+	serverMaxReceiveSize int
+	listener             net.Listener
+	// set with SetTLS and SetPassword
+	tlsConfig *tls.Config
+	password  string
+}
+
+// Listen starts the lobby on the specified port and returns once it accepts connections.
+//
+// Parameters:
+// - port: The port on which the lobby listens for incoming connections.
+// - serverMaxReceiveSize: The maximum size for receiving data from clients.
+// - validate: Checks the config of a table a client wants to create, the client is rejected with the error.
+//
+// Returns:
+// - error: An error if the lobby cannot listen on the port.
+func (l *Lobby) Listen(port string, serverMaxReceiveSize int, validate func(config TableConfig) error) error {
+	log.Printf("lobby listening on port %v\n", port)
+	ln, err := listen(port, l.tlsConfig)
+	if err != nil {
+		return err
+	}
+	return l.Serve(ln, serverMaxReceiveSize, validate)
+}
+
+// Serve works like Listen but accepts the connections from ln, which lets the lobby run over
+// other transports such as websockets. The listener is closed when the lobby is closed.
+//
+// Parameters:
+// - ln: The listener to accept connections from.
+// - serverMaxReceiveSize: The maximum size for receiving data from clients.
+// - validate: Checks the config of a table a client wants to create, the client is rejected with the error.
+//
+// Returns:
+// - error: Always nil, the lobby accepts connections once it returns.
+func (l *Lobby) Serve(ln net.Listener, serverMaxReceiveSize int, validate func(config TableConfig) error) error {
+	l.serverMaxReceiveSize = serverMaxReceiveSize
+	l.validate = validate
+	l.listener = ln
+	l.tables = make(map[int]*Table)
+	l.nextId = 1
+	l.ready = make(chan *Table)
+	l.quit = make(chan bool)
+
+	go acceptConnections(ln, func(conn net.Conn) {
+		handleLobbyConnection(l, conn)
+	})
+	return nil
+}
+
+// Close stops accepting connections and closes every table of the lobby.
+//
+// Returns:
+// - None
+func (l *Lobby) Close() {
+	log.Printf("Closing lobby\n")
+	close(l.quit)
+	l.listener.Close()
+
+	l.mutex.Lock()
+	tables := []*Table{}
+	for _, table := range l.tables {
+		tables = append(tables, table)
+	}
+	l.mutex.Unlock()
+	for _, table := range tables {
+		table.Close()
+	}
+}
+
+// GetTableChannel returns the channel the tables are sent on once every seat has been taken.
+//
+// Returns:
+// - chan *Table: The channel of the tables ready to play.
+func (l *Lobby) GetTableChannel() chan *Table {
+	return l.ready
+}
+
+// GetTables returns the tables of the lobby ordered by id, both the open and the running ones.
+//
+// Returns:
+// - []TableInfo: A description of every table.
+func (l *Lobby) GetTables() []TableInfo {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return getTableInfos(l)
+}
+
+// getTableInfos describes every table, the lobby mutex has to be held by the caller.
+func getTableInfos(l *Lobby) []TableInfo {
+	infos := []TableInfo{}
+	for _, table := range l.tables {
+		table.server.mutex.Lock()
+		seated := len(table.server.tokens)
+		table.server.mutex.Unlock()
+		infos = append(infos, TableInfo{Id: table.Id, Config: table.Config, Seated: seated, Started: table.started})
+	}
+	sort.Slice(infos, func(i int, j int) bool {
+		return infos[i].Id < infos[j].Id
+	})
+	return infos
+}
+
+// handleLobbyConnection performs the handshake with a new connection of the lobby. Clients with
+// a session token get back their seat at the table the token was issued for, other clients
+// list, create or join a table as asked in their hello. Clients without the password of the
+// lobby are rejected before anything else.
+//
+// Parameters:
+// - l: The lobby instance.
+// - conn: The newly accepted connection.
+//
+// Returns:
+// - None
+func handleLobbyConnection(l *Lobby, conn net.Conn) {
+	h, ok := readHello(conn)
+	if !ok || !checkPassword(conn, l.password, h) {
+		return
+	}
+
+	if h.Token != "" {
+		table := findTableByToken(l, h.Token)
+		if table == nil {
+			reject(conn, fmt.Errorf("unknown session token"))
+			return
+		}
+		seatConnection(table.server, conn, h)
+		return
+	}
+
+	if h.Lobby == nil {
+		reject(conn, fmt.Errorf("the server is a lobby, list, create or join a table"))
+		return
+	}
+
+	var table *Table
+	switch h.Lobby.Action {
+	case lobbyList:
+		l.mutex.Lock()
+		tables := getTableInfos(l)
+		l.mutex.Unlock()
+		writeWelcome(conn, welcome{ActorId: -1, Tables: tables})
+		conn.Close()
+		return
+	case lobbyCreate:
+		if h.Spectate {
+			reject(conn, fmt.Errorf("spectators cannot create a table"))
+			return
+		}
+		var err error
+		table, err = createTable(l, h.Lobby.Config)
+		if err != nil {
+			reject(conn, err)
+			return
+		}
+	case lobbyJoin:
+		l.mutex.Lock()
+		table = l.tables[h.Lobby.Table]
+		started := table != nil && table.started
+		l.mutex.Unlock()
+		if table != nil && h.Spectate {
+			addSpectator(table.server, conn, h)
+			return
+		}
+		if table == nil || started {
+			reject(conn, fmt.Errorf("no open table with id %d", h.Lobby.Table))
+			return
+		}
+	default:
+		reject(conn, fmt.Errorf("unknown lobby action %q", h.Lobby.Action))
+		return
+	}
+
+	_, isNew, ok := seatConnection(table.server, conn, h)
+	if !ok || !isNew {
+		return
+	}
+
+	l.mutex.Lock()
+	table.server.mutex.Lock()
+	full := len(table.server.tokens) == table.Config.Players
+	table.server.mutex.Unlock()
+	start := full && !table.started
+	if start {
+		table.started = true
+	}
+	l.mutex.Unlock()
+
+	if start {
+		log.Printf("table %d is full\n", table.Id)
+		select {
+		case <-l.quit:
+		case l.ready <- table:
+		}
+	}
+}
+
+// createTable adds a new table to the lobby. A table that has not started is removed again
+// once none of its players is connected, so tables left by their players do not pile up.
+//
+// Parameters:
+// - l: The lobby instance.
+// - config: The settings of the table sent by the client.
+//
+// Returns:
+// - *Table: The new table, with every seat free.
+// - error: An error if the config is missing or invalid, or the lobby holds maxOpenTables open tables.
+func createTable(l *Lobby, config *TableConfig) (*Table, error) {
+	if config == nil {
+		return nil, fmt.Errorf("expected the settings of the table to create")
+	}
+	if config.Players < 1 {
+		return nil, fmt.Errorf("a table needs at least 1 player, got %d", config.Players)
+	}
+	if l.validate != nil {
+		err := l.validate(*config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	open := 0
+	for _, table := range l.tables {
+		if !table.started {
+			open += 1
+		}
+	}
+	if open >= maxOpenTables {
+		return nil, fmt.Errorf("the lobby has %d open tables, join one of them", open)
+	}
+	server := &Server{playerNum: config.Players, serverMaxReceiveSize: l.serverMaxReceiveSize, table: l.nextId}
+	createSeats(server)
+	table := &Table{Id: l.nextId, Config: *config, server: server, lobby: l}
+	server.dropped = func() {
+		removeAbandonedTable(l, table)
+	}
+	l.tables[table.Id] = table
+	l.nextId += 1
+	log.Printf("created table %d with %d players and %d bots\n", table.Id, config.Players, config.Bots)
+	return table, nil
+}
+
+// removeAbandonedTable removes a table that has not started from the lobby and closes it if
+// none of its players is connected anymore. Running tables are left to the game at them.
+func removeAbandonedTable(l *Lobby, table *Table) {
+	l.mutex.Lock()
+	if table.started || l.tables[table.Id] != table {
+		l.mutex.Unlock()
+		return
+	}
+	table.server.mutex.Lock()
+	connected := 0
+	for _, conn := range table.server.conn {
+		if conn != nil {
+			connected += 1
+		}
+	}
+	table.server.mutex.Unlock()
+	if connected > 0 {
+		l.mutex.Unlock()
+		return
+	}
+	delete(l.tables, table.Id)
+	l.mutex.Unlock()
+
+	log.Printf("table %d was left by all its players and is removed\n", table.Id)
+	table.server.Close()
+}
+
+// findTableByToken returns the table with the seat the session token was issued for, or nil.
+func findTableByToken(l *Lobby, token string) *Table {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, table := range l.tables {
+		table.server.mutex.Lock()
+		found := false
+		for _, t := range table.server.tokens {
+			if t == token {
+				found = true
+			}
+		}
+		table.server.mutex.Unlock()
+		if found {
+			return table
+		}
+	}
+	return nil
+}
+
+// ListTables connects to a lobby, with the dialer if one is set, and returns its tables.
+// The client does not take a seat and can Connect afterwards.
+//
+// Parameters:
+// - hostname: The host of the lobby.
+// - port: The port of the lobby.
+//
+// Returns:
+// - []TableInfo: The tables of the lobby ordered by id.
+// - error: An error if connecting fails or the server is not a lobby.
+func (c *Client) ListTables(hostname string, port string) ([]TableInfo, error) {
+	conn, err := c.open(hostname, port)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	h := createHello()
+	h.Password = c.password
+	h.Lobby = &lobbyRequest{Action: lobbyList}
+	w, err := handshake(conn, h)
+	if err != nil {
+		return nil, err
+	}
+	return w.Tables, nil
+}
@@ -0,0 +1,205 @@
+package tcp
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+)
+
+// loadServerTLS loads the certificate and private key a server presents to its clients.
+//
+// Parameters:
+// - certFile: Path of the PEM encoded certificate, may hold the whole chain.
+// - keyFile: Path of the PEM encoded private key of the certificate.
+//
+// Returns:
+// - *tls.Config: The TLS settings of the server.
+// - error: An error if the files cannot be read or do not hold a matching certificate and key.
+func loadServerTLS(certFile string, keyFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// loadClientTLS loads the certificate authorities a client trusts to sign the server's certificate.
+//
+// Parameters:
+// - caFile: Path of the PEM encoded CA certificates, or of the self-signed server certificate.
+//
+// Returns:
+// - *tls.Config: The TLS settings of the client.
+// - error: An error if the file cannot be read or holds no certificate.
+func loadClientTLS(caFile string) (*tls.Config, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("failed to load TLS CA: no certificate found in %s", caFile)
+	}
+	return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
+}
+
+// listen listens for TCP connections on port, wrapped in TLS if config is set.
+//
+// Parameters:
+// - port: The port to listen on.
+// - config: The TLS settings of the server, nil for plain TCP.
+//
+// Returns:
+// - net.Listener: The listener.
+// - error: An error if the port cannot be listened on.
+func listen(port string, config *tls.Config) (net.Listener, error) {
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return nil, err
+	}
+	if config != nil {
+		return tls.NewListener(ln, config), nil
+	}
+	return ln, nil
+}
+
+// dialTCP opens a TCP connection to address, performing the TLS handshake if config is set.
+// Connecting and the handshake give up after handshakeTimeout, so an unreachable host does not hang the client.
+//
+// Parameters:
+// - address: The host and port of the server.
+// - config: The TLS settings of the client, nil for plain TCP.
+//
+// Returns:
+// - net.Conn: The connection.
+// - error: An error if connecting or the TLS handshake fails.
+func dialTCP(address string, config *tls.Config) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: handshakeTimeout}
+	if config == nil {
+		return dialer.Dial("tcp", address)
+	}
+	return tls.DialWithDialer(dialer, "tcp", address, config)
+}
+
+// checkPassword rejects a client whose hello does not carry the password of the server.
+// Every client is accepted if the password is empty.
+//
+// Parameters:
+// - conn: The connection of the client, closed if it is rejected.
+// - password: The password of the server.
+// - h: The hello sent by the client.
+//
+// Returns:
+// - bool: true if the client may continue the handshake.
+func checkPassword(conn net.Conn, password string, h hello) bool {
+	if password == "" {
+		return true
+	}
+	if h.Password == "" {
+		reject(conn, fmt.Errorf("the table is protected by a password"))
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(h.Password), []byte(password)) != 1 {
+		reject(conn, fmt.Errorf("wrong table password"))
+		return false
+	}
+	return true
+}
+
+// SetTLS makes the server accept only TLS connections presenting the given certificate,
+// must be called before Listen.
+//
+// Parameters:
+// - certFile: Path of the PEM encoded certificate.
+// - keyFile: Path of the PEM encoded private key of the certificate.
+//
+// Returns:
+// - error: An error if the certificate or the key cannot be loaded.
+func (s *Server) SetTLS(certFile string, keyFile string) error {
+	config, err := loadServerTLS(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	s.tlsConfig = config
+	return nil
+}
+
+// GetTLSConfig returns the TLS settings set with SetTLS, so other transports can use them.
+//
+// Returns:
+// - *tls.Config: The TLS settings, nil if the server does not use TLS.
+func (s *Server) GetTLSConfig() *tls.Config {
+	return s.tlsConfig
+}
+
+// SetPassword makes the server reject clients that do not send the password in their hello,
+// must be called before Listen.
+//
+// Parameters:
+// - password: The password shared with the players, empty to accept every client.
+func (s *Server) SetPassword(password string) {
+	s.password = password
+}
+
+// SetTLS makes the lobby accept only TLS connections presenting the given certificate,
+// must be called before Listen.
+//
+// Parameters:
+// - certFile: Path of the PEM encoded certificate.
+// - keyFile: Path of the PEM encoded private key of the certificate.
+//
+// Returns:
+// - error: An error if the certificate or the key cannot be loaded.
+func (l *Lobby) SetTLS(certFile string, keyFile string) error {
+	config, err := loadServerTLS(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	l.tlsConfig = config
+	return nil
+}
+
+// GetTLSConfig returns the TLS settings set with SetTLS, so other transports can use them.
+//
+// Returns:
+// - *tls.Config: The TLS settings, nil if the lobby does not use TLS.
+func (l *Lobby) GetTLSConfig() *tls.Config {
+	return l.tlsConfig
+}
+
+// SetPassword makes the lobby reject clients that do not send the password in their hello,
+// listing the tables included. Must be called before Listen.
+//
+// Parameters:
+// - password: The password shared with the players, empty to accept every client.
+func (l *Lobby) SetPassword(password string) {
+	l.password = password
+}
+
+// SetTLS makes the client connect over TLS and only trust servers whose certificate is signed
+// by one of the certificates in caFile. Must be called before Connect.
+//
+// Parameters:
+// - caFile: Path of the PEM encoded CA certificates, or of the self-signed server certificate.
+//
+// Returns:
+// - error: An error if the certificates cannot be loaded.
+func (c *Client) SetTLS(caFile string) error {
+	config, err := loadClientTLS(caFile)
+	if err != nil {
+		return err
+	}
+	c.tlsConfig = config
+	return nil
+}
+
+// SetPassword sets the password sent in the hello, must be called before Connect.
+//
+// Parameters:
+// - password: The password of the table.
+func (c *Client) SetPassword(password string) {
+	c.password = password
+}
@@ -1,84 +1,224 @@
 package tcp
 
 import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"maps"
 	"net"
+	"sync"
+	"time"
 )
 
 const (
-	pingMagic = "ABCZ"
-	pongMagic = "ZCBA"
+	// version of the handshake and framing spoken by this package, and the oldest version the
+	// server still accepts
+	protocolVersion    = 2
+	minProtocolVersion = 2
+	// name the client introduces itself with in the hello
+	clientName = "pointsalad-go"
+	// kind sent in the hello by clients that play with a bot strategy
+	kindBot = "bot"
+
+	// clients of protocol version 1 opened the handshake with the ping magic and expected the pong
+	// magic followed by a welcome, which is used to tell them to upgrade
+	legacyPingMagic = "ABCZ"
+	legacyPongMagic = "ZCBA"
+
+	// size in bytes of the length header written in front of every message
+	headerSize = 4
+	// max size of the hello and welcome messages exchanged during the handshake, the welcome
+	// of a lobby lists every table
+	handshakeMaxSize = 64 * 1024
+
+	handshakeTimeout = 10 * time.Second
+	writeTimeout     = 10 * time.Second
+	// how many messages a spectator can fall behind before the host drops it
+	spectatorBufferSize = 256
+	reconnectAttempts   = 10
+	reconnectDelay      = time.Second
 )
 
+// messageConn is implemented by connections that keep message boundaries themselves, such as
+// websockets. Whole messages are written to and read from them without the length header.
+type messageConn interface {
+	ReadMessage(maxSize int) ([]byte, error)
+	WriteMessage(data []byte) error
+}
+
+// writeMessage writes data as a single framed message to w. The frame consists of a
+// big endian uint32 length header followed by the data itself, which lets the reader
+// recover message boundaries no matter how the underlying stream splits or merges writes.
+// A messageConn writes the data as one message of its own instead.
+//
+// Parameters:
+// - w: The writer to send the frame to, usually a net.Conn.
+// - data: The message to send, may be empty.
+//
+// Returns:
+// - error: An error if the header or the data could not be written.
+func writeMessage(w io.Writer, data []byte) error {
+	if mc, ok := w.(messageConn); ok {
+		return mc.WriteMessage(data)
+	}
+	buf := make([]byte, headerSize+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	copy(buf[headerSize:], data)
+	_, err := w.Write(buf)
+	return err
+}
+
+// readMessage reads a single framed message written by writeMessage from r.
+// It blocks until the whole message has arrived, so the returned slice always
+// contains exactly one message. A messageConn reads one message of its own instead.
+//
+// Parameters:
+// - r: The reader to read the frame from, usually a net.Conn.
+// - maxSize: The largest message size accepted, larger messages are rejected.
+//
+// Returns:
+// - []byte: The message data.
+// - error: An error if reading fails or the message is larger than maxSize.
+func readMessage(r io.Reader, maxSize int) ([]byte, error) {
+	if mc, ok := r.(messageConn); ok {
+		return mc.ReadMessage(maxSize)
+	}
+	header := make([]byte, headerSize)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header)
+	if uint64(size) > uint64(maxSize) {
+		return nil, fmt.Errorf("message of %d bytes exceeds max size of %d bytes", size, maxSize)
+	}
+	buf := make([]byte, size)
+	_, err = io.ReadFull(r, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// hello is the first framed JSON message sent by the client. It carries the newest protocol
+// version the client speaks, the name of the client program and the features it supports.
+// An empty token asks for a new seat, a token issued earlier resumes that seat. A new player
+// may ask for a specific seat and tells the name it plays under. A client that plays with a bot
+// strategy instead of a human says so with the kind "bot" and the name of the strategy.
+// Spectators get no seat and no token. The password is only checked by servers that have one.
+// Clients of a Lobby also say which table they want,
+// see lobbyRequest.
+type hello struct {
+	Version  int           `json:"version"`
+	Client   string        `json:"client,omitempty"`
+	Name     string        `json:"name,omitempty"`
+	Kind     string        `json:"kind,omitempty"`
+	Strategy string        `json:"strategy,omitempty"`
+	Seat     *int          `json:"seat,omitempty"`
+	Features []string      `json:"features,omitempty"`
+	Token    string        `json:"token,omitempty"`
+	Password string        `json:"password,omitempty"`
+	Spectate bool          `json:"spectate,omitempty"`
+	Lobby    *lobbyRequest `json:"lobby,omitempty"`
+}
+
+// welcome is the framed JSON message the server answers the hello with. It carries the protocol
+// version both sides speak from then on, the features they both support, the actor id and
+// session token of the seat, or an error if the client was rejected. The actor id is -1 for
+// clients without a seat. A Lobby also sends the id of the table the seat belongs to, or the
+// open tables if they were asked for.
+type welcome struct {
+	Version  int         `json:"version"`
+	ActorId  int         `json:"actorId"`
+	Features []string    `json:"features,omitempty"`
+	Token    string      `json:"token,omitempty"`
+	Table    int         `json:"table,omitempty"`
+	Tables   []TableInfo `json:"tables,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
 type Client struct {
 	conn                 net.Conn
+	hostname             string
+	port                 string
+	token                string
+	lobby                *lobbyRequest
+	table                int
+	spectate             bool
+	password             string
+	name                 string
+	strategy             string
+	seat                 *int
+	actorId              int
+	tlsConfig            *tls.Config
+	dialer               func(address string, config *tls.Config) (net.Conn, error)
 	in                   chan []byte
 	out                  chan []byte
-	quitRead             chan bool
-	quitWrite            chan bool
+	quit                 chan bool
+	mutex                sync.Mutex
+	writer               sync.WaitGroup
 	clientMaxReceiveSize int
 }
 
 // Connect establishes a TCP connection to the specified host and port,
-// performs a ping-pong test to verify the connection, and initializes
+// performs the handshake to verify the connection and get a seat, and initializes
 // channels for reading and writing data. The function also starts two
 // goroutines for handling reading and writing concurrently.
 //
+// If the connection drops later on, the client reconnects on its own using the
+// session token it got during the handshake, so the read and write channels stay usable.
+//
 // Parameters:
 // - hostname: The target host to connect to.
 // - port: The target port to connect to.
 // - clientMaxReceiveSize: The maximum size for receiving data from the server.
 //
 // Returns:
-// - error: An error if the connection or ping-pong test fails, or nil if successful.
+// - error: An error if the connection or handshake fails, or nil if successful.
 func (c *Client) Connect(hostname string, port string, clientMaxReceiveSize int) error {
 	c.clientMaxReceiveSize = clientMaxReceiveSize
-	conn, err := net.Dial("tcp", hostname+":"+port)
-	if err != nil {
-		return err
-	}
+	c.hostname = hostname
+	c.port = port
 
-	buf := []byte(pingMagic)
-	_, err = conn.Write(buf)
+	conn, err := c.dial()
 	if err != nil {
 		return err
 	}
-	buf = make([]byte, len(pongMagic))
-	_, err = conn.Read(buf)
-	if err != nil {
-		return err
-	}
-	if string(buf) != pongMagic {
-		return fmt.Errorf("Failed ping pong test\n")
-	}
 
 	c.conn = conn
 	c.in = make(chan []byte)
 	c.out = make(chan []byte)
-	c.quitRead = make(chan bool)
-	c.quitWrite = make(chan bool)
+	c.quit = make(chan bool)
 
 	go c.handleRead()
+	c.writer.Add(1)
 	go c.handleWrite()
 	return nil
 }
 
 // Close terminates the client's connection by closing the TCP connection,
-// and sends signals to stop the reading and writing goroutines. It logs
+// and signals the reading and writing goroutines to stop. It logs
 // the closure of the client connection.
 //
-// This function gracefully shuts down the client by ensuring that the
-// connection is properly closed and that no further read/write operations
-// are attempted.
+// This function gracefully shuts down the client by ensuring that a message
+// already handed to the write channel is written, that the connection is properly
+// closed and that no further read/write operations or reconnection attempts are made.
 //
 // Returns:
 // - None
 func (c *Client) Close() {
 	log.Printf("Closing client\n")
+	close(c.quit)
+	c.writer.Wait()
+	c.mutex.Lock()
 	c.conn.Close()
-	c.quitRead <- true
-	c.quitWrite <- true
+	c.mutex.Unlock()
 }
 
 // GetReadChannel returns the channel used for reading data from the client connection.
@@ -101,139 +241,368 @@ func (c *Client) GetWriteChannel() chan []byte {
 	return c.out
 }
 
-// handleRead continuously reads data from the client's connection in a loop,
-// and sends the data to the read channel (`c.in`). If an error occurs while reading
-// or the read operation is interrupted, it closes the read channel and stops the goroutine.
-// The function stops reading when the quitRead channel is signaled.
+// SetSessionToken sets the session token sent during the handshake, which makes
+// Connect resume the seat the token was issued for instead of taking a new one.
+//
+// Parameters:
+// - token: A session token returned by GetSessionToken in an earlier connection.
+func (c *Client) SetSessionToken(token string) {
+	c.token = token
+}
+
+// GetSessionToken returns the session token issued by the server for this client's seat.
+//
+// Returns:
+// - string: The session token, empty before Connect succeeded.
+func (c *Client) GetSessionToken() string {
+	return c.token
+}
+
+// CreateTable makes Connect create a new table in a Lobby and take its first seat,
+// must be called before Connect.
+//
+// Parameters:
+// - config: The number of players and bots of the table.
+func (c *Client) CreateTable(config TableConfig) {
+	c.lobby = &lobbyRequest{Action: lobbyCreate, Config: &config}
+}
+
+// JoinTable makes Connect take a seat at an open table of a Lobby, must be called before Connect.
+//
+// Parameters:
+// - id: The id of the table, as listed by ListTables.
+func (c *Client) JoinTable(id int) {
+	c.lobby = &lobbyRequest{Action: lobbyJoin, Table: id}
+}
+
+// SetDialer replaces the plain TCP connection to the server with another transport, such as
+// websockets. Must be called before Connect.
+//
+// Parameters:
+//   - dial: Opens a connection to address, which is hostname:port, over TLS with config unless
+//     config is nil.
+func (c *Client) SetDialer(dial func(address string, config *tls.Config) (net.Conn, error)) {
+	c.dialer = dial
+}
+
+// Spectate makes Connect join as a spectator, who gets every message sent to all players but
+// has no seat. Combined with JoinTable the client watches a table of a Lobby, open or running.
+// Must be called before Connect.
+func (c *Client) Spectate() {
+	c.spectate = true
+}
+
+// SetName sets the name the player is shown under, sent in the hello. Must be called before Connect.
+//
+// Parameters:
+// - name: The name of the player, empty to leave it to the server.
+func (c *Client) SetName(name string) {
+	c.name = name
+}
+
+// SetBot makes Connect tell the server that the seat is played by a bot with the named strategy
+// instead of a human. Must be called before Connect.
+//
+// Parameters:
+// - strategy: The name of the strategy of the bot.
+func (c *Client) SetBot(strategy string) {
+	c.strategy = strategy
+}
+
+// RequestSeat makes Connect ask for a specific seat instead of the next free one, the client
+// is rejected if the seat is taken. Must be called before Connect.
+//
+// Parameters:
+// - seat: The actor id of the seat.
+func (c *Client) RequestSeat(seat int) {
+	c.seat = &seat
+}
+
+// GetActorId returns the actor id of the client's seat, as assigned by the server in the handshake.
+//
+// Returns:
+// - int: The actor id, -1 for spectators.
+func (c *Client) GetActorId() int {
+	return c.actorId
+}
+
+// GetTableId returns the id of the lobby table the client's seat belongs to.
+//
+// Returns:
+// - int: The id of the table, 0 if the server is not a lobby.
+func (c *Client) GetTableId() int {
+	return c.table
+}
+
+// dial opens a new connection to the server and performs the handshake,
+// presenting the session token if the client already has one.
+//
+// Returns:
+// - net.Conn: The connection, ready for framed messages.
+// - error: An error if connecting fails or the server rejected the client.
+func (c *Client) dial() (net.Conn, error) {
+	conn, err := c.open(c.hostname, c.port)
+	if err != nil {
+		return nil, err
+	}
+	h := createHello()
+	h.Token = c.token
+	h.Name = c.name
+	if c.strategy != "" {
+		h.Kind = kindBot
+		h.Strategy = c.strategy
+	}
+	h.Password = c.password
+	h.Spectate = c.spectate
+	// the token alone finds the seat again
+	if c.token == "" {
+		h.Lobby = c.lobby
+		h.Seat = c.seat
+	}
+	w, err := handshake(conn, h)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c.token = w.Token
+	c.table = w.Table
+	c.actorId = w.ActorId
+	return useFeatures(conn, w.Features), nil
+}
+
+// open opens a connection to the server with the dialer, or over TCP if none is set.
+// TLS is used if it was set up with SetTLS.
+func (c *Client) open(hostname string, port string) (net.Conn, error) {
+	address := net.JoinHostPort(hostname, port)
+	if c.dialer != nil {
+		return c.dialer(address, c.tlsConfig)
+	}
+	return dialTCP(address, c.tlsConfig)
+}
+
+// createHello returns the hello of this client, with the protocol version and the supported features.
+func createHello() hello {
+	return hello{Version: protocolVersion, Client: clientName, Features: supportedFeatures}
+}
+
+// handshake sends the hello and reads back the welcome.
+//
+// Parameters:
+// - conn: A newly opened connection to the server.
+// - h: The hello to send.
+//
+// Returns:
+//   - welcome: The welcome of the server.
+//   - error: An error if the handshake fails, the server speaks an incompatible protocol version
+//     or the server rejected the client.
+func handshake(conn net.Conn, h hello) (welcome, error) {
+	w := welcome{}
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		return w, err
+	}
+	err = writeMessage(conn, data)
+	if err != nil {
+		return w, err
+	}
+
+	data, err = readMessage(conn, handshakeMaxSize)
+	if err != nil {
+		return w, fmt.Errorf("server did not answer the handshake, check whether it uses TLS: %w", err)
+	}
+	err = json.Unmarshal(data, &w)
+	if err != nil {
+		return w, fmt.Errorf("server answered the handshake with an unknown welcome: %w", err)
+	}
+	if w.Error != "" {
+		return w, fmt.Errorf("Server rejected connection: %s", w.Error)
+	}
+	if w.Version < minProtocolVersion || w.Version > h.Version {
+		return w, fmt.Errorf("server speaks protocol version %d, the client speaks versions %d to %d", w.Version, minProtocolVersion, h.Version)
+	}
+
+	conn.SetDeadline(time.Time{})
+	return w, nil
+}
+
+// reconnect replaces a broken connection, retrying a few times before giving up.
+//
+// Parameters:
+// - old: The connection that failed.
+//
+// Returns:
+// - net.Conn: The new connection, or nil if the client is closing or all attempts failed.
+func (c *Client) reconnect(old net.Conn) net.Conn {
+	old.Close()
+	for range reconnectAttempts {
+		select {
+		case <-c.quit:
+			return nil
+		case <-time.After(reconnectDelay):
+		}
+		log.Printf("Connection lost, reconnecting to %s:%s\n", c.hostname, c.port)
+		conn, err := c.dial()
+		if err != nil {
+			log.Printf("Failed to reconnect: %s\n", err)
+			continue
+		}
+		c.mutex.Lock()
+		c.conn = conn
+		c.mutex.Unlock()
+		return conn
+	}
+	return nil
+}
+
+// handleRead continuously reads framed messages from the client's connection in a loop,
+// and sends each message to the read channel (`c.in`). If an error occurs while reading
+// it tries to reconnect, and only if that fails it closes the read channel and stops the goroutine.
+// The function stops reading when the quit channel is closed.
 //
-// It uses a buffered slice to store the incoming data, and selects between
-// quitting or sending the data to the read channel.
+// Every message sent by the server arrives as exactly one value on the read channel,
+// and selects between quitting or sending the message to the read channel.
 //
 // Returns:
 // - None
 func (c *Client) handleRead() {
+	c.mutex.Lock()
+	conn := c.conn
+	c.mutex.Unlock()
 	for {
-		buf := make([]byte, c.clientMaxReceiveSize)
-		n, err := c.conn.Read(buf)
+		buf, err := readMessage(conn, c.clientMaxReceiveSize)
 		if err != nil {
-			// log.Printf("ERROR: %v\n", err)
-			close(c.in)
-			<-c.quitRead
-			return
+			conn = c.reconnect(conn)
+			if conn == nil {
+				close(c.in)
+				return
+			}
+			continue
 		}
 		select {
-		case <-c.quitRead:
+		case <-c.quit:
 			return
-		case c.in <- buf[:n]:
+		case c.in <- buf:
 		}
 	}
 }
 
 // handleWrite continuously listens for data on the write channel (`c.out`)
-// and writes it to the client's connection. If an error occurs while writing,
-// the loop is broken, and the function terminates. It stops writing when the quitWrite
-// channel is signaled.
-//
-// The function uses a loop to wait for data to write and checks for a quit signal
-// to stop the writing process. Once the data is successfully written, the function
-// proceeds to the next data or terminates if an error occurs.
+// and writes it to the client's connection as a framed message. If an error occurs while writing,
+// the message is dropped, the reading goroutine notices the broken connection and reconnects.
+// It stops writing when the quit channel is closed.
 //
 // Returns:
 // - None
 func (c *Client) handleWrite() {
-loop:
+	defer c.writer.Done()
 	for {
 		var valToSend []byte
 		select {
-		case <-c.quitWrite:
+		case <-c.quit:
 			return
 		case valToSend = <-c.out:
 		}
-		_, err := c.conn.Write(valToSend)
+		c.mutex.Lock()
+		conn := c.conn
+		c.mutex.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		err := writeMessage(conn, valToSend)
 		if err != nil {
 			// log.Printf("ERROR: %v\n", err)
-			break loop
+			continue
 		}
 	}
-	<-c.quitWrite
 }
 
 type Server struct {
-	conn      []net.Conn
-	out       map[int]chan []byte
-	in        map[int]chan []byte
-	quitRead  map[int]chan bool
-	quitWrite map[int]chan bool
+	// current connection of every seat, nil while the player is disconnected
+	conn   map[int]net.Conn
+	tokens map[int]string
+	// names the players sent in their hello
+	names map[int]string
+	// strategies of the players that said in their hello that they are bots
+	strategies map[int]string
+	// called without the mutex held when a seated player disconnects, nil if nothing has to be done
+	dropped func()
+	// the name every seat is kept for, set with ReserveSeats, nil if anyone can take any seat
+	reserved map[int]string
+	out      map[int]chan []byte
+	in       map[int]chan []byte
+	status   map[int]chan bool
+	// new spectators, as the channel to send them messages on
+	spectators chan chan []byte
+	joined     chan int
+	quit       chan bool
+	mutex      sync.Mutex
+	// running write goroutines
+	writers sync.WaitGroup
 
+	playerNum            int
 	serverMaxReceiveSize int
-	listener             net.Listener
+	// id of the lobby table the server holds the seats of, 0 for a standalone server
+	table    int
+	listener net.Listener
+	// set with SetTLS and SetPassword
+	tlsConfig *tls.Config
+	password  string
 }
 
 // Listen initializes the server to listen on the specified port and accepts connections
 // from a predefined number of players. It sets up channels for communication with each player
 // and starts goroutines to handle reading and writing data from/to each connected client.
 //
+// The server keeps accepting connections after Listen returns, so a player whose connection
+// dropped can resume its seat with the session token it was issued when joining.
+//
 // Parameters:
 // - port: The port on which the server listens for incoming connections.
 // - playerNum: The number of players (clients) the server expects to connect.
 // - serverMaxReceiveSize: The maximum size for receiving data from clients.
 //
 // Returns:
-//   - error: Returns an error if there is an issue during the server setup or client connections,
-//     or nil if the server was successfully initialized and is accepting connections.
+//   - error: Returns an error if there is an issue during the server setup,
+//     or nil once every seat has been taken by a player.
 func (server *Server) Listen(port string, playerNum int, serverMaxReceiveSize int) error {
-	server.serverMaxReceiveSize = serverMaxReceiveSize
 	log.Printf("listening on port %v\n", port)
-	ln, err := net.Listen("tcp", ":"+port)
+	ln, err := listen(port, server.tlsConfig)
 	if err != nil {
 		return err
 	}
+	return server.Serve(ln, playerNum, serverMaxReceiveSize)
+}
+
+// Serve works like Listen but accepts the connections from ln, which lets the server run over
+// other transports such as websockets. The listener is closed when the server is closed.
+//
+// Parameters:
+// - ln: The listener to accept connections from.
+// - playerNum: The number of players (clients) the server expects to connect.
+// - serverMaxReceiveSize: The maximum size for receiving data from clients.
+//
+// Returns:
+// - error: nil once every seat has been taken by a player.
+func (server *Server) Serve(ln net.Listener, playerNum int, serverMaxReceiveSize int) error {
+	server.serverMaxReceiveSize = serverMaxReceiveSize
+	server.playerNum = playerNum
 	server.listener = ln
-	id := 0
+	createSeats(server)
 
-	server.out = make(map[int]chan []byte)
-	server.in = make(map[int]chan []byte)
-	server.quitRead = make(map[int]chan bool)
-	server.quitWrite = make(map[int]chan bool)
+	go acceptConnections(ln, func(conn net.Conn) {
+		handleConnection(server, conn)
+	})
 
-	for len(server.conn) < playerNum {
-		log.Printf("Waiting for %d player(s)\n", playerNum-len(server.conn))
-		conn, err := ln.Accept()
-		if err != nil {
-			log.Printf("Failed to accept connection %s", err)
-			continue
-		}
-		addr := conn.RemoteAddr()
-		log.Printf("%s connected\n", addr.String())
-		server.conn = append(server.conn, conn)
-		server.out[id] = make(chan []byte)
-		server.in[id] = make(chan []byte)
-		server.quitRead[id] = make(chan bool)
-		server.quitWrite[id] = make(chan bool)
-
-		buf := make([]byte, len(pingMagic))
-		_, err = conn.Read(buf)
-		if err != nil {
-			return err
-		}
-		if string(buf) != pingMagic {
-			return fmt.Errorf("expected ping pong test\n")
-		}
-		buf = []byte(pongMagic)
-		_, err = conn.Write(buf)
-		if err != nil {
-			return err
-		}
-
-		go handleRead(server, id)
-		go handleWrite(server, id)
-		id += 1
+	for joined := 0; joined < playerNum; joined += 1 {
+		log.Printf("Waiting for %d player(s)\n", playerNum-joined)
+		<-server.joined
 	}
 	return nil
 }
 
 // Close gracefully shuts down the server by closing all client connections,
 // stopping the read and write operations, and closing the server listener.
+// Messages already handed to a write channel are written before the connections are closed.
 // It ensures all resources are cleaned up and that the server terminates
 // without leaving any open connections or goroutines.
 //
@@ -241,22 +610,28 @@ func (server *Server) Listen(port string, playerNum int, serverMaxReceiveSize in
 // - None
 func (s *Server) Close() {
 	log.Printf("Closing server\n")
-	for k := range s.in {
-		err := s.conn[k].Close()
-		if err != nil {
-			log.Fatalf("Failed to close server correctly")
-		}
+	// under the mutex so no spectator writer starts after quit is closed
+	s.mutex.Lock()
+	close(s.quit)
+	s.mutex.Unlock()
+	if s.listener != nil {
+		s.listener.Close()
 	}
-	for k := range s.in {
-		s.quitRead[k] <- true
-		s.quitWrite[k] <- true
+	s.writers.Wait()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for id, conn := range s.conn {
+		if conn != nil {
+			conn.Close()
+		}
+		s.conn[id] = nil
 	}
-	s.listener.Close()
 }
 
 // GetReadChannels returns a map of channels used for reading data from each connected client.
 // This allows other parts of the application to access the channels and read incoming data
-// from the clients.
+// from the clients. The channels stay the same when a client reconnects.
 //
 // Returns:
 //   - map[int]chan []byte: A map of read channels, where the key is the client ID and the value
@@ -267,7 +642,7 @@ func (s *Server) GetReadChannels() map[int]chan []byte {
 
 // GetWriteChannels returns a map of channels used for writing data to each connected client.
 // This allows other parts of the application to send data to the clients via their respective
-// write channels.
+// write channels. Data written while a client is disconnected is dropped.
 //
 // Returns:
 //   - map[int]chan []byte: A map of write channels, where the key is the client ID and the value
@@ -276,38 +651,531 @@ func (s *Server) GetWriteChannels() map[int]chan []byte {
 	return s.out
 }
 
-// handleRead continuously reads data from a client connection and sends the
-// data to the associated read channel (`s.in[connId]`). If an error occurs
-// while reading or if the quitRead signal is triggered, it gracefully closes
-// the read channel and stops the goroutine.
+// GetStatusChannels returns a map of channels reporting the connection status of each client.
+// false is sent when a client's connection drops and true when it reconnects with its session
+// token. Only the latest status is kept, so a reader may miss a short disconnect but always
+// sees the current status.
+//
+// Returns:
+//   - map[int]chan bool: A map of status channels, where the key is the client ID and the value
+//     is the status channel for that client.
+func (s *Server) GetStatusChannels() map[int]chan bool {
+	return s.status
+}
+
+// GetPlayerNames returns the names the players sent in their hello.
+//
+// Returns:
+//   - map[int]string: A copy of the names, where the key is the client ID. Players that sent no
+//     name are missing.
+func (s *Server) GetPlayerNames() map[int]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	names := make(map[int]string)
+	for id, name := range s.names {
+		names[id] = name
+	}
+	return names
+}
+
+// ReserveSeats keeps every seat for the player connecting with the given name, as a resumed
+// game has to give every player back its own seat. New players only get a seat kept for the
+// name they send in their hello, seats kept for an empty name go to players without a name.
+// Must be called before Listen.
+//
+// Parameters:
+// - names: The name every seat is kept for by client ID, nil to let anyone take any seat.
+func (s *Server) ReserveSeats(names map[int]string) {
+	s.reserved = maps.Clone(names)
+}
+
+// GetBotStrategies returns the strategies of the players that connected as bots.
+//
+// Returns:
+//   - map[int]string: A copy of the strategies, where the key is the client ID. Players that
+//     connected as humans are missing.
+func (s *Server) GetBotStrategies() map[int]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return maps.Clone(s.strategies)
+}
+
+// createSeats sets up the channels of every seat and starts their write goroutines,
+// playerNum and serverMaxReceiveSize have to be set by the caller.
+//
+// Parameters:
+// - s: The server instance.
+//
+// Returns:
+// - None
+func createSeats(s *Server) {
+	s.conn = make(map[int]net.Conn)
+	s.tokens = make(map[int]string)
+	s.names = make(map[int]string)
+	s.strategies = make(map[int]string)
+	s.out = make(map[int]chan []byte)
+	s.in = make(map[int]chan []byte)
+	s.status = make(map[int]chan bool)
+	s.spectators = make(chan chan []byte)
+	s.joined = make(chan int)
+	s.quit = make(chan bool)
+
+	for id := range s.playerNum {
+		s.out[id] = make(chan []byte)
+		s.in[id] = make(chan []byte)
+		// holds only the latest status, see setStatus
+		s.status[id] = make(chan bool, 1)
+	}
+	// started once the maps are filled, the writers read them
+	for id := range s.playerNum {
+		s.writers.Add(1)
+		go handleWrite(s, id)
+	}
+}
+
+// GetSpectatorChannel returns the channel new spectators arrive on. Every spectator is
+// represented by the buffered channel to send it data on, data sent after the spectator left is
+// dropped. Closing the channel closes the connection of the spectator.
+//
+// Returns:
+//   - chan chan []byte: The channel of the write channels of new spectators.
+func (s *Server) GetSpectatorChannel() chan chan []byte {
+	return s.spectators
+}
+
+// acceptConnections accepts connections until the listener is closed, and handles
+// each of them in its own goroutine.
+//
+// Parameters:
+// - ln: The listener to accept connections from.
+// - handle: Performs the handshake with a new connection.
+//
+// Returns:
+// - None
+func acceptConnections(ln net.Listener, handle func(conn net.Conn)) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("Failed to accept connection %s", err)
+			continue
+		}
+		go handle(conn)
+	}
+}
+
+// handleConnection performs the handshake with a new connection and binds it to a seat.
+// Clients without a token get the next free seat and a new session token, clients with a token
+// get back the seat the token was issued for, replacing any connection still bound to it.
+// Clients are rejected if the password is wrong, the token is unknown, every seat is taken or
+// they ask for a lobby table.
+//
+// Parameters:
+// - s: The server instance, which holds the client connections and associated channels.
+// - conn: The newly accepted connection.
+//
+// Returns:
+// - None
+func handleConnection(s *Server, conn net.Conn) {
+	h, ok := readHello(conn)
+	if !ok || !checkPassword(conn, s.password, h) {
+		return
+	}
+	if h.Lobby != nil {
+		reject(conn, fmt.Errorf("the server hosts a single game, not a lobby"))
+		return
+	}
+	if h.Spectate {
+		addSpectator(s, conn, h)
+		return
+	}
+	id, isNew, ok := seatConnection(s, conn, h)
+	if ok && isNew {
+		s.joined <- id
+	}
+}
+
+// readHello reads the hello of a new connection and checks that the client speaks a protocol
+// version the server accepts. The connection is closed if the handshake fails, clients with
+// an incompatible version are told so first.
+//
+// Parameters:
+// - conn: The newly accepted connection.
+//
+// Returns:
+// - hello: The hello sent by the client.
+// - bool: false if the handshake failed.
+func readHello(conn net.Conn) (hello, bool) {
+	addr := conn.RemoteAddr()
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	h := hello{}
+
+	data, err := readHelloData(conn)
+	if errors.Is(err, errLegacyClient) {
+		log.Printf("%s rejected: %s\n", addr.String(), err)
+		// answered the way version 1 clients expect, so they show the error
+		conn.Write([]byte(legacyPongMagic))
+		writeMessage(conn, []byte(fmt.Sprintf(`{"error": %q}`, err.Error())))
+		conn.Close()
+		return h, false
+	}
+	if err != nil {
+		log.Printf("%s failed handshake: %s\n", addr.String(), err)
+		conn.Close()
+		return h, false
+	}
+	err = json.Unmarshal(data, &h)
+	if err != nil {
+		log.Printf("%s failed handshake: %s\n", addr.String(), err)
+		conn.Close()
+		return h, false
+	}
+	if h.Version < minProtocolVersion {
+		reject(conn, fmt.Errorf("protocol version %d is not supported, the server speaks versions %d to %d", h.Version, minProtocolVersion, protocolVersion))
+		return h, false
+	}
+	log.Printf("%s says hello with %s, protocol version %d\n", addr.String(), h.Client, h.Version)
+	return h, true
+}
+
+// readHelloData reads the framed hello. Clients of protocol version 1 send the ping magic
+// in front of it instead of a length header, they are reported with errLegacyClient.
+//
+// Parameters:
+// - conn: The newly accepted connection.
+//
+// Returns:
+// - []byte: The hello as JSON.
+// - error: errLegacyClient, or an error if reading fails or the hello is too large.
+func readHelloData(conn net.Conn) ([]byte, error) {
+	if mc, ok := conn.(messageConn); ok {
+		data, err := mc.ReadMessage(handshakeMaxSize)
+		if err == nil && string(data) == legacyPingMagic {
+			return nil, errLegacyClient
+		}
+		return data, err
+	}
+	header := make([]byte, headerSize)
+	_, err := io.ReadFull(conn, header)
+	if err != nil {
+		return nil, err
+	}
+	if string(header) == legacyPingMagic {
+		return nil, errLegacyClient
+	}
+	size := binary.BigEndian.Uint32(header)
+	if uint64(size) > handshakeMaxSize {
+		return nil, fmt.Errorf("message of %d bytes exceeds max size of %d bytes", size, handshakeMaxSize)
+	}
+	data := make([]byte, size)
+	_, err = io.ReadFull(conn, data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// reject tells a client why it was rejected and closes the connection.
+func reject(conn net.Conn, err error) {
+	log.Printf("%s rejected: %s\n", conn.RemoteAddr().String(), err)
+	writeWelcome(conn, welcome{ActorId: -1, Error: err.Error()})
+	conn.Close()
+}
+
+// seatConnection binds a connection that sent its hello to a seat of the server and
+// answers with the welcome. Clients without a token get the seat they asked for or the next
+// free one and a new session token, clients with a token get back the seat the token was
+// issued for. The features both sides support are used from then on.
+//
+// Parameters:
+// - s: The server instance holding the seats.
+// - conn: The connection, its hello has been read.
+// - h: The hello of the client, without a token for a new player.
+//
+// Returns:
+// - int: The seat (client ID) of the client.
+// - bool: true if the seat was newly taken, false if the client resumes its seat.
+// - bool: false if the client was rejected or the welcome could not be sent.
+func seatConnection(s *Server, conn net.Conn, h hello) (int, bool, bool) {
+	addr := conn.RemoteAddr()
+
+	s.mutex.Lock()
+	id, isNew, err := takeSeat(s, h.Token, h.Seat, h.Name)
+	token := s.tokens[id]
+	if err == nil && h.Name != "" {
+		s.names[id] = h.Name
+	}
+	if err == nil && isNew && h.Kind == kindBot {
+		s.strategies[id] = h.Strategy
+	}
+	s.mutex.Unlock()
+
+	if err != nil {
+		reject(conn, err)
+		return id, isNew, false
+	}
+
+	features := negotiateFeatures(h.Features)
+	err = writeWelcome(conn, welcome{ActorId: id, Features: features, Token: token, Table: s.table})
+	if err != nil {
+		log.Printf("%s failed handshake: %s\n", addr.String(), err)
+		conn.Close()
+		if isNew {
+			s.mutex.Lock()
+			delete(s.tokens, id)
+			delete(s.names, id)
+			delete(s.strategies, id)
+			s.mutex.Unlock()
+		}
+		return id, isNew, false
+	}
+	conn.SetDeadline(time.Time{})
+	conn = useFeatures(conn, features)
+
+	s.mutex.Lock()
+	old := s.conn[id]
+	s.conn[id] = conn
+	if !isNew {
+		setStatus(s, id, true)
+	}
+	s.mutex.Unlock()
+	if old != nil {
+		old.Close()
+	}
+
+	go handleRead(s, id, conn)
+
+	if isNew {
+		log.Printf("%s connected as player %d %q\n", addr.String(), id, h.Name)
+	} else {
+		log.Printf("%s reconnected as player %d\n", addr.String(), id)
+	}
+	return id, isNew, true
+}
+
+// addSpectator answers a spectator's hello and hands its write channel to the host. The spectator
+// gets a writing goroutine like a seat does, and a reading goroutine that only notices when it leaves.
+//
+// Parameters:
+// - s: The server instance of the game the spectator watches.
+// - conn: The connection of the spectator, its hello has been read.
+// - h: The hello of the spectator.
+//
+// Returns:
+// - None
+func addSpectator(s *Server, conn net.Conn, h hello) {
+	addr := conn.RemoteAddr()
+	features := negotiateFeatures(h.Features)
+	err := writeWelcome(conn, welcome{ActorId: -1, Features: features, Table: s.table})
+	if err != nil {
+		log.Printf("%s failed handshake: %s\n", addr.String(), err)
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	conn = useFeatures(conn, features)
+
+	s.mutex.Lock()
+	select {
+	case <-s.quit:
+		s.mutex.Unlock()
+		conn.Close()
+		return
+	default:
+	}
+	s.writers.Add(1)
+	s.mutex.Unlock()
+
+	out := make(chan []byte, spectatorBufferSize)
+	go handleSpectatorWrite(s, conn, out)
+	go func() {
+		for {
+			_, err := readMessage(conn, handshakeMaxSize)
+			if err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+	log.Printf("%s connected as a spectator\n", addr.String())
+
+	select {
+	case <-s.quit:
+	case s.spectators <- out:
+	}
+}
+
+// handleSpectatorWrite writes the data sent on out to a spectator. Once writing fails the
+// spectator is gone and the data is dropped, so the host never waits for a spectator that left.
+// The goroutine stops when the server is closed, or when the host closes out because the
+// spectator fell too far behind.
+//
+// Parameters:
+// - s: The server instance of the game the spectator watches.
+// - conn: The connection of the spectator.
+// - out: The channel the host sends the spectator's data on.
+//
+// Returns:
+// - None
+func handleSpectatorWrite(s *Server, conn net.Conn, out chan []byte) {
+	defer s.writers.Done()
+	defer conn.Close()
+	gone := false
+	for {
+		var buf []byte
+		ok := true
+		select {
+		case <-s.quit:
+			return
+		case buf, ok = <-out:
+		}
+		if !ok {
+			log.Printf("spectator %s fell behind and was dropped\n", conn.RemoteAddr().String())
+			return
+		}
+		if gone {
+			continue
+		}
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		err := writeMessage(conn, buf)
+		if err != nil {
+			log.Printf("spectator %s left\n", conn.RemoteAddr().String())
+			gone = true
+			conn.Close()
+		}
+	}
+}
+
+// takeSeat finds the seat for a connecting client, the server mutex has to be held by the caller.
+// If the seats are reserved a new player only gets a seat reserved for its name.
+//
+// Parameters:
+// - s: The server instance.
+// - token: The session token sent by the client, empty for a new player.
+// - seat: The seat a new player asked for, nil for the next free one.
+// - name: The name a new player sent in its hello.
+//
+// Returns:
+//   - int: The seat (client ID) of the client.
+//   - bool: true if the seat was newly taken, false if the client resumes its seat.
+//   - error: An error if the token is unknown, the seat asked for is taken or reserved for another
+//     name, or no seat is free for the player.
+func takeSeat(s *Server, token string, seat *int, name string) (int, bool, error) {
+	if token != "" {
+		for id, t := range s.tokens {
+			if t == token {
+				return id, false, nil
+			}
+		}
+		return 0, false, fmt.Errorf("unknown session token")
+	}
+	if seat != nil {
+		if *seat < 0 || *seat >= s.playerNum {
+			return 0, false, fmt.Errorf("there is no seat %d, the seats are 0 to %d", *seat, s.playerNum-1)
+		}
+		if _, taken := s.tokens[*seat]; taken {
+			return 0, false, fmt.Errorf("seat %d is taken", *seat)
+		}
+		if s.reserved != nil && s.reserved[*seat] != name {
+			return 0, false, fmt.Errorf("seat %d is kept for another player", *seat)
+		}
+		s.tokens[*seat] = createSessionToken()
+		return *seat, true, nil
+	}
+	for id := range s.playerNum {
+		if _, taken := s.tokens[id]; !taken && (s.reserved == nil || s.reserved[id] == name) {
+			s.tokens[id] = createSessionToken()
+			return id, true, nil
+		}
+	}
+	if s.reserved != nil {
+		return 0, false, fmt.Errorf("no free seat is kept for the name %q", name)
+	}
+	return 0, false, fmt.Errorf("every seat is taken")
+}
+
+func createSessionToken() string {
+	buf := make([]byte, 16)
+	_, err := rand.Read(buf)
+	if err != nil {
+		log.Fatalf("Failed to create session token: %s", err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// writeWelcome sends the welcome with the protocol version of the server. Clients speaking
+// a newer version than the server have to fall back to it or disconnect.
+func writeWelcome(conn net.Conn, w welcome) error {
+	w.Version = protocolVersion
+	data, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	return writeMessage(conn, data)
+}
+
+// setStatus replaces the status waiting in the status channel of a client with the new one,
+// so that sending never blocks. The server mutex has to be held by the caller.
+//
+// Parameters:
+// - s: The server instance.
+// - connId: The ID of the connection (client) whose status changed.
+// - connected: The new status.
+//
+// Returns:
+// - None
+func setStatus(s *Server, connId int, connected bool) {
+	select {
+	case <-s.status[connId]:
+	default:
+	}
+	s.status[connId] <- connected
+}
+
+// handleRead continuously reads framed messages from a client connection and sends
+// each message to the associated read channel (`s.in[connId]`). If an error occurs
+// while reading, the client is marked as disconnected, unless the connection has
+// already been replaced by a reconnect. The goroutine stops when the server is closed.
 //
 // Parameters:
 // - s: The server instance, which holds the client connections and associated channels.
 // - connId: The ID of the connection (client) being read from.
+// - conn: The connection to read from.
 //
 // Returns:
 // - None
-func handleRead(s *Server, connId int) {
+func handleRead(s *Server, connId int, conn net.Conn) {
 	for {
-		buf := make([]byte, s.serverMaxReceiveSize, s.serverMaxReceiveSize)
-		read, err := s.conn[connId].Read(buf)
+		buf, err := readMessage(conn, s.serverMaxReceiveSize)
 		if err != nil {
-			close(s.in[connId])
-			<-s.quitRead[connId]
+			s.mutex.Lock()
+			disconnected := s.conn[connId] == conn
+			if disconnected {
+				log.Printf("player %d disconnected\n", connId)
+				s.conn[connId] = nil
+				setStatus(s, connId, false)
+			}
+			s.mutex.Unlock()
+			conn.Close()
+			if disconnected && s.dropped != nil {
+				s.dropped()
+			}
 			return
 		}
 		select {
-		case <-s.quitRead[connId]:
+		case <-s.quit:
 			return
-		case s.in[connId] <- buf[:read]:
+		case s.in[connId] <- buf:
 		}
 	}
 }
 
 // handleWrite continuously listens for data to write to a client connection
-// and writes the data to the client's connection. If an error occurs while
-// writing or if the quitWrite signal is triggered, it stops the goroutine and
-// gracefully terminates the write operation.
+// and writes the data to the client's current connection as a framed message.
+// Data is dropped while the client is disconnected, and if writing fails the connection
+// is closed so the reading goroutine reports the disconnect. The goroutine stops when the server is closed.
 //
 // Parameters:
 // - s: The server instance, which holds the client connections and associated channels.
@@ -316,17 +1184,24 @@ func handleRead(s *Server, connId int) {
 // Returns:
 // - None
 func handleWrite(s *Server, connId int) {
+	defer s.writers.Done()
 	var buf []byte
 	for {
 		select {
-		case <-s.quitWrite[connId]:
+		case <-s.quit:
 			return
 		case buf = <-s.out[connId]:
 		}
-		_, err := s.conn[connId].Write(buf)
+		s.mutex.Lock()
+		conn := s.conn[connId]
+		s.mutex.Unlock()
+		if conn == nil {
+			continue
+		}
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		err := writeMessage(conn, buf)
 		if err != nil {
-			<-s.quitWrite[connId]
-			return
+			conn.Close()
 		}
 	}
 }
@@ -0,0 +1,735 @@
+package tcp
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFramingRoundTrip(t *testing.T) {
+	messages := [][]byte{
+		[]byte("pick 1 or 2 vegetables"),
+		{},
+		[]byte("A"),
+		[]byte(strings.Repeat("x", 5000)),
+	}
+
+	// all messages written back to back end up in one stream
+	stream := bytes.Buffer{}
+	for _, m := range messages {
+		err := writeMessage(&stream, m)
+		if err != nil {
+			t.Fatalf("failed to write message: %v", err)
+		}
+	}
+
+	for i, expected := range messages {
+		m, err := readMessage(&stream, 1<<16)
+		if err != nil {
+			t.Fatalf("failed to read message %d: %v", i, err)
+		}
+		if !bytes.Equal(m, expected) {
+			t.Errorf("expected message %d to be %d bytes got %d bytes", i, len(expected), len(m))
+		}
+	}
+}
+
+func TestFramingMaxSize(t *testing.T) {
+	stream := bytes.Buffer{}
+	err := writeMessage(&stream, []byte("ABCDE"))
+	if err != nil {
+		t.Fatalf("failed to write message: %v", err)
+	}
+	_, err = readMessage(&stream, 4)
+	if err == nil {
+		t.Errorf("expected error when message is larger than max size")
+	}
+}
+
+func TestClientReceivesSplitMessages(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	c := Client{
+		conn:                 clientConn,
+		in:                   make(chan []byte),
+		out:                  make(chan []byte),
+		quit:                 make(chan bool),
+		clientMaxReceiveSize: 1 << 16,
+	}
+	go c.handleRead()
+	c.writer.Add(1)
+	go c.handleWrite()
+
+	first := strings.Repeat("market ", 300)
+	second := "pick"
+
+	// write both frames one byte at a time to simulate a fragmented stream
+	go func() {
+		stream := bytes.Buffer{}
+		writeMessage(&stream, []byte(first))
+		writeMessage(&stream, []byte(second))
+		for _, b := range stream.Bytes() {
+			serverConn.Write([]byte{b})
+		}
+	}()
+
+	if got := string(<-c.GetReadChannel()); got != first {
+		t.Errorf("expected first message of %d bytes got %d bytes", len(first), len(got))
+	}
+	if got := string(<-c.GetReadChannel()); got != second {
+		t.Errorf("expected %q got %q", second, got)
+	}
+
+	serverConn.Close()
+	c.Close()
+}
+
+func getFreePort(t *testing.T) string {
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer ln.Close()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return port
+}
+
+func TestReconnectWithSessionToken(t *testing.T) {
+	port := getFreePort(t)
+	server := Server{}
+	listening := make(chan error)
+	go func() {
+		listening <- server.Listen(port, 1, 1024)
+	}()
+
+	c := Client{}
+	c.SetBot("greedy")
+	var err error
+	for range 50 {
+		err = c.Connect("127.0.0.1", port, 1024)
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	if err := <-listening; err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer server.Close()
+	defer c.Close()
+
+	if c.GetSessionToken() == "" {
+		t.Fatalf("expected a session token after connecting")
+	}
+
+	// drop the connection, the client reconnects on its own
+	c.mutex.Lock()
+	c.conn.Close()
+	c.mutex.Unlock()
+
+	if connected := <-server.GetStatusChannels()[0]; !connected {
+		if connected = <-server.GetStatusChannels()[0]; !connected {
+			t.Fatalf("expected client to reconnect")
+		}
+	}
+
+	// the seat stays a bot after reconnecting
+	if strategies := server.GetBotStrategies(); !reflect.DeepEqual(strategies, map[int]string{0: "greedy"}) {
+		t.Errorf("expected seat 0 to be a greedy bot got %v", strategies)
+	}
+
+	server.GetWriteChannels()[0] <- []byte("state")
+	if got := string(<-c.GetReadChannel()); got != "state" {
+		t.Errorf("expected %q got %q", "state", got)
+	}
+	c.GetWriteChannel() <- []byte("AB")
+	if got := string(<-server.GetReadChannels()[0]); got != "AB" {
+		t.Errorf("expected %q got %q", "AB", got)
+	}
+
+	// a client with an unknown token is rejected
+	other := Client{}
+	other.SetSessionToken("unknown")
+	err = other.Connect("127.0.0.1", port, 1024)
+	if err == nil || !strings.Contains(err.Error(), "unknown session token") {
+		t.Errorf("expected unknown session token to be rejected, got %v", err)
+	}
+}
+
+func TestReservedSeats(t *testing.T) {
+	port := getFreePort(t)
+	server := Server{}
+	server.ReserveSeats(map[int]string{0: "Ada", 1: "Grace", 2: ""})
+	listening := make(chan error)
+	go func() {
+		listening <- server.Listen(port, 3, 1024)
+	}()
+
+	connect := func(name string, seat int) (*Client, error) {
+		c := &Client{}
+		c.SetName(name)
+		if seat >= 0 {
+			c.RequestSeat(seat)
+		}
+		var err error
+		for range 50 {
+			err = c.Connect("127.0.0.1", port, 1024)
+			if err == nil || strings.Contains(err.Error(), "kept") {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return c, err
+	}
+
+	grace, err := connect("Grace", -1)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer grace.Close()
+	if grace.GetActorId() != 1 {
+		t.Errorf("expected Grace to get back seat 1 got %d", grace.GetActorId())
+	}
+	_, err = connect("Bob", -1)
+	if err == nil || !strings.Contains(err.Error(), "no free seat is kept") {
+		t.Errorf("expected a player of another game to be rejected, got %v", err)
+	}
+	_, err = connect("Bob", 0)
+	if err == nil || !strings.Contains(err.Error(), "seat 0 is kept for another player") {
+		t.Errorf("expected the seat of Ada to be refused to Bob, got %v", err)
+	}
+	nameless, err := connect("", -1)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer nameless.Close()
+	ada, err := connect("Ada", -1)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer ada.Close()
+	if err := <-listening; err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer server.Close()
+	if ada.GetActorId() != 0 || nameless.GetActorId() != 2 {
+		t.Errorf("expected Ada to get seat 0 and the player without a name seat 2, got %d and %d", ada.GetActorId(), nameless.GetActorId())
+	}
+}
+
+func TestLobby(t *testing.T) {
+	port := getFreePort(t)
+	lobby := Lobby{}
+	err := lobby.Listen(port, 1024, func(config TableConfig) error {
+		if config.Players+config.Bots > 6 {
+			return fmt.Errorf("too many players")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer lobby.Close()
+
+	creator := Client{}
+	creator.CreateTable(TableConfig{Players: 2, Bots: 1})
+	err = creator.Connect("127.0.0.1", port, 1024)
+	if err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	defer creator.Close()
+	if creator.GetTableId() != 1 {
+		t.Errorf("expected to be seated at table 1 got %d", creator.GetTableId())
+	}
+
+	invalid := Client{}
+	invalid.CreateTable(TableConfig{Players: 5, Bots: 2})
+	err = invalid.Connect("127.0.0.1", port, 1024)
+	if err == nil || !strings.Contains(err.Error(), "too many players") {
+		t.Errorf("expected the invalid table to be rejected, got %v", err)
+	}
+
+	tables, err := (&Client{}).ListTables("127.0.0.1", port)
+	if err != nil {
+		t.Fatalf("failed to list tables: %v", err)
+	}
+	expected := []TableInfo{{Id: 1, Config: TableConfig{Players: 2, Bots: 1}, Seated: 1}}
+	if !reflect.DeepEqual(tables, expected) {
+		t.Errorf("expected tables %v got %v", expected, tables)
+	}
+
+	joiner := Client{}
+	joiner.JoinTable(1)
+	err = joiner.Connect("127.0.0.1", port, 1024)
+	if err != nil {
+		t.Fatalf("failed to join table: %v", err)
+	}
+	defer joiner.Close()
+
+	table := <-lobby.GetTableChannel()
+	if table.Id != 1 {
+		t.Fatalf("expected table 1 to start got %d", table.Id)
+	}
+	table.GetWriteChannels()[1] <- []byte("state")
+	if got := string(<-joiner.GetReadChannel()); got != "state" {
+		t.Errorf("expected %q got %q", "state", got)
+	}
+	creator.GetWriteChannel() <- []byte("AB")
+	if got := string(<-table.GetReadChannels()[0]); got != "AB" {
+		t.Errorf("expected %q got %q", "AB", got)
+	}
+
+	// spectators can watch a running table
+	spectator := Client{}
+	spectator.JoinTable(1)
+	spectator.Spectate()
+	err = spectator.Connect("127.0.0.1", port, 1024)
+	if err != nil {
+		t.Fatalf("failed to spectate table: %v", err)
+	}
+	defer spectator.Close()
+	watching := <-table.GetSpectatorChannel()
+	watching <- []byte("hands")
+	if got := string(<-spectator.GetReadChannel()); got != "hands" {
+		t.Errorf("expected %q got %q", "hands", got)
+	}
+
+	late := Client{}
+	late.JoinTable(1)
+	err = late.Connect("127.0.0.1", port, 1024)
+	if err == nil {
+		t.Errorf("expected a running table to be closed to new players")
+	}
+
+	table.Close()
+	if tables := lobby.GetTables(); len(tables) != 0 {
+		t.Errorf("expected a closed table to leave the lobby, got %v", tables)
+	}
+}
+
+func TestLobbyAbandonedTables(t *testing.T) {
+	port := getFreePort(t)
+	lobby := Lobby{}
+	err := lobby.Listen(port, 1024, nil)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer lobby.Close()
+
+	creator := Client{}
+	creator.CreateTable(TableConfig{Players: 2})
+	err = creator.Connect("127.0.0.1", port, 1024)
+	if err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	if tables := lobby.GetTables(); len(tables) != 1 {
+		t.Fatalf("expected the created table got %v", tables)
+	}
+
+	// the table is removed once its only player leaves before it started
+	creator.Close()
+	for range 100 {
+		if len(lobby.GetTables()) == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if tables := lobby.GetTables(); len(tables) != 0 {
+		t.Errorf("expected the abandoned table to be removed got %v", tables)
+	}
+
+	for range maxOpenTables {
+		_, err := createTable(&lobby, &TableConfig{Players: 2})
+		if err != nil {
+			t.Fatalf("failed to create table: %v", err)
+		}
+	}
+	other := Client{}
+	other.CreateTable(TableConfig{Players: 2})
+	err = other.Connect("127.0.0.1", port, 1024)
+	if err == nil || !strings.Contains(err.Error(), "open tables") {
+		t.Errorf("expected a table above the limit to be rejected, got %v", err)
+	}
+
+	// running tables do not count toward the limit
+	lobby.mutex.Lock()
+	lobby.tables[2].started = true
+	lobby.mutex.Unlock()
+	_, err = createTable(&lobby, &TableConfig{Players: 2})
+	if err != nil {
+		t.Errorf("expected a table to be created once another started, got %v", err)
+	}
+}
+
+func TestSpectator(t *testing.T) {
+	port := getFreePort(t)
+	server := Server{}
+	listening := make(chan error)
+	go func() {
+		listening <- server.Listen(port, 1, 1024)
+	}()
+
+	spectator := Client{}
+	spectator.Spectate()
+	var err error
+	for range 50 {
+		err = spectator.Connect("127.0.0.1", port, 1024)
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("failed to connect spectator: %v", err)
+	}
+	defer spectator.Close()
+	if spectator.GetSessionToken() != "" {
+		t.Errorf("expected a spectator to get no session token")
+	}
+	watching := <-server.GetSpectatorChannel()
+
+	// the spectator does not take the seat
+	select {
+	case err := <-listening:
+		t.Fatalf("expected Listen to wait for the player, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	player := Client{}
+	err = player.Connect("127.0.0.1", port, 1024)
+	if err != nil {
+		t.Fatalf("failed to connect player: %v", err)
+	}
+	defer player.Close()
+	if err := <-listening; err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	watching <- []byte("market")
+	if got := string(<-spectator.GetReadChannel()); got != "market" {
+		t.Errorf("expected %q got %q", "market", got)
+	}
+}
+
+// createSelfSignedCert writes a self-signed certificate for localhost and its key to a temporary directory.
+func createSelfSignedCert(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLS(t *testing.T) {
+	certFile, keyFile := createSelfSignedCert(t)
+	otherCertFile, _ := createSelfSignedCert(t)
+	port := getFreePort(t)
+	server := Server{}
+	err := server.SetTLS(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("failed to load certificate: %v", err)
+	}
+	listening := make(chan error)
+	go func() {
+		listening <- server.Listen(port, 1, 1024)
+	}()
+
+	// a plain client and a client not trusting the certificate cannot connect
+	plain := Client{}
+	for range 50 {
+		err = plain.Connect("127.0.0.1", port, 1024)
+		if err == nil || !strings.Contains(err.Error(), "refused") {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err == nil {
+		t.Fatalf("expected a plain client to fail the handshake")
+	}
+	untrusting := Client{}
+	err = untrusting.SetTLS(otherCertFile)
+	if err != nil {
+		t.Fatalf("failed to load CA: %v", err)
+	}
+	err = untrusting.Connect("127.0.0.1", port, 1024)
+	if err == nil {
+		t.Fatalf("expected a client not trusting the certificate to be refused")
+	}
+
+	c := Client{}
+	err = c.SetTLS(certFile)
+	if err != nil {
+		t.Fatalf("failed to load CA: %v", err)
+	}
+	err = c.Connect("127.0.0.1", port, 1024)
+	if err != nil {
+		t.Fatalf("failed to connect over TLS: %v", err)
+	}
+	if err := <-listening; err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer server.Close()
+	defer c.Close()
+
+	server.GetWriteChannels()[0] <- []byte("state")
+	if got := string(<-c.GetReadChannel()); got != "state" {
+		t.Errorf("expected %q got %q", "state", got)
+	}
+	c.GetWriteChannel() <- []byte("AB")
+	if got := string(<-server.GetReadChannels()[0]); got != "AB" {
+		t.Errorf("expected %q got %q", "AB", got)
+	}
+
+	if err := (&Client{}).SetTLS(keyFile); err == nil {
+		t.Errorf("expected a file without certificates to be refused as CA")
+	}
+}
+
+func TestPassword(t *testing.T) {
+	port := getFreePort(t)
+	server := Server{}
+	server.SetPassword("salad")
+	listening := make(chan error)
+	go func() {
+		listening <- server.Listen(port, 1, 1024)
+	}()
+
+	missing := Client{}
+	var err error
+	for range 50 {
+		err = missing.Connect("127.0.0.1", port, 1024)
+		if err == nil || !strings.Contains(err.Error(), "refused") {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err == nil || !strings.Contains(err.Error(), "the table is protected by a password") {
+		t.Errorf("expected a client without password to be rejected, got %v", err)
+	}
+	wrong := Client{}
+	wrong.SetPassword("tomato")
+	err = wrong.Connect("127.0.0.1", port, 1024)
+	if err == nil || !strings.Contains(err.Error(), "wrong table password") {
+		t.Errorf("expected a wrong password to be rejected, got %v", err)
+	}
+
+	c := Client{}
+	c.SetPassword("salad")
+	err = c.Connect("127.0.0.1", port, 1024)
+	if err != nil {
+		t.Fatalf("failed to connect with the password: %v", err)
+	}
+	if err := <-listening; err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer server.Close()
+	defer c.Close()
+
+	// the lobby checks the password before listing the tables
+	lobbyPort := getFreePort(t)
+	lobby := Lobby{}
+	lobby.SetPassword("salad")
+	err = lobby.Listen(lobbyPort, 1024, nil)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer lobby.Close()
+	_, err = (&Client{}).ListTables("127.0.0.1", lobbyPort)
+	if err == nil || !strings.Contains(err.Error(), "password") {
+		t.Errorf("expected listing the tables without password to be rejected, got %v", err)
+	}
+	member := Client{}
+	member.SetPassword("salad")
+	tables, err := member.ListTables("127.0.0.1", lobbyPort)
+	if err != nil || len(tables) != 0 {
+		t.Errorf("expected no tables got %v %v", tables, err)
+	}
+}
+
+// sendHello opens a connection to the server, sends data as the hello and returns the welcome.
+func sendHello(t *testing.T, port string, data []byte) (net.Conn, welcome) {
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", port))
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	err = writeMessage(conn, data)
+	if err != nil {
+		t.Fatalf("failed to send hello: %v", err)
+	}
+	w := welcome{}
+	reply, err := readMessage(conn, handshakeMaxSize)
+	if err != nil {
+		t.Fatalf("failed to read welcome: %v", err)
+	}
+	err = json.Unmarshal(reply, &w)
+	if err != nil {
+		t.Fatalf("failed to parse welcome %q: %v", reply, err)
+	}
+	return conn, w
+}
+
+func TestHandshakeVersions(t *testing.T) {
+	port := getFreePort(t)
+	server := Server{}
+	listening := make(chan error)
+	go func() {
+		listening <- server.Listen(port, 2, 1024)
+	}()
+
+	var conn net.Conn
+	var err error
+	for range 50 {
+		conn, err = net.Dial("tcp", net.JoinHostPort("127.0.0.1", port))
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+
+	// a version 1 client is answered the way it expects and told to update
+	conn.Write([]byte(legacyPingMagic))
+	writeMessage(conn, []byte("{}"))
+	buf := make([]byte, len(legacyPongMagic))
+	_, err = io.ReadFull(conn, buf)
+	if err != nil || string(buf) != legacyPongMagic {
+		t.Fatalf("expected the legacy pong magic got %q %v", buf, err)
+	}
+	reply, err := readMessage(conn, handshakeMaxSize)
+	if err != nil || !strings.Contains(string(reply), "please update the client") {
+		t.Errorf("expected the legacy client to be told to update, got %q %v", reply, err)
+	}
+	conn.Close()
+
+	conn, w := sendHello(t, port, []byte(`{"version": 1}`))
+	conn.Close()
+	if !strings.Contains(w.Error, "protocol version 1 is not supported") || w.Version != protocolVersion {
+		t.Errorf("expected version 1 to be rejected, got %+v", w)
+	}
+
+	// a newer client is offered the version of the server, only known features are accepted
+	conn, w = sendHello(t, port, []byte(`{"version": 99, "client": "test", "seat": 1, "features": ["telepathy", "compression"]}`))
+	if w.Error != "" || w.Version != protocolVersion || w.ActorId != 1 || !reflect.DeepEqual(w.Features, []string{featureCompression}) {
+		t.Errorf("expected version %d, seat 1 and compression, got %+v", protocolVersion, w)
+	}
+	defer conn.Close()
+
+	c := Client{}
+	c.RequestSeat(1)
+	err = c.Connect("127.0.0.1", port, 1024)
+	if err == nil || !strings.Contains(err.Error(), "seat 1 is taken") {
+		t.Errorf("expected the taken seat to be refused, got %v", err)
+	}
+
+	// a client without features reads and writes uncompressed messages
+	conn, w = sendHello(t, port, []byte(`{"version": 2, "name": "Ada"}`))
+	if w.Error != "" || w.ActorId != 0 || len(w.Features) != 0 {
+		t.Fatalf("expected seat 0 without features got %+v", w)
+	}
+	defer conn.Close()
+	if err := <-listening; err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	server.GetWriteChannels()[0] <- []byte("state")
+	reply, err = readMessage(conn, 1024)
+	if err != nil || string(reply) != "state" {
+		t.Errorf("expected %q got %q %v", "state", reply, err)
+	}
+	writeMessage(conn, []byte("AB"))
+	if got := string(<-server.GetReadChannels()[0]); got != "AB" {
+		t.Errorf("expected %q got %q", "AB", got)
+	}
+	if names := server.GetPlayerNames(); !reflect.DeepEqual(names, map[int]string{0: "Ada"}) {
+		t.Errorf("expected seat 0 to be called %q got %v", "Ada", names)
+	}
+	if strategies := server.GetBotStrategies(); len(strategies) != 0 {
+		t.Errorf("expected no bots got %v", strategies)
+	}
+}
+
+func TestCompressedConn(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	writer := useFeatures(a, []string{featureCompression}).(messageConn)
+	reader := useFeatures(b, []string{featureCompression}).(messageConn)
+
+	messages := [][]byte{
+		[]byte(`{"kind": "StateUpdate"}`),
+		{},
+		[]byte(strings.Repeat("carrot ", 1000)),
+	}
+	go func() {
+		for _, m := range messages {
+			writer.WriteMessage(m)
+		}
+		writer.WriteMessage([]byte(strings.Repeat("x", 2000)))
+	}()
+	for i, expected := range messages {
+		m, err := reader.ReadMessage(8000)
+		if err != nil {
+			t.Fatalf("failed to read message %d: %v", i, err)
+		}
+		if !bytes.Equal(m, expected) {
+			t.Errorf("expected message %d to be %q got %q", i, expected, m)
+		}
+	}
+	// compresses well but is too large once decompressed
+	_, err := reader.ReadMessage(1000)
+	if err == nil {
+		t.Errorf("expected error when the decompressed message is larger than max size")
+	}
+}
@@ -0,0 +1,525 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// appended to the key of the client to compute the accept header, see RFC 6455 section 1.3
+	acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+
+	// largest payload of a control frame
+	maxControlSize = 125
+	// largest message returned through Read, messages are usually read whole with ReadMessage
+	streamMaxSize = 64 * 1024
+	// status code sent in the close frame of a normal closure
+	closeNormal = 1000
+
+	handshakeTimeout = 10 * time.Second
+	closeTimeout     = time.Second
+)
+
+// Conn is a websocket connection as described in RFC 6455. It keeps message boundaries, every
+// WriteMessage sends one message and every ReadMessage returns one, and it implements net.Conn
+// where every Write sends one message and Read returns the data of the messages in order.
+// Pings are answered while reading, and a close frame from the other side ends the connection.
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// the client side masks the frames it sends, the server side does not
+	client     bool
+	writeMutex sync.Mutex
+	// rest of a message partially returned by Read
+	pending []byte
+}
+
+// ReadMessage reads the next data message, joining fragmented frames and answering the
+// control frames that arrive in between.
+//
+// Parameters:
+// - maxSize: The largest message size accepted, larger messages are rejected.
+//
+// Returns:
+//   - []byte: The data of the message.
+//   - error: io.EOF if the other side closed the connection, or an error if reading fails,
+//     the frames break the protocol or the message is larger than maxSize.
+func (c *Conn) ReadMessage(maxSize int) ([]byte, error) {
+	if len(c.pending) > 0 {
+		message := c.pending
+		c.pending = nil
+		if len(message) > maxSize {
+			return nil, fmt.Errorf("message of %d bytes exceeds max size of %d bytes", len(message), maxSize)
+		}
+		return message, nil
+	}
+
+	message := []byte{}
+	started := false
+	for {
+		fin, opcode, payload, err := c.readFrame(maxSize - len(message))
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case opPing:
+			err := c.writeFrame(opPong, payload)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			// echo the status code and close, see RFC 6455 section 5.5.1
+			c.writeFrame(opClose, payload[:min(2, len(payload))])
+			c.conn.Close()
+			return nil, io.EOF
+		case opText, opBinary:
+			if started {
+				return nil, fmt.Errorf("expected a continuation frame, got opcode %d", opcode)
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				return nil, fmt.Errorf("continuation frame without a message to continue")
+			}
+		default:
+			return nil, fmt.Errorf("unknown opcode %d", opcode)
+		}
+		message = append(message, payload...)
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// readFrame reads a single frame and unmasks its payload.
+//
+// Parameters:
+// - maxSize: The largest payload accepted for a data frame, control frames are limited to 125 bytes.
+//
+// Returns:
+// - bool: true if the frame is the final fragment of its message.
+// - byte: The opcode of the frame.
+// - []byte: The unmasked payload.
+// - error: An error if reading fails or the frame breaks the protocol.
+func (c *Conn) readFrame(maxSize int) (bool, byte, []byte, error) {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(c.reader, header)
+	if err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, fmt.Errorf("reserved bits are set without a negotiated extension")
+	}
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	if masked == c.client {
+		return false, 0, nil, fmt.Errorf("frames sent by the client have to be masked and frames sent by the server must not be")
+	}
+
+	size := uint64(header[1] & 0x7F)
+	switch size {
+	case 126:
+		buf := make([]byte, 2)
+		_, err = io.ReadFull(c.reader, buf)
+		size = uint64(binary.BigEndian.Uint16(buf))
+	case 127:
+		buf := make([]byte, 8)
+		_, err = io.ReadFull(c.reader, buf)
+		size = binary.BigEndian.Uint64(buf)
+	}
+	if err != nil {
+		return false, 0, nil, err
+	}
+	if opcode >= opClose {
+		if !fin || size > maxControlSize {
+			return false, 0, nil, fmt.Errorf("control frames cannot be fragmented or longer than %d bytes", maxControlSize)
+		}
+	} else if size > uint64(max(maxSize, 0)) {
+		return false, 0, nil, fmt.Errorf("message of at least %d bytes exceeds max size", size)
+	}
+
+	key := make([]byte, 4)
+	if masked {
+		_, err = io.ReadFull(c.reader, key)
+		if err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, size)
+	_, err = io.ReadFull(c.reader, payload)
+	if err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= key[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage sends data as a single message, as a text message if it is valid UTF-8 so
+// browsers receive JSON as a string, and as a binary message otherwise.
+//
+// Parameters:
+// - data: The message to send, may be empty.
+//
+// Returns:
+// - error: An error if the frame could not be written.
+func (c *Conn) WriteMessage(data []byte) error {
+	if utf8.Valid(data) {
+		return c.writeFrame(opText, data)
+	}
+	return c.writeFrame(opBinary, data)
+}
+
+// writeFrame writes a final frame, masked with a random key on the client side.
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	var key []byte
+	if c.client {
+		key = make([]byte, 4)
+		_, err := rand.Read(key)
+		if err != nil {
+			return err
+		}
+	}
+	frame := encodeFrame(true, opcode, payload, key)
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// encodeFrame encodes a frame with the shortest length encoding.
+//
+// Parameters:
+// - fin: true if the frame is the final fragment of its message.
+// - opcode: The opcode of the frame.
+// - payload: The payload, it is not changed.
+// - key: The masking key, nil for an unmasked frame.
+//
+// Returns:
+// - []byte: The frame.
+func encodeFrame(fin bool, opcode byte, payload []byte, key []byte) []byte {
+	frame := []byte{opcode}
+	if fin {
+		frame[0] |= 0x80
+	}
+	maskBit := byte(0)
+	if key != nil {
+		maskBit = 0x80
+	}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, maskBit|byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	if key == nil {
+		return append(frame, payload...)
+	}
+	frame = append(frame, key...)
+	for i, b := range payload {
+		frame = append(frame, b^key[i%4])
+	}
+	return frame
+}
+
+// Read reads the data of the messages in order, as if they were one stream.
+func (c *Conn) Read(p []byte) (int, error) {
+	if len(c.pending) == 0 {
+		message, err := c.ReadMessage(streamMaxSize)
+		if err != nil {
+			return 0, err
+		}
+		c.pending = message
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// Write sends p as a single message.
+func (c *Conn) Write(p []byte) (int, error) {
+	err := c.WriteMessage(p)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close sends a close frame, without waiting for the answer, and closes the connection.
+func (c *Conn) Close() error {
+	c.conn.SetWriteDeadline(time.Now().Add(closeTimeout))
+	c.writeFrame(opClose, binary.BigEndian.AppendUint16(nil, closeNormal))
+	return c.conn.Close()
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// getAcceptKey returns the Sec-WebSocket-Accept value the server answers the key of the client with.
+func getAcceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// headerContains returns true if one of the comma separated values of the header is value,
+// ignoring case.
+func headerContains(header http.Header, name string, value string) bool {
+	for _, line := range header.Values(name) {
+		for _, v := range strings.Split(line, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Upgrade performs the server side of the opening handshake and takes over the connection of
+// the request. Requests that are not websocket handshakes are answered with an HTTP error.
+//
+// Parameters:
+// - w: The response writer of the request, it has to support hijacking the connection.
+// - r: The handshake request of the client.
+//
+// Returns:
+// - *Conn: The websocket connection.
+// - error: An error if the request is not a valid handshake or the connection cannot be taken over.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "expected a GET request", http.StatusMethodNotAllowed)
+		return nil, fmt.Errorf("expected a GET request, got %s", r.Method)
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "expected a websocket handshake", http.StatusBadRequest)
+		return nil, fmt.Errorf("expected a websocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, fmt.Errorf("unsupported websocket version %q", r.Header.Get("Sec-WebSocket-Version"))
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, fmt.Errorf("missing Sec-WebSocket-Key")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websockets are not supported", http.StatusInternalServerError)
+		return nil, fmt.Errorf("the response writer cannot hand over the connection")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + getAcceptKey(key) + "\r\n\r\n"
+	conn.SetWriteDeadline(time.Now().Add(handshakeTimeout))
+	_, err = conn.Write([]byte(response))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetWriteDeadline(time.Time{})
+	return &Conn{conn: conn, reader: rw.Reader}, nil
+}
+
+// Dial opens a websocket connection to ws://address/, or wss://address/ if config is set, and
+// performs the client side of the opening handshake.
+//
+// Parameters:
+// - address: The host and port of the server, ex. localhost:8080.
+// - config: The TLS settings of the client, nil for a plain websocket.
+//
+// Returns:
+// - net.Conn: The websocket connection, a *Conn.
+// - error: An error if connecting fails or the server does not accept the handshake.
+func Dial(address string, config *tls.Config) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: handshakeTimeout}
+	if config != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, config)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+
+	nonce := make([]byte, 16)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+	request := "GET / HTTP/1.1\r\n" +
+		"Host: " + address + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+	_, err = conn.Write([]byte(request))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, &http.Request{Method: http.MethodGet})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("server refused the websocket handshake: %s", response.Status)
+	}
+	if !headerContains(response.Header, "Upgrade", "websocket") || response.Header.Get("Sec-WebSocket-Accept") != getAcceptKey(key) {
+		conn.Close()
+		return nil, fmt.Errorf("server answered the websocket handshake wrongly")
+	}
+
+	conn.SetDeadline(time.Time{})
+	return &Conn{conn: conn, reader: reader, client: true}, nil
+}
+
+// Listener accepts websocket connections. It is an http.Handler that upgrades every request,
+// so it can be served by an http.Server or an httptest.Server, and a net.Listener that hands
+// out the upgraded connections.
+type Listener struct {
+	conns  chan net.Conn
+	done   chan bool
+	once   sync.Once
+	addr   net.Addr
+	server *http.Server
+}
+
+// CreateListener creates a listener that is not served yet, see Listen to serve it on a port.
+//
+// Returns:
+// - *Listener: The listener, to be used as the handler of an HTTP server.
+func CreateListener() *Listener {
+	return &Listener{
+		conns: make(chan net.Conn),
+		done:  make(chan bool),
+		addr:  &net.TCPAddr{},
+	}
+}
+
+// Listen serves a new listener on the specified port, upgrading requests on every path.
+//
+// Parameters:
+// - port: The port on which to listen for websocket connections.
+// - config: The TLS settings of the server for wss://, nil for plain websockets.
+//
+// Returns:
+// - *Listener: The listener, closing it stops the HTTP server.
+// - error: An error if the port cannot be listened on.
+func Listen(port string, config *tls.Config) (*Listener, error) {
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return nil, err
+	}
+	if config != nil {
+		ln = tls.NewListener(ln, config)
+	}
+	l := CreateListener()
+	l.addr = ln.Addr()
+	l.server = &http.Server{Handler: l}
+	go l.server.Serve(ln)
+	return l, nil
+}
+
+// ServeHTTP upgrades the request and waits until the connection is accepted.
+func (l *Listener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	select {
+	case <-l.done:
+		conn.Close()
+	case l.conns <- conn:
+	}
+}
+
+// Accept waits for the next websocket connection.
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case <-l.done:
+		return nil, net.ErrClosed
+	case conn := <-l.conns:
+		return conn, nil
+	}
+}
+
+// Close stops accepting connections, and stops the HTTP server if it was started by Listen.
+// Connections that were accepted stay open.
+func (l *Listener) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+		if l.server != nil {
+			l.server.Close()
+		}
+	})
+	return nil
+}
+
+func (l *Listener) Addr() net.Addr {
+	return l.addr
+}
@@ -0,0 +1,75 @@
+package websocket
+
+import (
+	"HomeExam/network/tcp"
+	"log"
+)
+
+// Server is a game server whose clients connect with websockets. It works like tcp.Server,
+// seats, session tokens, spectators and passwords included, but every message is carried by a
+// websocket message instead of a length prefixed frame, so browsers can connect to ws://host:port/,
+// or wss://host:port/ after SetTLS.
+type Server struct {
+	tcp.Server
+}
+
+// Listen serves websockets on the specified port and waits until every seat has been taken,
+// see tcp.Server.Listen.
+//
+// Parameters:
+// - port: The port on which the server listens for websocket connections.
+// - playerNum: The number of players (clients) the server expects to connect.
+// - serverMaxReceiveSize: The maximum size for receiving data from clients.
+//
+// Returns:
+// - error: An error if the port cannot be listened on, or nil once every seat has been taken.
+func (s *Server) Listen(port string, playerNum int, serverMaxReceiveSize int) error {
+	log.Printf("listening for websockets on port %v\n", port)
+	ln, err := Listen(port, s.GetTLSConfig())
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln, playerNum, serverMaxReceiveSize)
+}
+
+// Lobby is a lobby whose clients connect with websockets, see tcp.Lobby.
+type Lobby struct {
+	tcp.Lobby
+}
+
+// Listen serves websockets on the specified port and returns once the lobby accepts
+// connections, see tcp.Lobby.Listen.
+//
+// Parameters:
+// - port: The port on which the lobby listens for websocket connections.
+// - serverMaxReceiveSize: The maximum size for receiving data from clients.
+// - validate: Checks the config of a table a client wants to create.
+//
+// Returns:
+// - error: An error if the port cannot be listened on.
+func (l *Lobby) Listen(port string, serverMaxReceiveSize int, validate func(config tcp.TableConfig) error) error {
+	log.Printf("lobby listening for websockets on port %v\n", port)
+	ln, err := Listen(port, l.GetTLSConfig())
+	if err != nil {
+		return err
+	}
+	return l.Serve(ln, serverMaxReceiveSize, validate)
+}
+
+// Client is a game client that connects with a websocket, see tcp.Client.
+type Client struct {
+	tcp.Client
+}
+
+// Connect connects to ws://hostname:port/, or wss://hostname:port/ after SetTLS, and performs
+// the handshake, see tcp.Client.Connect. Reconnects use websockets too.
+func (c *Client) Connect(hostname string, port string, clientMaxReceiveSize int) error {
+	c.SetDialer(Dial)
+	return c.Client.Connect(hostname, port, clientMaxReceiveSize)
+}
+
+// ListTables returns the tables of a lobby served over websockets, see tcp.Client.ListTables.
+func (c *Client) ListTables(hostname string, port string) ([]tcp.TableInfo, error) {
+	c.SetDialer(Dial)
+	return c.Client.ListTables(hostname, port)
+}
@@ -0,0 +1,240 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// connectPair returns both ends of a websocket connection made through an httptest server.
+func connectPair(t *testing.T) (*Conn, *Conn) {
+	ln := CreateListener()
+	ts := httptest.NewServer(ln)
+	t.Cleanup(ts.Close)
+
+	conn, err := Dial(ts.Listener.Addr().String(), nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	accepted, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("failed to accept: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		accepted.Close()
+	})
+	return conn.(*Conn), accepted.(*Conn)
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	client, server := connectPair(t)
+	messages := [][]byte{
+		[]byte(`{"kind": "StateUpdate"}`),
+		{},
+		[]byte(strings.Repeat("x", 200)),
+		[]byte(strings.Repeat("y", 70000)),
+		{0xff, 0xfe, 0x00},
+	}
+
+	go func() {
+		for _, m := range messages {
+			client.WriteMessage(m)
+		}
+	}()
+	for i, expected := range messages {
+		m, err := server.ReadMessage(1 << 20)
+		if err != nil {
+			t.Fatalf("failed to read message %d: %v", i, err)
+		}
+		if !bytes.Equal(m, expected) {
+			t.Errorf("expected message %d to be %d bytes got %d bytes", i, len(expected), len(m))
+		}
+	}
+
+	go func() {
+		for _, m := range messages {
+			server.WriteMessage(m)
+		}
+	}()
+	for i, expected := range messages {
+		m, err := client.ReadMessage(1 << 20)
+		if err != nil {
+			t.Fatalf("failed to read message %d: %v", i, err)
+		}
+		if !bytes.Equal(m, expected) {
+			t.Errorf("expected message %d to be %d bytes got %d bytes", i, len(expected), len(m))
+		}
+	}
+}
+
+func TestFragmentsAndControlFrames(t *testing.T) {
+	client, server := connectPair(t)
+	key := []byte{1, 2, 3, 4}
+
+	// a ping in the middle of a fragmented message is answered, the message arrives whole
+	go func() {
+		client.conn.Write(encodeFrame(false, opText, []byte("pick "), key))
+		client.conn.Write(encodeFrame(true, opPing, []byte("ping"), key))
+		client.conn.Write(encodeFrame(true, opContinuation, []byte("AB"), key))
+	}()
+	m, err := server.ReadMessage(1024)
+	if err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	if string(m) != "pick AB" {
+		t.Errorf("expected %q got %q", "pick AB", m)
+	}
+	_, opcode, payload, err := client.readFrame(1024)
+	if err != nil || opcode != opPong || string(payload) != "ping" {
+		t.Errorf("expected a pong answering the ping, got opcode %d %q %v", opcode, payload, err)
+	}
+
+	// frames from the client have to be masked
+	go client.conn.Write(encodeFrame(true, opText, []byte("AB"), nil))
+	_, err = server.ReadMessage(1024)
+	if err == nil {
+		t.Errorf("expected an unmasked client frame to be rejected")
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	client, server := connectPair(t)
+	go client.WriteMessage([]byte("ABCDE"))
+	_, err := server.ReadMessage(4)
+	if err == nil {
+		t.Errorf("expected error when message is larger than max size")
+	}
+}
+
+func TestClose(t *testing.T) {
+	client, server := connectPair(t)
+	client.Close()
+	_, err := server.ReadMessage(1024)
+	if err == nil {
+		t.Errorf("expected reading to fail once the other side closed the connection")
+	}
+}
+
+func TestRejectsPlainRequests(t *testing.T) {
+	ts := httptest.NewServer(CreateListener())
+	defer ts.Close()
+	response, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected %d got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestServerAndClient(t *testing.T) {
+	ln := CreateListener()
+	ts := httptest.NewServer(ln)
+	defer ts.Close()
+	hostname, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+
+	server := Server{}
+	listening := make(chan error)
+	go func() {
+		listening <- server.Serve(ln, 1, 1024)
+	}()
+	c := Client{}
+	err := c.Connect(hostname, port, 1024)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	if err := <-listening; err != nil {
+		t.Fatalf("failed to serve: %v", err)
+	}
+	defer server.Close()
+	if c.GetSessionToken() == "" {
+		t.Fatalf("expected a session token after connecting")
+	}
+
+	server.GetWriteChannels()[0] <- []byte(`{"kind": "StateUpdate"}`)
+	if got := string(<-c.GetReadChannel()); got != `{"kind": "StateUpdate"}` {
+		t.Errorf("expected the state update got %q", got)
+	}
+	c.GetWriteChannel() <- []byte("AB")
+	if got := string(<-server.GetReadChannels()[0]); got != "AB" {
+		t.Errorf("expected %q got %q", "AB", got)
+	}
+
+	// the client leaves, a new websocket client takes the seat back with the session token
+	c.Close()
+	if connected := <-server.GetStatusChannels()[0]; connected {
+		t.Fatalf("expected the client to be disconnected")
+	}
+	other := Client{}
+	other.SetSessionToken(c.GetSessionToken())
+	err = other.Connect(hostname, port, 1024)
+	if err != nil {
+		t.Fatalf("failed to reconnect: %v", err)
+	}
+	defer other.Close()
+	if connected := <-server.GetStatusChannels()[0]; !connected {
+		t.Fatalf("expected the client to reconnect")
+	}
+	server.GetWriteChannels()[0] <- []byte("state")
+	if got := string(<-other.GetReadChannel()); got != "state" {
+		t.Errorf("expected %q got %q", "state", got)
+	}
+}
+
+func TestSecureWebSocket(t *testing.T) {
+	ln := CreateListener()
+	ts := httptest.NewTLSServer(ln)
+	defer ts.Close()
+	hostname, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := Server{}
+	server.SetPassword("salad")
+	listening := make(chan error)
+	go func() {
+		listening <- server.Serve(ln, 1, 1024)
+	}()
+
+	wrong := Client{}
+	err = wrong.SetTLS(caFile)
+	if err != nil {
+		t.Fatalf("failed to load CA: %v", err)
+	}
+	err = wrong.Connect(hostname, port, 1024)
+	if err == nil || !strings.Contains(err.Error(), "password") {
+		t.Errorf("expected a client without password to be rejected, got %v", err)
+	}
+
+	c := Client{}
+	err = c.SetTLS(caFile)
+	if err != nil {
+		t.Fatalf("failed to load CA: %v", err)
+	}
+	c.SetPassword("salad")
+	err = c.Connect(hostname, port, 1024)
+	if err != nil {
+		t.Fatalf("failed to connect over wss: %v", err)
+	}
+	if err := <-listening; err != nil {
+		t.Fatalf("failed to serve: %v", err)
+	}
+	defer server.Close()
+	defer c.Close()
+
+	server.GetWriteChannels()[0] <- []byte("state")
+	if got := string(<-c.GetReadChannel()); got != "state" {
+		t.Errorf("expected %q got %q", "state", got)
+	}
+}